@@ -0,0 +1,148 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/cli/go-gh/v2/pkg/api"
+	graphql "github.com/cli/shurcooL-graphql"
+
+	"github.com/github/gh-combine/internal/github"
+)
+
+// Merge methods for --auto-merge
+const (
+	mergeMethodMerge  = "merge"
+	mergeMethodSquash = "squash"
+	mergeMethodRebase = "rebase"
+)
+
+// Merge statuses of the combined PR
+const (
+	mergeStatusAutoMerge = "auto-merge enabled"
+	mergeStatusMerged    = "merged"
+)
+
+var errInvalidMergeMethod = errors.New("invalid --auto-merge value")
+
+// PullRequestMergeMethod is the GraphQL enum for merge methods
+type PullRequestMergeMethod string
+
+// EnablePullRequestAutoMergeInput is the GraphQL input type of the enablePullRequestAutoMerge mutation
+type EnablePullRequestAutoMergeInput struct {
+	PullRequestID graphql.ID             `json:"pullRequestId"`
+	MergeMethod   PullRequestMergeMethod `json:"mergeMethod"`
+}
+
+// mergeCombinedPR enables auto-merge on the combined PR with --auto-merge. If auto-merge cannot be enabled
+// (e.g. it is not allowed in the repository) or only --merge-when-green is set, it waits for CI to pass and
+// merges the PR directly
+func mergeCombinedPR(ctx context.Context, graphQlClient *api.GraphQLClient, restClient RESTClientInterface, repo github.Repo, prNumber int) (string, error) {
+	method := autoMergeMethod
+	if method == "" {
+		method = mergeMethodMerge
+	}
+
+	if autoMergeMethod != "" {
+		err := enableAutoMerge(ctx, graphQlClient, restClient, repo, prNumber, method)
+		if err == nil {
+			Logger.Debug("Enabled auto-merge on combined PR", "repo", repo, "pr", prNumber, "method", method)
+			return mergeStatusAutoMerge, nil
+		}
+		if !mergeWhenGreen {
+			return "", fmt.Errorf("failed to enable auto-merge: %w", err)
+		}
+		Logger.Debug("Failed to enable auto-merge, falling back to merging when green", "repo", repo, "pr", prNumber, "error", err)
+	}
+
+	return mergeWhenCIPasses(ctx, graphQlClient, restClient, repo, prNumber, method)
+}
+
+// enableAutoMerge calls the enablePullRequestAutoMerge GraphQL mutation on a pull request
+func enableAutoMerge(ctx context.Context, graphQlClient *api.GraphQLClient, restClient RESTClientInterface, repo github.Repo, prNumber int, method string) error {
+	nodeID, err := getPullRequestNodeID(ctx, restClient, repo, prNumber)
+	if err != nil {
+		return err
+	}
+
+	var mutation struct {
+		EnablePullRequestAutoMerge struct {
+			ClientMutationID string
+		} `graphql:"enablePullRequestAutoMerge(input: $input)"`
+	}
+
+	variables := map[string]interface{}{
+		"input": EnablePullRequestAutoMergeInput{
+			PullRequestID: graphql.ID(nodeID),
+			MergeMethod:   PullRequestMergeMethod(strings.ToUpper(method)),
+		},
+	}
+
+	return graphQlClient.MutateWithContext(ctx, "EnableAutoMerge", &mutation, variables)
+}
+
+// mergeWhenCIPasses waits for CI on the combined branch and merges the pull request once it passes
+func mergeWhenCIPasses(ctx context.Context, graphQlClient *api.GraphQLClient, restClient RESTClientInterface, repo github.Repo, prNumber int, method string) (string, error) {
+	sha, err := getBranchSHA(ctx, restClient, repo, combineBranchName)
+	if err != nil {
+		return "", err
+	}
+
+	state, err := waitForCommitCI(ctx, graphQlClient, repo, sha, ciWaitTimeout())
+	if err != nil {
+		return "", err
+	}
+	if !ciPassing(state) {
+		return "", fmt.Errorf("CI is %s on the combined PR, not merging", state)
+	}
+
+	if err := mergePullRequest(ctx, restClient, repo, prNumber, sha, method); err != nil {
+		return "", err
+	}
+
+	Logger.Debug("Merged combined PR", "repo", repo, "pr", prNumber, "method", method)
+	return mergeStatusMerged, nil
+}
+
+// mergePullRequest merges a pull request, as long as its head is still at the given SHA
+func mergePullRequest(ctx context.Context, client RESTClientInterface, repo github.Repo, prNumber int, sha, method string) error {
+	endpoint := fmt.Sprintf("repos/%s/%s/pulls/%d/merge", repo.Owner, repo.Repo, prNumber)
+	body, err := encodePayload(map[string]string{
+		"sha":          sha,
+		"merge_method": method,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode payload: %w", err)
+	}
+
+	if err := client.Put(endpoint, body, nil); err != nil {
+		return fmt.Errorf("failed to merge pull request: %w", err)
+	}
+	return nil
+}
+
+// getPullRequestNodeID returns the GraphQL node ID of a pull request
+func getPullRequestNodeID(ctx context.Context, client RESTClientInterface, repo github.Repo, prNumber int) (string, error) {
+	var pull struct {
+		NodeID string `json:"node_id"`
+	}
+	endpoint := fmt.Sprintf("repos/%s/%s/pulls/%d", repo.Owner, repo.Repo, prNumber)
+	if err := client.Get(endpoint, &pull); err != nil {
+		return "", fmt.Errorf("failed to get pull request #%d: %w", prNumber, err)
+	}
+	return pull.NodeID, nil
+}
+
+// ValidateMergeMethod checks that --auto-merge is one of the supported merge methods
+func ValidateMergeMethod(method string) error {
+	if method == "" {
+		return nil
+	}
+	if !slices.Contains([]string{mergeMethodMerge, mergeMethodSquash, mergeMethodRebase}, method) {
+		return fmt.Errorf("%w: %q (must be %s, %s or %s)", errInvalidMergeMethod, method, mergeMethodMerge, mergeMethodSquash, mergeMethodRebase)
+	}
+	return nil
+}
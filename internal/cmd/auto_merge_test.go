@@ -0,0 +1,89 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/github/gh-combine/internal/github"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMergePullRequest(t *testing.T) {
+	t.Parallel()
+
+	var gotEndpoint string
+	var gotPayload map[string]string
+	client := &MockRESTClient{
+		PutFunc: func(endpoint string, body io.Reader, response interface{}) error {
+			gotEndpoint = endpoint
+			return json.NewDecoder(body).Decode(&gotPayload)
+		},
+	}
+
+	repo := github.Repo{Owner: "owner", Repo: "repo"}
+	err := mergePullRequest(context.Background(), client, repo, 42, "abc123", mergeMethodSquash)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "repos/owner/repo/pulls/42/merge", gotEndpoint)
+	assert.Equal(t, map[string]string{"sha": "abc123", "merge_method": "squash"}, gotPayload)
+}
+
+func TestMergePullRequestError(t *testing.T) {
+	t.Parallel()
+
+	client := &MockRESTClient{
+		PutFunc: func(endpoint string, body io.Reader, response interface{}) error {
+			return errors.New("HTTP 405: Pull Request is not mergeable")
+		},
+	}
+
+	err := mergePullRequest(context.Background(), client, github.Repo{Owner: "owner", Repo: "repo"}, 42, "abc123", mergeMethodMerge)
+	assert.ErrorContains(t, err, "failed to merge pull request")
+}
+
+func TestGetPullRequestNodeID(t *testing.T) {
+	t.Parallel()
+
+	client := &MockRESTClient{
+		GetFunc: func(endpoint string, response interface{}) error {
+			assert.Equal(t, "repos/owner/repo/pulls/7", endpoint)
+			return json.Unmarshal([]byte(`{"number": 7, "node_id": "PR_kwDOabc"}`), response)
+		},
+	}
+
+	nodeID, err := getPullRequestNodeID(context.Background(), client, github.Repo{Owner: "owner", Repo: "repo"}, 7)
+	assert.NoError(t, err)
+	assert.Equal(t, "PR_kwDOabc", nodeID)
+}
+
+func TestValidateMergeMethod(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		method  string
+		wantErr bool
+	}{
+		{method: "", wantErr: false},
+		{method: "merge", wantErr: false},
+		{method: "squash", wantErr: false},
+		{method: "rebase", wantErr: false},
+		{method: "SQUASH", wantErr: true},
+		{method: "fast-forward", wantErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.method, func(t *testing.T) {
+			t.Parallel()
+
+			err := ValidateMergeMethod(test.method)
+			if test.wantErr {
+				assert.ErrorIs(t, err, errInvalidMergeMethod)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
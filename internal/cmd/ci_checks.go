@@ -0,0 +1,234 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/cli/go-gh/v2/pkg/api"
+)
+
+// CI states as evaluated from the status check rollup or the individual check contexts
+const (
+	ciStatePassing = "passing"
+	ciStatePending = "pending"
+	ciStateFailing = "failing"
+	ciStateNone    = "none"
+)
+
+// Policies for --ci-pending
+const (
+	ciPendingWait  = "wait"
+	ciPendingSkip  = "skip"
+	ciPendingAllow = "allow"
+)
+
+var (
+	errInvalidCIPending  = errors.New("invalid --ci-pending value")
+	errWaitForCIConflict = errors.New("--wait-for-ci cannot be used with --ci-pending allow")
+
+	// How often pending checks are polled, backing off from the initial to the max interval
+	ciInitialPollInterval = 5 * time.Second
+	ciMaxPollInterval     = time.Minute
+
+	// How long pending checks are waited for with --ci-pending wait when --wait-for-ci is not set
+	ciDefaultWaitTimeout = 15 * time.Minute
+
+	// The deadline for pending PR checks, shared by every PR of the run so that waiting on many pending PRs
+	// takes at most one --wait-for-ci rather than one per PR. It is set when the first PR starts waiting
+	// and reset by resetPRCIDeadline
+	prCIWaitDeadline time.Time
+)
+
+// ciState evaluates the CI state of the last commit of a PR.
+// Without --require-checks or --ignore-checks the aggregate status check rollup is used.
+// Otherwise the individual check runs and commit statuses are evaluated.
+// A commit without any checks is neither passing nor failing, see ciPassing
+func ciState(response *prStatusResponse, requireChecks, ignoreChecks []string) string {
+	commits := response.Data.Repository.PullRequest.Commits.Nodes
+	if len(commits) == 0 {
+		Logger.Debug("No commits found for PR")
+		return ciStateFailing
+	}
+
+	statusCheckRollup := commits[0].Commit.StatusCheckRollup
+	if statusCheckRollup == nil && len(requireChecks) == 0 {
+		Logger.Debug("No status checks found for PR")
+		return ciStateNone
+	}
+
+	if len(requireChecks) == 0 && len(ignoreChecks) == 0 {
+		state := checkStateCategory(statusCheckRollup.State)
+		if state != ciStatePassing {
+			Logger.Debug("PR failed CI check", "status", statusCheckRollup.State)
+		}
+		return state
+	}
+
+	var contexts []checkContext
+	if statusCheckRollup != nil {
+		for _, c := range statusCheckRollup.Contexts {
+			if !checkNameMatchesAny(c.Name, ignoreChecks) {
+				contexts = append(contexts, c)
+			}
+		}
+	}
+
+	// Only the required checks are evaluated, a required check that has not reported yet is pending
+	if len(requireChecks) > 0 {
+		state := ciStatePassing
+		for _, required := range requireChecks {
+			matched := false
+			for _, c := range contexts {
+				if !checkNameMatches(c.Name, required) {
+					continue
+				}
+				matched = true
+				state = worseCIState(state, checkStateCategory(c.State))
+			}
+			if !matched {
+				Logger.Debug("Required check has not reported", "check", required)
+				state = worseCIState(state, ciStatePending)
+			}
+		}
+		return state
+	}
+
+	// Every check that is not ignored is evaluated
+	state := ciStatePassing
+	for _, c := range contexts {
+		state = worseCIState(state, checkStateCategory(c.State))
+	}
+	return state
+}
+
+// ciPassing reports whether a CI state lets a PR through. A commit without any checks only passes with --allow-no-checks,
+// since a missing rollup can also mean that CI has not registered its checks yet
+func ciPassing(state string) bool {
+	return state == ciStatePassing || (state == ciStateNone && allowNoChecks)
+}
+
+// checkStateCategory maps rollup, check run and commit status states to passing, pending or failing
+func checkStateCategory(state string) string {
+	switch state {
+	case "SUCCESS", "NEUTRAL", "SKIPPED":
+		return ciStatePassing
+	case "PENDING", "EXPECTED", "QUEUED", "IN_PROGRESS", "WAITING", "REQUESTED":
+		return ciStatePending
+	default:
+		return ciStateFailing
+	}
+}
+
+// checkRunState returns the conclusion of a completed check run, or its status while it is still running
+func checkRunState(status, conclusion string) string {
+	if status != "COMPLETED" || conclusion == "" {
+		return status
+	}
+	return conclusion
+}
+
+// worseCIState returns the worse of two CI states, where failing is worse than pending which is worse than passing
+func worseCIState(a, b string) string {
+	order := []string{ciStatePassing, ciStatePending, ciStateFailing}
+	if slices.Index(order, b) > slices.Index(order, a) {
+		return b
+	}
+	return a
+}
+
+// checkNameMatches checks if a check name matches a name or glob pattern, ignoring case
+func checkNameMatches(name, pattern string) bool {
+	match, err := newTextMatcher(pattern, false, true, true)
+	if err != nil {
+		return false
+	}
+	return match(name)
+}
+
+// checkNameMatchesAny checks if a check name matches any of the patterns
+func checkNameMatchesAny(name string, patterns []string) bool {
+	return slices.ContainsFunc(patterns, func(pattern string) bool { return checkNameMatches(name, pattern) })
+}
+
+// waitForCI polls the status of a PR with backoff until its checks are no longer pending or the deadline is reached.
+// Once the deadline has passed the status is still polled once, so a PR whose checks finished in the meantime is not skipped
+func waitForCI(ctx context.Context, graphQlClient *api.GraphQLClient, spinner *Spinner, owner, repo string, prNumber int, deadline time.Time) (*prStatusResponse, string, error) {
+	interval := ciInitialPollInterval
+
+	if spinner != nil {
+		defer spinner.UpdateMessage(fmt.Sprintf("Processing %s/%s", owner, repo))
+	}
+
+	for {
+		remaining := time.Until(deadline)
+		if spinner != nil {
+			spinner.UpdateMessage(fmt.Sprintf("Waiting for CI on %s/%s#%d (%s left)", owner, repo, prNumber, remaining.Round(time.Second)))
+		}
+		Logger.Debug("Waiting for pending CI checks", "pr", prNumber, "interval", interval, "remaining", remaining)
+
+		select {
+		case <-ctx.Done():
+			return nil, "", ctx.Err()
+		case <-time.After(min(interval, max(remaining, 0))):
+		}
+
+		response, err := GetPRStatusInfo(ctx, graphQlClient, owner, repo, prNumber)
+		if err != nil {
+			return nil, "", err
+		}
+
+		state := ciState(response, requireChecks, ignoreChecks)
+		if state != ciStatePending {
+			return response, state, nil
+		}
+		if !time.Now().Before(deadline) {
+			Logger.Debug("Timed out waiting for CI checks", "pr", prNumber, "deadline", deadline)
+			return response, state, nil
+		}
+
+		interval = nextPollInterval(interval)
+	}
+}
+
+// nextPollInterval doubles the poll interval up to ciMaxPollInterval
+func nextPollInterval(interval time.Duration) time.Duration {
+	return min(interval*2, ciMaxPollInterval)
+}
+
+// ciWaitTimeout returns how long pending checks are waited for
+func ciWaitTimeout() time.Duration {
+	if waitForCITimeout > 0 {
+		return waitForCITimeout
+	}
+	return ciDefaultWaitTimeout
+}
+
+// prCIDeadline returns the run-wide deadline for pending PR checks, starting it at now if no PR has waited yet
+func prCIDeadline(now time.Time) time.Time {
+	if prCIWaitDeadline.IsZero() {
+		prCIWaitDeadline = now.Add(ciWaitTimeout())
+	}
+	return prCIWaitDeadline
+}
+
+// resetPRCIDeadline clears the deadline for pending PR checks, so the next PR that waits gets a full timeout
+func resetPRCIDeadline() {
+	prCIWaitDeadline = time.Time{}
+}
+
+// ValidateCIPending checks that --ci-pending is one of the supported policies and does not conflict with --wait-for-ci
+func ValidateCIPending(policy string, waitTimeout time.Duration) error {
+	if !slices.Contains([]string{ciPendingWait, ciPendingSkip, ciPendingAllow}, policy) {
+		return fmt.Errorf("%w: %q (must be %s, %s or %s)", errInvalidCIPending, policy, ciPendingWait, ciPendingSkip, ciPendingAllow)
+	}
+	if waitTimeout < 0 {
+		return fmt.Errorf("invalid --wait-for-ci value: %s", waitTimeout)
+	}
+	if waitTimeout > 0 && policy == ciPendingAllow {
+		return errWaitForCIConflict
+	}
+	return nil
+}
@@ -0,0 +1,288 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+// newStatusResponse builds a prStatusResponse with a single commit from a statusCheckRollup JSON document
+func newStatusResponse(t *testing.T, rollup string) *prStatusResponse {
+	t.Helper()
+
+	response := &prStatusResponse{}
+	data := `{"data":{"repository":{"pullRequest":{"commits":{"nodes":[{"commit":{"statusCheckRollup":` + rollup + `}}]}}}}}`
+	if err := json.Unmarshal([]byte(data), response); err != nil {
+		t.Fatalf("failed to build status response: %v", err)
+	}
+	return response
+}
+
+func TestCIState(t *testing.T) {
+	t.Parallel()
+
+	contexts := `{"state":"FAILURE","contexts":[
+		{"name":"build","state":"SUCCESS"},
+		{"name":"test","state":"SUCCESS"},
+		{"name":"flaky-e2e","state":"FAILURE"},
+		{"name":"deploy-preview","state":"IN_PROGRESS"}
+	]}`
+
+	tests := []struct {
+		name          string
+		rollup        string
+		requireChecks []string
+		ignoreChecks  []string
+		want          string
+	}{
+		{
+			name:   "rollup success",
+			rollup: `{"state":"SUCCESS"}`,
+			want:   ciStatePassing,
+		},
+		{
+			name:   "rollup pending",
+			rollup: `{"state":"PENDING"}`,
+			want:   ciStatePending,
+		},
+		{
+			name:   "no checks with the rollup is none",
+			rollup: `null`,
+			want:   ciStateNone,
+		},
+		{
+			name:         "no checks with ignored checks is none",
+			rollup:       `null`,
+			ignoreChecks: []string{"flaky-*"},
+			want:         ciStateNone,
+		},
+		{
+			name:   "rollup failure",
+			rollup: contexts,
+			want:   ciStateFailing,
+		},
+		{
+			name:          "required checks pass even though an optional check fails",
+			rollup:        contexts,
+			requireChecks: []string{"build", "test"},
+			want:          ciStatePassing,
+		},
+		{
+			name:          "required check names are case insensitive globs",
+			rollup:        contexts,
+			requireChecks: []string{"BUILD", "te*"},
+			want:          ciStatePassing,
+		},
+		{
+			name:          "required check is failing",
+			rollup:        contexts,
+			requireChecks: []string{"build", "flaky-e2e"},
+			want:          ciStateFailing,
+		},
+		{
+			name:          "required check is still running",
+			rollup:        contexts,
+			requireChecks: []string{"deploy-preview"},
+			want:          ciStatePending,
+		},
+		{
+			name:          "required check has not reported",
+			rollup:        contexts,
+			requireChecks: []string{"lint"},
+			want:          ciStatePending,
+		},
+		{
+			name:          "no checks with required checks is pending",
+			rollup:        `null`,
+			requireChecks: []string{"build"},
+			want:          ciStatePending,
+		},
+		{
+			name:          "ignored check is not required",
+			rollup:        contexts,
+			requireChecks: []string{"build", "flaky-e2e"},
+			ignoreChecks:  []string{"flaky-*"},
+			want:          ciStatePending,
+		},
+		{
+			name:         "ignoring the failing check leaves the pending one",
+			rollup:       contexts,
+			ignoreChecks: []string{"flaky-*"},
+			want:         ciStatePending,
+		},
+		{
+			name:         "ignoring the failing and pending checks",
+			rollup:       contexts,
+			ignoreChecks: []string{"flaky-*", "deploy-preview"},
+			want:         ciStatePassing,
+		},
+		{
+			name:          "neutral and skipped checks pass",
+			rollup:        `{"state":"SUCCESS","contexts":[{"name":"a","state":"NEUTRAL"},{"name":"b","state":"SKIPPED"}]}`,
+			requireChecks: []string{"a", "b"},
+			want:          ciStatePassing,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			response := newStatusResponse(t, test.rollup)
+			if got := ciState(response, test.requireChecks, test.ignoreChecks); got != test.want {
+				t.Errorf("ciState() = %q; want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestCIStateNoCommits(t *testing.T) {
+	t.Parallel()
+
+	if got := ciState(&prStatusResponse{}, []string{"build"}, nil); got != ciStateFailing {
+		t.Errorf("ciState() = %q; want %q", got, ciStateFailing)
+	}
+}
+
+func TestCIPassing(t *testing.T) {
+	// Since this test changes global state, don't use t.Parallel()
+	origAllowNoChecks := allowNoChecks
+	defer func() { allowNoChecks = origAllowNoChecks }()
+
+	tests := []struct {
+		state         string
+		allowNoChecks bool
+		want          bool
+	}{
+		{state: ciStatePassing, want: true},
+		{state: ciStatePending, want: false},
+		{state: ciStateFailing, want: false},
+		{state: ciStateNone, want: false},
+		{state: ciStateNone, allowNoChecks: true, want: true},
+		{state: ciStateFailing, allowNoChecks: true, want: false},
+	}
+
+	for _, test := range tests {
+		allowNoChecks = test.allowNoChecks
+		if got := ciPassing(test.state); got != test.want {
+			t.Errorf("ciPassing(%q) with allowNoChecks=%v = %v; want %v", test.state, test.allowNoChecks, got, test.want)
+		}
+	}
+}
+
+func TestSetStatusCheckRollup(t *testing.T) {
+	t.Parallel()
+
+	response := &prStatusResponse{}
+	setStatusCheckRollup(response, "PENDING", []checkContext{{Name: "build", State: "SUCCESS"}})
+	setStatusCheckRollup(response, "PENDING", []checkContext{{Name: "test", State: "IN_PROGRESS"}})
+
+	rollup := response.Data.Repository.PullRequest.Commits.Nodes[0].Commit.StatusCheckRollup
+	if rollup.State != "PENDING" {
+		t.Errorf("rollup state = %q; want %q", rollup.State, "PENDING")
+	}
+	want := []checkContext{{Name: "build", State: "SUCCESS"}, {Name: "test", State: "IN_PROGRESS"}}
+	if !slices.Equal(rollup.Contexts, want) {
+		t.Errorf("rollup contexts = %v; want %v", rollup.Contexts, want)
+	}
+	if got := ciState(response, []string{"build", "test"}, nil); got != ciStatePending {
+		t.Errorf("ciState() = %q; want %q", got, ciStatePending)
+	}
+}
+
+func TestValidateCIPending(t *testing.T) {
+	t.Parallel()
+
+	for _, policy := range []string{ciPendingWait, ciPendingSkip, ciPendingAllow} {
+		if err := ValidateCIPending(policy, 0); err != nil {
+			t.Errorf("ValidateCIPending(%q) returned error: %v", policy, err)
+		}
+	}
+
+	if err := ValidateCIPending("later", 0); !errors.Is(err, errInvalidCIPending) {
+		t.Errorf("want %v, got %v", errInvalidCIPending, err)
+	}
+
+	if err := ValidateCIPending(ciPendingWait, 10*time.Minute); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	if err := ValidateCIPending(ciPendingAllow, 10*time.Minute); !errors.Is(err, errWaitForCIConflict) {
+		t.Errorf("want %v, got %v", errWaitForCIConflict, err)
+	}
+
+	if err := ValidateCIPending(ciPendingWait, -time.Minute); err == nil {
+		t.Error("expected an error for a negative --wait-for-ci")
+	}
+}
+
+func TestNextPollInterval(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		interval time.Duration
+		want     time.Duration
+	}{
+		{interval: 5 * time.Second, want: 10 * time.Second},
+		{interval: 20 * time.Second, want: 40 * time.Second},
+		{interval: 40 * time.Second, want: ciMaxPollInterval},
+		{interval: ciMaxPollInterval, want: ciMaxPollInterval},
+	}
+
+	for _, test := range tests {
+		if got := nextPollInterval(test.interval); got != test.want {
+			t.Errorf("nextPollInterval(%s) = %s; want %s", test.interval, got, test.want)
+		}
+	}
+}
+
+func TestCIWaitTimeout(t *testing.T) {
+	// Since this test changes global state, don't use t.Parallel()
+	origWaitForCITimeout := waitForCITimeout
+	defer func() {
+		waitForCITimeout = origWaitForCITimeout
+	}()
+
+	waitForCITimeout = 0
+	if got := ciWaitTimeout(); got != ciDefaultWaitTimeout {
+		t.Errorf("ciWaitTimeout() = %s; want %s", got, ciDefaultWaitTimeout)
+	}
+
+	waitForCITimeout = 3 * time.Minute
+	if got := ciWaitTimeout(); got != 3*time.Minute {
+		t.Errorf("ciWaitTimeout() = %s; want %s", got, 3*time.Minute)
+	}
+}
+
+func TestPRCIDeadline(t *testing.T) {
+	// Since this test changes global state, don't use t.Parallel()
+	origWaitForCITimeout := waitForCITimeout
+	origDeadline := prCIWaitDeadline
+	defer func() {
+		waitForCITimeout = origWaitForCITimeout
+		prCIWaitDeadline = origDeadline
+	}()
+
+	waitForCITimeout = 10 * time.Minute
+	prCIWaitDeadline = time.Time{}
+	start := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	first := prCIDeadline(start)
+	if want := start.Add(10 * time.Minute); !first.Equal(want) {
+		t.Errorf("prCIDeadline() = %s; want %s", first, want)
+	}
+
+	// Later PRs share the deadline of the first one instead of getting a full timeout each
+	if got := prCIDeadline(start.Add(8 * time.Minute)); !got.Equal(first) {
+		t.Errorf("prCIDeadline() = %s; want %s", got, first)
+	}
+
+	// A new run or re-evaluation does not inherit the expired deadline
+	resetPRCIDeadline()
+	later := start.Add(time.Hour)
+	if got, want := prCIDeadline(later), later.Add(10*time.Minute); !got.Equal(want) {
+		t.Errorf("prCIDeadline() after reset = %s; want %s", got, want)
+	}
+}
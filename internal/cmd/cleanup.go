@@ -0,0 +1,345 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/cli/go-gh/v2/pkg/api"
+	"github.com/spf13/cobra"
+
+	"github.com/github/gh-combine/internal/github"
+)
+
+var olderThan time.Duration
+
+// minTransientBranchAge is how old working, scratch and bisect branches have to be before they are deleted, even
+// with --older-than 0, so that the branches of a run that is still in progress are left alone
+const minTransientBranchAge = time.Hour
+
+// StaleBranch is a branch created by gh-combine that is no longer used by an open PR
+type StaleBranch struct {
+	Name    string
+	Reason  string
+	Age     time.Duration
+	Deleted bool
+	Error   error
+}
+
+// CleanupResult is the outcome of cleaning up the stale branches of a repository
+type CleanupResult struct {
+	Repo     github.Repo
+	Branches []StaleBranch
+	Error    error
+}
+
+// branch is a branch as returned by the branches API
+type branch struct {
+	Name      string `json:"name"`
+	Protected bool   `json:"protected"`
+	Commit    struct {
+		SHA string `json:"sha"`
+	} `json:"commit"`
+}
+
+// newCleanupCmd creates the cleanup subcommand, which deletes combined, working and scratch branches left behind
+func newCleanupCmd() *cobra.Command {
+	cleanupCmd := &cobra.Command{
+		Use:   "cleanup owner/repo",
+		Short: "Delete stale branches left behind by gh-combine",
+		Long: `Delete the combined, working and dry-run scratch branches left behind by interrupted runs and old combined PRs.
+    A branch is stale when it has no open PR, or when its combined PR was merged or closed.
+    Branches are found by the --combine-branch-name and --working-branch-suffix names.
+    Working, scratch and bisect branches are only deleted once they have not changed for an hour, even with --older-than 0.
+    Examples:
+      gh combine cleanup owner/repo                     # Delete the stale branches of a repository
+      gh combine cleanup owner/repo --dry-run           # List the stale branches without deleting them
+      gh combine cleanup --file repos.txt --older-than 168h # Delete stale branches that have not changed for a week`,
+		Args: cobra.ArbitraryArgs,
+		RunE: runCleanup,
+	}
+
+	cleanupCmd.Flags().StringVar(&reposFile, "file", "", "File containing repository names, one per line")
+	cleanupCmd.Flags().StringVar(&combineBranchName, "combine-branch-name", "combined-prs", "Name of the combined PR branch")
+	cleanupCmd.Flags().StringVar(&workingBranchSuffix, "working-branch-suffix", "-working", "Suffix of the working branch")
+	cleanupCmd.Flags().DurationVar(&olderThan, "older-than", 0, "Only delete branches whose last commit is older than this (e.g. 72h)")
+	cleanupCmd.Flags().BoolVar(&dryRun, "dry-run", false, "Only list the stale branches without deleting them")
+
+	return cleanupCmd
+}
+
+// runCleanup is the main execution function for the cleanup command
+func runCleanup(cmd *cobra.Command, args []string) error {
+	ctx, cancel := SetupSignalContext()
+	defer cancel()
+
+	repos, err := ParseRepositories(args, reposFile)
+	if err != nil {
+		return fmt.Errorf("failed to parse repositories: %w", err)
+	}
+	if len(repos) == 0 {
+		return errors.New("no repositories specified")
+	}
+
+	restClient, err := api.DefaultRESTClient()
+	if err != nil {
+		return fmt.Errorf("failed to create REST client: %w", err)
+	}
+
+	restClientWrapper := struct {
+		RESTClientInterface
+	}{restClient}
+
+	var results []CleanupResult
+	for _, repo := range repos {
+		// Check for cancellation
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+			// Continue processing
+		}
+
+		result := CleanupResult{Repo: repo}
+		result.Branches, result.Error = cleanupRepository(ctx, restClientWrapper, repo, time.Now())
+		results = append(results, result)
+	}
+
+	displayCleanupResults(results)
+	return nil
+}
+
+// cleanupRepository deletes the stale branches of a repository, or only lists them with --dry-run
+func cleanupRepository(ctx context.Context, client RESTClientInterface, repo github.Repo, now time.Time) ([]StaleBranch, error) {
+	stale, err := findStaleBranches(ctx, client, repo, now)
+	if err != nil {
+		return nil, err
+	}
+	if dryRun {
+		return stale, nil
+	}
+
+	for i := range stale {
+		if err := deleteBranch(ctx, client, repo, stale[i].Name); err != nil {
+			Logger.Warn("Failed to delete stale branch", "repo", repo, "branch", stale[i].Name, "error", err)
+			stale[i].Error = err
+			continue
+		}
+		Logger.Debug("Deleted stale branch", "repo", repo, "branch", stale[i].Name)
+		stale[i].Deleted = true
+	}
+	return stale, nil
+}
+
+// findStaleBranches returns the branches created by gh-combine that have no open PR, or whose PR was merged or
+// closed, and whose last commit is older than --older-than. Working, scratch and bisect branches also have to be
+// older than minTransientBranchAge
+func findStaleBranches(ctx context.Context, client RESTClientInterface, repo github.Repo, now time.Time) ([]StaleBranch, error) {
+	defaultBranch, err := getDefaultBranch(ctx, client, repo)
+	if err != nil {
+		return nil, err
+	}
+
+	branches, err := listBranches(ctx, client, repo)
+	if err != nil {
+		return nil, err
+	}
+
+	openPulls, err := listPullRequests(ctx, client, repo, "open")
+	if err != nil {
+		return nil, err
+	}
+
+	open := make(map[string]bool)
+	for _, pull := range openPulls {
+		if !pull.FromFork(repo) {
+			open[pull.Head.Ref] = true
+		}
+	}
+
+	var stale []StaleBranch
+	for _, b := range branches {
+		// Check for cancellation
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		default:
+			// Continue processing
+		}
+
+		if b.Name == defaultBranch || b.Protected || open[b.Name] || !isCombineBranchName(b.Name) {
+			continue
+		}
+
+		minAge := olderThan
+		if b.Name != combineBranchName {
+			minAge = max(minAge, minTransientBranchAge)
+		}
+
+		age, err := commitAge(ctx, client, repo, b.Commit.SHA, now)
+		if err != nil {
+			return nil, err
+		}
+		if age < minAge {
+			Logger.Debug("Stale branch is too recent", "repo", repo, "branch", b.Name, "age", age, "min_age", minAge)
+			continue
+		}
+
+		pull, err := lastClosedCombinedPR(ctx, client, repo, b.Name)
+		if err != nil {
+			return nil, err
+		}
+
+		reason := "no open PR"
+		switch {
+		case pull != nil && pull.MergedAt != nil:
+			reason = fmt.Sprintf("PR #%d was merged", pull.Number)
+		case pull != nil:
+			reason = fmt.Sprintf("PR #%d was closed", pull.Number)
+		}
+		stale = append(stale, StaleBranch{Name: b.Name, Reason: reason, Age: age})
+	}
+	return stale, nil
+}
+
+// isCombineBranchName reports whether a branch name is one gh-combine uses for the combined, working, scratch
+// or bisect branch
+func isCombineBranchName(name string) bool {
+	return name == combineBranchName ||
+		name == combineBranchName+workingBranchSuffix ||
+		strings.HasPrefix(name, combineBranchName+scratchBranchInfix) ||
+		strings.HasPrefix(name, combineBranchName+bisectBranchInfix)
+}
+
+// lastClosedCombinedPR returns the most recent closed PR created by gh-combine from a branch, or nil if there is none.
+// Only the closed PRs of the branch are fetched, rather than every closed PR of the repository
+func lastClosedCombinedPR(ctx context.Context, client RESTClientInterface, repo github.Repo, branch string) (*github.Pull, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	default:
+		// Continue processing
+	}
+
+	var pulls github.Pulls
+	endpoint := fmt.Sprintf("repos/%s/%s/pulls?state=closed&head=%s:%s&per_page=100", repo.Owner, repo.Repo, repo.Owner, branch)
+	if err := client.Get(endpoint, &pulls); err != nil {
+		return nil, fmt.Errorf("failed to fetch closed pull requests of %s: %w", branch, err)
+	}
+
+	// The list is sorted by creation date, newest first
+	for _, pull := range pulls {
+		if !pull.FromFork(repo) && isCombinedPRBody(pull.Body) {
+			return &pull, nil
+		}
+	}
+	return nil, nil
+}
+
+// listBranches fetches all branches of a repository, handling pagination
+func listBranches(ctx context.Context, client RESTClientInterface, repo github.Repo) ([]branch, error) {
+	var allBranches []branch
+	for page := 1; ; page++ {
+		// Check for cancellation
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		default:
+			// Continue processing
+		}
+
+		var branches []branch
+		endpoint := fmt.Sprintf("repos/%s/%s/branches?per_page=100&page=%d", repo.Owner, repo.Repo, page)
+		if err := client.Get(endpoint, &branches); err != nil {
+			return nil, fmt.Errorf("failed to fetch branches from page %d: %w", page, err)
+		}
+		allBranches = append(allBranches, branches...)
+
+		// If fewer than 100 branches are returned, we've reached the last page
+		if len(branches) < 100 {
+			return allBranches, nil
+		}
+	}
+}
+
+// listPullRequests fetches all pull requests of a repository in a state, newest first, handling pagination
+func listPullRequests(ctx context.Context, client RESTClientInterface, repo github.Repo, state string) (github.Pulls, error) {
+	var allPulls github.Pulls
+	for page := 1; ; page++ {
+		// Check for cancellation
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		default:
+			// Continue processing
+		}
+
+		var pulls github.Pulls
+		endpoint := fmt.Sprintf("repos/%s/%s/pulls?state=%s&per_page=100&page=%d", repo.Owner, repo.Repo, state, page)
+		if err := client.Get(endpoint, &pulls); err != nil {
+			return nil, fmt.Errorf("failed to fetch %s pull requests from page %d: %w", state, page, err)
+		}
+		allPulls = append(allPulls, pulls...)
+
+		// If fewer than 100 PRs are returned, we've reached the last page
+		if len(pulls) < 100 {
+			return allPulls, nil
+		}
+	}
+}
+
+// commitAge returns how long ago a commit was committed
+func commitAge(ctx context.Context, client RESTClientInterface, repo github.Repo, sha string, now time.Time) (time.Duration, error) {
+	var commit struct {
+		Commit struct {
+			Committer struct {
+				Date time.Time `json:"date"`
+			} `json:"committer"`
+		} `json:"commit"`
+	}
+	if err := client.Get(fmt.Sprintf("repos/%s/%s/commits/%s", repo.Owner, repo.Repo, sha), &commit); err != nil {
+		return 0, fmt.Errorf("failed to get commit %s: %w", sha, err)
+	}
+	return now.Sub(commit.Commit.Committer.Date), nil
+}
+
+// formatAge formats a duration in the largest whole unit of days, hours or minutes
+func formatAge(age time.Duration) string {
+	switch {
+	case age >= 24*time.Hour:
+		return fmt.Sprintf("%dd", int(age/(24*time.Hour)))
+	case age >= time.Hour:
+		return fmt.Sprintf("%dh", int(age/time.Hour))
+	default:
+		return fmt.Sprintf("%dm", int(age/time.Minute))
+	}
+}
+
+// displayCleanupResults prints the stale branches of every repository and whether they were deleted
+func displayCleanupResults(results []CleanupResult) {
+	for _, result := range results {
+		if result.Error != nil {
+			fmt.Printf("- %s %s\n", result.Repo, colorize("failed: "+result.Error.Error(), colorYellow))
+			continue
+		}
+		if len(result.Branches) == 0 {
+			fmt.Printf("- %s no stale branches\n", result.Repo)
+			continue
+		}
+
+		fmt.Printf("- %s\n", result.Repo)
+		for _, stale := range result.Branches {
+			details := fmt.Sprintf("%s, last commit %s ago", stale.Reason, formatAge(stale.Age))
+			switch {
+			case stale.Error != nil:
+				fmt.Printf("  - %s (%s) %s\n", stale.Name, details, colorize("failed: "+stale.Error.Error(), colorYellow))
+			case stale.Deleted:
+				fmt.Printf("  - %s (%s) deleted\n", stale.Name, details)
+			default:
+				fmt.Printf("  - %s (%s) would be deleted\n", stale.Name, details)
+			}
+		}
+	}
+}
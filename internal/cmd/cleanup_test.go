@@ -0,0 +1,116 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/github/gh-combine/internal/github"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCleanupRepository(t *testing.T) {
+	// Since this test changes global state, don't use t.Parallel()
+	origBranch, origSuffix, origOlderThan, origDryRun := combineBranchName, workingBranchSuffix, olderThan, dryRun
+	defer func() {
+		combineBranchName, workingBranchSuffix, olderThan, dryRun = origBranch, origSuffix, origOlderThan, origDryRun
+	}()
+	combineBranchName = "combined-prs"
+	workingBranchSuffix = "-working"
+
+	now := time.Date(2025, 1, 10, 12, 0, 0, 0, time.UTC)
+	responses := map[string]string{
+		"repos/owner/repo": `{"default_branch": "main"}`,
+		"repos/owner/repo/branches?per_page=100&page=1": `[
+			{"name": "main", "commit": {"sha": "main"}},
+			{"name": "combined-prs", "commit": {"sha": "old"}},
+			{"name": "combined-prs-working", "commit": {"sha": "recent"}},
+			{"name": "combined-prs-dry-run-abc", "commit": {"sha": "old"}},
+			{"name": "combined-prs-bisect-def", "commit": {"sha": "fresh"}},
+			{"name": "deps-combined", "commit": {"sha": "old"}},
+			{"name": "open-combined", "commit": {"sha": "old"}},
+			{"name": "feature", "commit": {"sha": "old"}},
+			{"name": "combined-prs-protected", "protected": true, "commit": {"sha": "old"}}
+		]`,
+		"repos/owner/repo/pulls?state=open&per_page=100&page=1": `[
+			{"number": 9, "head": {"ref": "open-combined", "repo": {"full_name": "owner/repo"}}},
+			{"number": 8, "head": {"ref": "combined-prs", "repo": {"full_name": "someone/repo"}}}
+		]`,
+		"repos/owner/repo/pulls?state=closed&head=owner:combined-prs&per_page=100": `[
+			{"number": 6, "body": "Custom body\n\n<!-- gh-combine:prs=1,2 -->", "head": {"ref": "combined-prs", "repo": {"full_name": "owner/repo"}}},
+			{"number": 4, "body": "Custom body\n\n<!-- gh-combine:prs=1 -->", "merged_at": "2024-12-01T00:00:00Z", "head": {"ref": "combined-prs", "repo": {"full_name": "owner/repo"}}}
+		]`,
+		"repos/owner/repo/pulls?state=closed&head=owner:combined-prs-working&per_page=100": `[
+			{"number": 5, "body": "Not combined", "head": {"ref": "combined-prs-working", "repo": {"full_name": "owner/repo"}}}
+		]`,
+		"repos/owner/repo/pulls?state=closed&head=owner:combined-prs-dry-run-abc&per_page=100": `[]`,
+		"repos/owner/repo/commits/old":    `{"commit": {"committer": {"date": "2025-01-01T12:00:00Z"}}}`,
+		"repos/owner/repo/commits/recent": `{"commit": {"committer": {"date": "2025-01-10T10:00:00Z"}}}`,
+		"repos/owner/repo/commits/fresh":  `{"commit": {"committer": {"date": "2025-01-10T11:30:00Z"}}}`,
+	}
+
+	tests := []struct {
+		name        string
+		dryRun      bool
+		olderThan   time.Duration
+		want        []StaleBranch
+		wantDeleted []string
+	}{
+		{
+			name:   "dry run",
+			dryRun: true,
+			want: []StaleBranch{
+				{Name: "combined-prs", Reason: "PR #6 was closed", Age: 9 * 24 * time.Hour},
+				{Name: "combined-prs-working", Reason: "no open PR", Age: 2 * time.Hour},
+				{Name: "combined-prs-dry-run-abc", Reason: "no open PR", Age: 9 * 24 * time.Hour},
+			},
+		},
+		{
+			name:      "older than",
+			olderThan: 24 * time.Hour,
+			want: []StaleBranch{
+				{Name: "combined-prs", Reason: "PR #6 was closed", Age: 9 * 24 * time.Hour, Deleted: true},
+				{Name: "combined-prs-dry-run-abc", Reason: "no open PR", Age: 9 * 24 * time.Hour, Deleted: true},
+			},
+			wantDeleted: []string{"combined-prs", "combined-prs-dry-run-abc"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dryRun = test.dryRun
+			olderThan = test.olderThan
+
+			var deleted []string
+			client := &MockRESTClient{
+				GetFunc: func(endpoint string, response interface{}) error {
+					data, ok := responses[endpoint]
+					if !ok {
+						return errors.New("unexpected endpoint " + endpoint)
+					}
+					return json.Unmarshal([]byte(data), response)
+				},
+				DeleteFunc: func(endpoint string, response interface{}) error {
+					deleted = append(deleted, strings.TrimPrefix(endpoint, "repos/owner/repo/git/refs/heads/"))
+					return nil
+				},
+			}
+
+			stale, err := cleanupRepository(context.Background(), client, github.Repo{Owner: "owner", Repo: "repo"}, now)
+			assert.NoError(t, err)
+			assert.Equal(t, test.want, stale)
+			assert.Equal(t, test.wantDeleted, deleted)
+		})
+	}
+}
+
+func TestFormatAge(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "3d", formatAge(3*24*time.Hour+5*time.Hour))
+	assert.Equal(t, "5h", formatAge(5*time.Hour+30*time.Minute))
+	assert.Equal(t, "12m", formatAge(12*time.Minute))
+}
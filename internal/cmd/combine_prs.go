@@ -6,6 +6,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"slices"
 	"strings"
 
 	"github.com/cli/go-gh/v2/pkg/api"
@@ -18,115 +19,243 @@ type RESTClientInterface interface {
 	Get(endpoint string, response interface{}) error
 	Delete(endpoint string, response interface{}) error
 	Patch(endpoint string, body io.Reader, response interface{}) error
+	Put(endpoint string, body io.Reader, response interface{}) error
 }
 
 // CombineOpts holds options for combining PRs
 // Use this struct to pass options to CombinePRsWithStats and related functions
 // This makes the code more maintainable and clear
 type CombineOpts struct {
-	Noop    bool
-	Command string
-	Repo    github.Repo
-	Pulls   github.Pulls
+	Noop     bool
+	VerifyCI bool
+	Command  string
+	Repo     github.Repo
+	Pulls    github.Pulls
+	// Journal records the changes of the run for the undo command, nil when they are not recorded
+	Journal *RunJournal
 }
 
-// CombinePRsWithStats combines PRs and returns stats for summary output
-func CombinePRsWithStats(ctx context.Context, graphQlClient *api.GraphQLClient, restClient RESTClientInterface, opts CombineOpts) (combined []string, mergeConflicts []string, combinedPRLink string, err error) {
+// CombineResult holds the outcome of combining PRs
+// Use this struct to report the combined, conflicting and ejected PRs back to the caller
+type CombineResult struct {
+	Combined       github.Pulls
+	MergeConflicts github.Pulls
+	Ejected        github.Pulls
+	PRNumber       int
+	PRLink         string
+	CIStatus       string
+	MergeStatus    string
+	MergeError     string
+	// UnsignedReason tells why the combined branch has unsigned merge commits with --commit-mode signed
+	UnsignedReason string
+	MetadataErrors []string
+	Moved          []string
+	Plan           *DryRunPlan
+	// Outdated are the combined PRs that got new commits during the run and were combined at the evaluated SHA
+	Outdated github.Pulls
+}
+
+// CombinePRsWithStats combines PRs and returns stats for summary output. The changes made to the repository
+// are recorded in opts.Journal, and branches created by a combine that fails before its PR exists are rolled back
+func CombinePRsWithStats(ctx context.Context, graphQlClient *api.GraphQLClient, restClient RESTClientInterface, opts CombineOpts) (*CombineResult, error) {
+	run := &RepoRun{Repo: opts.Repo.String()}
+	result, err := combinePRs(ctx, graphQlClient, restClient, opts, run)
+	if err != nil {
+		err = rollbackCombine(ctx, restClient, opts.Repo, run, err)
+	}
+	opts.Journal.record(*run)
+	return result, err
+}
+
+// combinePRs performs the combine and records every branch and PR it creates or deletes in run
+func combinePRs(ctx context.Context, graphQlClient *api.GraphQLClient, restClient RESTClientInterface, opts CombineOpts, run *RepoRun) (*CombineResult, error) {
+	result := &CombineResult{}
 	workingBranchName := combineBranchName + workingBranchSuffix
 
 	repoDefaultBranch, err := getDefaultBranch(ctx, restClient, opts.Repo)
 	if err != nil {
-		return nil, nil, "", fmt.Errorf("failed to get default branch: %w", err)
+		return result, fmt.Errorf("failed to get default branch: %w", err)
 	}
 
 	baseBranchSHA, err := getBranchSHA(ctx, restClient, opts.Repo, repoDefaultBranch)
 	if err != nil {
-		return nil, nil, "", fmt.Errorf("failed to get SHA of main branch: %w", err)
+		return result, fmt.Errorf("failed to get SHA of main branch: %w", err)
 	}
 
 	if opts.Noop {
-		Logger.Debug("Dry-run mode enabled. No changes will be made.")
-		Logger.Debug("Simulating branch operations", "workingBranch", workingBranchName, "defaultBranch", repoDefaultBranch)
+		Logger.Debug("Dry-run mode enabled. Only a scratch branch is created for trial merges.")
+		return planCombine(ctx, restClient, opts, repoDefaultBranch, baseBranchSHA)
+	}
+
+	// PRs are merged at the head SHA that was evaluated, report the ones that got new commits since
+	opts.Pulls, result.Moved, result.Outdated, err = checkMovedPRs(ctx, graphQlClient, restClient, opts.Repo, opts.Pulls)
+	if err != nil {
+		return result, err
+	}
+
+	err = deleteBranch(ctx, restClient, opts.Repo, workingBranchName)
+	if err != nil {
+		Logger.Debug("Working branch not found, continuing", "branch", workingBranchName)
+	}
+
+	err = deleteBranch(ctx, restClient, opts.Repo, combineBranchName)
+	if err != nil {
+		Logger.Debug("Combined branch not found, continuing", "branch", combineBranchName)
+	} else {
+		run.DeletedBranches = append(run.DeletedBranches, combineBranchName)
+	}
+
+	err = createBranch(ctx, restClient, opts.Repo, combineBranchName, baseBranchSHA)
+	if err != nil {
+		return result, fmt.Errorf("failed to create combined branch: %w", err)
+	}
+	run.CreatedBranches = append(run.CreatedBranches, combineBranchName)
+
+	result.Combined, result.MergeConflicts, result.UnsignedReason, err = buildCombinedBranch(ctx, graphQlClient, restClient, opts.Repo, baseBranchSHA, opts.Pulls)
+	if err != nil {
+		return result, err
+	}
+
+	data := newPRTemplateData(opts.Repo, result.Combined, result.MergeConflicts, nil, result.Outdated, opts.Command)
+	data.RunID = opts.Journal.runID()
+	prTitle, prBody, err := renderPRTitleAndBody(data)
+	if err != nil {
+		return result, fmt.Errorf("failed to render combined PR: %w", err)
+	}
+	prNumber, prErr := createPullRequestWithNumber(ctx, restClient, opts.Repo, prTitle, combineBranchName, repoDefaultBranch, prBody, draft)
+	if prErr != nil {
+		return result, fmt.Errorf("failed to create combined PR: %w", prErr)
+	}
+	if prNumber > 0 {
+		run.PRNumber = prNumber
+		result.PRNumber = prNumber
+		result.PRLink = fmt.Sprintf("https://github.com/%s/%s/pull/%d", opts.Repo.Owner, opts.Repo.Repo, prNumber)
+
+		// The combined PR exists at this point, so metadata failures are reported instead of failing the run
+		result.MetadataErrors = applyPRMetadata(ctx, graphQlClient, restClient, opts.Repo, prNumber, result.Combined)
 	}
 
-	if !opts.Noop {
-		err = deleteBranch(ctx, restClient, opts.Repo, workingBranchName)
+	if opts.VerifyCI && prNumber > 0 {
+		err = verifyCombinedPR(ctx, graphQlClient, restClient, opts, repoDefaultBranch, baseBranchSHA, result)
 		if err != nil {
-			Logger.Debug("Working branch not found, continuing", "branch", workingBranchName)
+			return result, fmt.Errorf("failed to verify CI of combined PR: %w", err)
 		}
+	}
 
-		err = deleteBranch(ctx, restClient, opts.Repo, combineBranchName)
+	if (autoMergeMethod != "" || mergeWhenGreen) && prNumber > 0 {
+		result.MergeStatus, err = mergeCombinedPR(ctx, graphQlClient, restClient, opts.Repo, prNumber)
 		if err != nil {
-			Logger.Debug("Combined branch not found, continuing", "branch", combineBranchName)
+			// The combined PR exists, so report the failure without failing the whole run
+			Logger.Warn("Failed to merge combined PR", "repo", opts.Repo, "pr", prNumber, "error", err)
+			result.MergeError = err.Error()
 		}
+	}
 
-		err = createBranch(ctx, restClient, opts.Repo, combineBranchName, baseBranchSHA)
-		if err != nil {
-			return nil, nil, "", fmt.Errorf("failed to create combined branch: %w", err)
+	return result, nil
+}
+
+// buildCombinedBranch builds the combined branch from the PRs, see buildBranch
+func buildCombinedBranch(ctx context.Context, graphQlClient *api.GraphQLClient, restClient RESTClientInterface, repo github.Repo, baseSHA string, pulls github.Pulls) (github.Pulls, github.Pulls, string, error) {
+	return buildBranch(ctx, graphQlClient, restClient, repo, combineBranchName, baseSHA, pulls)
+}
+
+// buildBranch merges the PRs one by one into a working branch created from baseSHA and then points the target
+// branch, which must already exist, at the result. PRs that fail to merge are returned as conflicts. With
+// --commit-mode signed, the reason the merge commits were kept unsigned is returned when the signed commit fails
+func buildBranch(ctx context.Context, graphQlClient *api.GraphQLClient, restClient RESTClientInterface, repo github.Repo, target, baseSHA string, pulls github.Pulls) (combined github.Pulls, mergeConflicts github.Pulls, unsignedReason string, err error) {
+	workingBranchName := target + workingBranchSuffix
+
+	err = createBranch(ctx, restClient, repo, workingBranchName, baseSHA)
+	if err != nil {
+		return nil, nil, "", fmt.Errorf("failed to create working branch: %w", err)
+	}
+
+	// A failed build must not leave the working branch behind
+	defer func() {
+		if err == nil {
+			return
+		}
+		if delErr := deleteBranch(ctx, restClient, repo, workingBranchName); delErr != nil {
+			Logger.Warn("Failed to delete working branch", "branch", workingBranchName, "error", delErr)
+			err = fmt.Errorf("%w (working branch %s remains)", err, workingBranchName)
 		}
+	}()
 
-		err = createBranch(ctx, restClient, opts.Repo, workingBranchName, baseBranchSHA)
+	head, tree := baseSHA, ""
+	for _, pr := range pulls {
+		merge, err := mergeBranch(ctx, restClient, repo, workingBranchName, mergeHead(repo, pr), mergeCommitMessage(pr))
 		if err != nil {
-			return nil, nil, "", fmt.Errorf("failed to create working branch: %w", err)
+			if isMergeConflictError(err) {
+				Logger.Debug("Merge conflict", "branch", pr.Head.Ref, "error", err)
+			} else {
+				Logger.Warn("Failed to merge branch", "branch", pr.Head.Ref, "error", err)
+			}
+			mergeConflicts = append(mergeConflicts, pr)
+			continue
 		}
-	}
 
-	combinedPrNumbers := []string{}
-	for _, pr := range opts.Pulls {
-		if opts.Noop {
-			Logger.Debug("Simulating merge of branch", "branch", pr.Head.Ref)
-			combined = append(combined, fmt.Sprintf("#%d - %s", pr.Number, pr.Title))
-			combinedPrNumbers = append(combinedPrNumbers, fmt.Sprintf("#%d", pr.Number))
-		} else {
-			err := mergeBranch(ctx, restClient, opts.Repo, workingBranchName, pr.Head.Ref)
+		Logger.Debug("Merged branch", "branch", pr.Head.Ref)
+		combined = append(combined, pr)
+
+		// An empty merge commit means the branch was already merged, so there is nothing to squash
+		if merge.SHA == "" {
+			continue
+		}
+		tree = merge.Commit.Tree.SHA
+
+		if commitMode == commitModeSquashEach {
+			head, err = replaceWorkingHead(ctx, restClient, repo, workingBranchName, squashCommitMessage(pr), tree, head)
 			if err != nil {
-				if isMergeConflictError(err) {
-					Logger.Debug("Merge conflict", "branch", pr.Head.Ref, "error", err)
-				} else {
-					Logger.Warn("Failed to merge branch", "branch", pr.Head.Ref, "error", err)
-				}
-				mergeConflicts = append(mergeConflicts, fmt.Sprintf("#%d", pr.Number))
-			} else {
-				Logger.Debug("Merged branch", "branch", pr.Head.Ref)
-				combined = append(combined, fmt.Sprintf("#%d - %s", pr.Number, pr.Title))
-				combinedPrNumbers = append(combinedPrNumbers, fmt.Sprintf("#%d", pr.Number))
+				return combined, mergeConflicts, "", fmt.Errorf("failed to squash #%d: %w", pr.Number, err)
 			}
 		}
 	}
 
-	if !opts.Noop {
-		err = updateRef(ctx, restClient, opts.Repo, combineBranchName, workingBranchName)
+	if commitMode == commitModeSingle && tree != "" {
+		_, err = replaceWorkingHead(ctx, restClient, repo, workingBranchName, singleCommitMessage(combined), tree, baseSHA)
 		if err != nil {
-			return combined, mergeConflicts, "", fmt.Errorf("failed to update combined branch: %w", err)
+			return combined, mergeConflicts, "", fmt.Errorf("failed to squash combined PRs: %w", err)
 		}
+	}
 
-		err = deleteBranch(ctx, restClient, opts.Repo, workingBranchName)
+	signed := false
+	if commitMode == commitModeSigned && tree != "" {
+		err = createSignedCommit(ctx, graphQlClient, restClient, repo, target, baseSHA, workingBranchName, singleCommitMessage(combined))
 		if err != nil {
-			Logger.Warn("Failed to delete working branch", "branch", workingBranchName, "error", err)
+			// Unsigned commits can only be used when no ruleset requires signed commits
+			if fallbackErr := checkUnsignedFallback(ctx, restClient, repo, target, err); fallbackErr != nil {
+				return combined, mergeConflicts, "", fallbackErr
+			}
+			Logger.Warn("Failed to create signed commit, falling back to merge commits", "repo", repo, "error", err)
+			unsignedReason = err.Error()
 		}
+		signed = err == nil
+	}
 
-		prBody := generatePRBody(combinedPrNumbers, mergeConflicts, opts.Command)
-		prTitle := "Combined PRs"
-		prNumber, prErr := createPullRequestWithNumber(ctx, restClient, opts.Repo, prTitle, combineBranchName, repoDefaultBranch, prBody, addLabels, addAssignees)
-		if prErr != nil {
-			return combined, mergeConflicts, "", fmt.Errorf("failed to create combined PR: %w", prErr)
-		}
-		if prNumber > 0 {
-			combinedPRLink = fmt.Sprintf("https://github.com/%s/%s/pull/%d", opts.Repo.Owner, opts.Repo.Repo, prNumber)
+	if !signed {
+		err = updateRef(ctx, restClient, repo, target, workingBranchName)
+		if err != nil {
+			return combined, mergeConflicts, "", fmt.Errorf("failed to update %s: %w", target, err)
 		}
 	}
 
-	return combined, mergeConflicts, combinedPRLink, nil
+	err = deleteBranch(ctx, restClient, repo, workingBranchName)
+	if err != nil {
+		Logger.Warn("Failed to delete working branch", "branch", workingBranchName, "error", err)
+	}
+
+	return combined, mergeConflicts, unsignedReason, nil
 }
 
 // createPullRequestWithNumber creates a PR and returns its number
-func createPullRequestWithNumber(ctx context.Context, client RESTClientInterface, repo github.Repo, title, head, base, body string, labels, assignees []string) (int, error) {
+func createPullRequestWithNumber(ctx context.Context, client RESTClientInterface, repo github.Repo, title, head, base, body string, draft bool) (int, error) {
 	endpoint := fmt.Sprintf("repos/%s/%s/pulls", repo.Owner, repo.Repo)
 	payload := map[string]interface{}{
 		"title": title,
 		"head":  head,
 		"base":  base,
 		"body":  body,
+		"draft": draft,
 	}
 
 	requestBody, err := encodePayload(payload)
@@ -142,30 +271,6 @@ func createPullRequestWithNumber(ctx context.Context, client RESTClientInterface
 		return 0, fmt.Errorf("failed to create pull request: %w", err)
 	}
 
-	if len(labels) > 0 {
-		labelsEndpoint := fmt.Sprintf("repos/%s/%s/issues/%d/labels", repo.Owner, repo.Repo, prResponse.Number)
-		labelsPayload, err := encodePayload(map[string][]string{"labels": labels})
-		if err != nil {
-			return prResponse.Number, fmt.Errorf("failed to encode labels payload: %w", err)
-		}
-		err = client.Post(labelsEndpoint, labelsPayload, nil)
-		if err != nil {
-			return prResponse.Number, fmt.Errorf("failed to add labels: %w", err)
-		}
-	}
-
-	if len(assignees) > 0 {
-		assigneesEndpoint := fmt.Sprintf("repos/%s/%s/issues/%d/assignees", repo.Owner, repo.Repo, prResponse.Number)
-		assigneesPayload, err := encodePayload(map[string][]string{"assignees": assignees})
-		if err != nil {
-			return prResponse.Number, fmt.Errorf("failed to encode assignees payload: %w", err)
-		}
-		err = client.Post(assigneesEndpoint, assigneesPayload, nil)
-		if err != nil {
-			return prResponse.Number, fmt.Errorf("failed to add assignees: %w", err)
-		}
-	}
-
 	return prResponse.Number, nil
 }
 
@@ -203,30 +308,74 @@ func getBranchSHA(ctx context.Context, client RESTClientInterface, repo github.R
 	return ref.Object.SHA, nil
 }
 
+const (
+	// combinedPRsHeader introduces the list of combined pull requests in the combined PR body
+	combinedPRsHeader = "✅ The following pull requests have been successfully combined:"
+
+	// generatedWithLink is written at the end of the default combined PR body
+	generatedWithLink = "> Generated with [gh-combine](https://github.com/github/gh-combine)"
+)
+
+// isCombinedPRBody reports whether a PR body was written by gh-combine, with the default body or a --body-template
+func isCombinedPRBody(body string) bool {
+	return strings.Contains(body, generatedWithLink) || combinedPRsMarkerRegex.MatchString(body)
+}
+
 // Updated generatePRBody to include the command used and handle PR autoclose logic
-// combinedPrNumbers looks like ["#1", "#2"]
-// mergeFailedPRs looks like ["#3", "#4"]
-func generatePRBody(combinedPrNumbers []string, mergeFailedPRs []string, command string) string {
-	body := "✅ The following pull requests have been successfully combined:\n"
-	for _, prNumber := range combinedPrNumbers {
-		prRef := prNumber
+// combinedPulls are the pull requests that were merged into the combined branch
+// mergeFailedPRs are the pull requests that could not be merged due to conflicts
+// ejectedPRs are the pull requests that were removed because they failed CI on the combined branch
+// outdatedPRs are the combined pull requests that got new commits during the run, they are listed apart and not closed
+func generatePRBody(combinedPulls, mergeFailedPRs, ejectedPRs, outdatedPRs github.Pulls, command string) string {
+	body := combinedPRsHeader + "\n"
+	for _, pull := range combinedPulls {
+		if isOutdated(pull.Number, outdatedPRs) {
+			continue
+		}
+		prRef := fmt.Sprintf("#%d", pull.Number)
 		if !noAutoclose {
-			prRef = "closes: " + prNumber
+			prRef = "closes: " + prRef
 		}
 		body += "- " + prRef + "\n"
 	}
 
+	body += generateDependencyTable(combinedPulls)
+
+	if len(outdatedPRs) > 0 {
+		body += "\n⚠️ The following pull requests got new commits during the run and were combined at the commit that was checked. They are not closed when this pull request is merged, since their new commits are not included:\n"
+		for _, pr := range outdatedPRs {
+			body += fmt.Sprintf("- #%d combined at %s\n", pr.Number, shortSHA(pr.Head.SHA))
+		}
+	}
+
 	if len(mergeFailedPRs) > 0 {
 		body += "\n⚠️ The following pull requests could not be merged due to conflicts:\n"
 		for _, pr := range mergeFailedPRs {
-			body += "- " + pr + "\n"
+			body += fmt.Sprintf("- #%d\n", pr.Number)
 		}
 	}
 
-	body += "\n> Generated with [gh-combine](https://github.com/github/gh-combine)\n"
-	body += fmt.Sprintf("\nCommand used:\n\n```bash\n%s\n```", command)
+	if len(ejectedPRs) > 0 {
+		body += "\n❌ The following pull requests were removed because CI failed on the combined branch when they were included:\n"
+		for _, pr := range ejectedPRs {
+			body += fmt.Sprintf("- #%d - %s\n", pr.Number, pr.Title)
+		}
+	}
+
+	footer := "\n" + generatedWithLink + "\n"
+	footer += fmt.Sprintf("\nCommand used:\n\n```bash\n%s\n```", command)
+
+	// The release notes get whatever room is left in the body, keeping room for what renderPRTitleAndBody appends
+	if !noReleaseNotes {
+		body += generateReleaseNotes(combinedPulls, maxPRBodyLength-len(body)-len(footer)-prBodySuffixLength(combinedPulls))
+	}
+
+	return body + footer
+}
 
-	return body
+// isOutdated reports whether a PR is one of the PRs combined at an outdated SHA
+func isOutdated(number int, outdated github.Pulls) bool {
+	return slices.ContainsFunc(outdated, func(pull github.Pull) bool { return pull.Number == number })
 }
 
 // deleteBranch deletes a branch in the repository
@@ -249,18 +398,24 @@ func createBranch(ctx context.Context, client RESTClientInterface, repo github.R
 	return client.Post(endpoint, body, nil)
 }
 
-// mergeBranch merges a branch into the base branch
-func mergeBranch(ctx context.Context, client RESTClientInterface, repo github.Repo, base, head string) error {
+// mergeBranch merges a branch or commit into the base branch
+func mergeBranch(ctx context.Context, client RESTClientInterface, repo github.Repo, base, head, message string) (*gitCommit, error) {
 	endpoint := fmt.Sprintf("repos/%s/%s/merges", repo.Owner, repo.Repo)
 	payload := map[string]string{
-		"base": base,
-		"head": head,
+		"base":           base,
+		"head":           head,
+		"commit_message": message,
 	}
 	body, err := encodePayload(payload)
 	if err != nil {
-		return fmt.Errorf("failed to encode payload: %w", err)
+		return nil, fmt.Errorf("failed to encode payload: %w", err)
 	}
-	return client.Post(endpoint, body, nil)
+
+	merge := &gitCommit{}
+	if err := client.Post(endpoint, body, merge); err != nil {
+		return nil, err
+	}
+	return merge, nil
 }
 
 // updateRef updates a branch to point to the latest commit of another branch
@@ -278,9 +433,14 @@ func updateRef(ctx context.Context, client RESTClientInterface, repo github.Repo
 	}
 
 	// Update the branch to point to the new SHA
-	endpoint = fmt.Sprintf("repos/%s/%s/git/refs/heads/%s", repo.Owner, repo.Repo, branch)
+	return setBranchSHA(ctx, client, repo, branch, ref.Object.SHA)
+}
+
+// setBranchSHA force-updates a branch to point to a commit
+func setBranchSHA(ctx context.Context, client RESTClientInterface, repo github.Repo, branch, sha string) error {
+	endpoint := fmt.Sprintf("repos/%s/%s/git/refs/heads/%s", repo.Owner, repo.Repo, branch)
 	payload := map[string]interface{}{
-		"sha":   ref.Object.SHA,
+		"sha":   sha,
 		"force": true,
 	}
 	body, err := encodePayload(payload)
@@ -290,6 +450,16 @@ func updateRef(ctx context.Context, client RESTClientInterface, repo github.Repo
 	return client.Patch(endpoint, body, nil)
 }
 
+// updatePullRequest replaces the title and body of an existing pull request
+func updatePullRequest(ctx context.Context, client RESTClientInterface, repo github.Repo, number int, title, body string) error {
+	endpoint := fmt.Sprintf("repos/%s/%s/pulls/%d", repo.Owner, repo.Repo, number)
+	payload, err := encodePayload(map[string]string{"title": title, "body": body})
+	if err != nil {
+		return fmt.Errorf("failed to encode payload: %w", err)
+	}
+	return client.Patch(endpoint, payload, nil)
+}
+
 func createPullRequest(ctx context.Context, client RESTClientInterface, repo github.Repo, title, head, base, body string, labels, assignees []string) error {
 	endpoint := fmt.Sprintf("repos/%s/%s/pulls", repo.Owner, repo.Repo)
 	payload := map[string]interface{}{
@@ -2,6 +2,9 @@ package cmd
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"io"
 	"strings"
 	"testing"
 
@@ -37,3 +40,102 @@ func TestCreatePullRequest(t *testing.T) {
 	err := createPullRequest(context.Background(), client, repo, title, head, base, body, labels, assignees)
 	assert.NoError(t, err)
 }
+
+func TestBuildCombinedBranch(t *testing.T) {
+	// Since this test reads global state, don't use t.Parallel()
+	var merged []string
+	client := &MockRESTClient{
+		PostFunc: func(endpoint string, body interface{}, response interface{}) error {
+			if strings.HasSuffix(endpoint, "/merges") {
+				data, _ := io.ReadAll(body.(io.Reader))
+				if strings.Contains(string(data), "conflicting-branch") {
+					return errors.New("HTTP 409: Merge conflict")
+				}
+				merged = append(merged, string(data))
+			}
+			return nil
+		},
+	}
+
+	pulls := github.Pulls{
+		{Number: 1, Head: github.Ref{Ref: "feature-1"}},
+		{Number: 2, Head: github.Ref{Ref: "conflicting-branch"}},
+		{Number: 3, Head: github.Ref{Ref: "feature-3"}},
+	}
+
+	combined, conflicts, _, err := buildCombinedBranch(context.Background(), nil, client, github.Repo{Owner: "owner", Repo: "repo"}, "abc123", pulls)
+	assert.NoError(t, err)
+	assert.Len(t, merged, 2)
+	assert.Equal(t, github.Pulls{pulls[0], pulls[2]}, combined)
+	assert.Equal(t, github.Pulls{pulls[1]}, conflicts)
+}
+
+func TestGeneratePRBody(t *testing.T) {
+	// Since this test reads global state, don't use t.Parallel()
+	combined := github.Pulls{
+		{Number: 1, Title: "Bump lodash from 4.17.20 to 4.17.21", Head: github.Ref{Ref: "dependabot/npm_and_yarn/lodash-4.17.21"}},
+		{Number: 2, Title: "Bump rack from 3.0.0 to 3.0.1", Head: github.Ref{Ref: "dependabot/bundler/rack-3.0.1"}},
+	}
+	conflicts := github.Pulls{{Number: 3}}
+	ejected := github.Pulls{{Number: 4, Title: "Bump react from 17.0.2 to 18.2.0"}}
+
+	body := generatePRBody(combined, conflicts, ejected, nil, "gh combine owner/repo")
+
+	assert.Contains(t, body, "- closes: #1\n- closes: #2\n")
+	assert.Contains(t, body, "could not be merged due to conflicts:\n- #3\n")
+	assert.Contains(t, body, "CI failed on the combined branch when they were included:\n- #4 - Bump react from 17.0.2 to 18.2.0\n")
+	assert.Contains(t, body, "| #2 | `rack` | 3.0.0 | 3.0.1 | patch | bundler |")
+	assert.Contains(t, body, "```bash\ngh combine owner/repo\n```")
+
+	body = generatePRBody(combined, nil, nil, nil, "gh combine owner/repo")
+	assert.NotContains(t, body, "conflicts")
+	assert.NotContains(t, body, "CI failed")
+
+	// A PR that moved during the run is combined at its old commit and must not be closed
+	combined[1].Head.SHA = "bbbbbbbbbb"
+	body = generatePRBody(combined, nil, nil, github.Pulls{combined[1]}, "gh combine owner/repo")
+	assert.Contains(t, body, combinedPRsHeader+"\n- closes: #1\n\n")
+	assert.NotContains(t, body, "closes: #2")
+	assert.Contains(t, body, "not closed when this pull request is merged, since their new commits are not included:\n- #2 combined at bbbbbbb\n")
+	assert.Equal(t, []int{1}, parseCombinedPRNumbers(body), "finalize must not close the outdated PR")
+}
+
+func TestCombinePRsReportsMergeError(t *testing.T) {
+	// Since this test changes global state, don't use t.Parallel()
+	origMethod, origMergeWhenGreen := autoMergeMethod, mergeWhenGreen
+	defer func() { autoMergeMethod, mergeWhenGreen = origMethod, origMergeWhenGreen }()
+	autoMergeMethod = mergeMethodSquash
+	mergeWhenGreen = false
+
+	client := &MockRESTClient{
+		GetFunc: func(endpoint string, response interface{}) error {
+			switch endpoint {
+			case "repos/owner/repo":
+				return json.Unmarshal([]byte(`{"default_branch": "main"}`), response)
+			case "repos/owner/repo/pulls/7":
+				return errors.New("HTTP 403")
+			}
+			return json.Unmarshal([]byte(`{"object": {"sha": "base"}}`), response)
+		},
+		PostFunc: func(endpoint string, body interface{}, response interface{}) error {
+			switch {
+			case strings.HasSuffix(endpoint, "/merges"):
+				return json.Unmarshal([]byte(`{"sha": "merge", "commit": {"tree": {"sha": "tree"}}}`), response)
+			case strings.HasSuffix(endpoint, "/pulls"):
+				return json.Unmarshal([]byte(`{"number": 7}`), response)
+			}
+			return nil
+		},
+	}
+
+	opts := CombineOpts{
+		Repo:  github.Repo{Owner: "owner", Repo: "repo"},
+		Pulls: github.Pulls{{Number: 1, Head: github.Ref{Ref: "feature-1", Repo: &github.RefRepo{FullName: "owner/repo"}}}},
+	}
+
+	result, err := CombinePRsWithStats(context.Background(), nil, client, opts)
+	assert.NoError(t, err, "a merge failure should not fail the run once the combined PR exists")
+	assert.Empty(t, result.MergeStatus)
+	assert.Contains(t, result.MergeError, "failed to enable auto-merge")
+	assert.Contains(t, result.MergeError, "HTTP 403")
+}
@@ -0,0 +1,111 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/github/gh-combine/internal/github"
+)
+
+// Commit modes for --commit-mode
+const (
+	// commitModeMerge keeps the merge commits created by the merges API
+	commitModeMerge = "merge"
+	// commitModeSquashEach replaces the merge commit of every PR with a single commit
+	commitModeSquashEach = "squash-each"
+	// commitModeSingle replaces all merge commits with one commit on top of the base branch
+	commitModeSingle = "single"
+	// commitModeSigned is like commitModeSingle, but the commit is created through GraphQL so GitHub signs it
+	commitModeSigned = "signed"
+)
+
+var errInvalidCommitMode = errors.New("invalid --commit-mode value")
+
+// gitCommit is a commit as returned by the merges and git commits APIs
+type gitCommit struct {
+	SHA    string `json:"sha"`
+	Commit struct {
+		Tree struct {
+			SHA string `json:"sha"`
+		} `json:"tree"`
+	} `json:"commit"`
+}
+
+// replaceWorkingHead creates a commit with the given tree and parent and force-updates the working branch to it
+func replaceWorkingHead(ctx context.Context, client RESTClientInterface, repo github.Repo, branch, message, tree, parent string) (string, error) {
+	sha, err := createCommit(ctx, client, repo, message, tree, []string{parent})
+	if err != nil {
+		return "", err
+	}
+	if err := setBranchSHA(ctx, client, repo, branch, sha); err != nil {
+		return "", fmt.Errorf("failed to update %s: %w", branch, err)
+	}
+	return sha, nil
+}
+
+// createCommit creates a commit object and returns its SHA
+func createCommit(ctx context.Context, client RESTClientInterface, repo github.Repo, message, tree string, parents []string) (string, error) {
+	endpoint := fmt.Sprintf("repos/%s/%s/git/commits", repo.Owner, repo.Repo)
+	body, err := encodePayload(map[string]interface{}{
+		"message": message,
+		"tree":    tree,
+		"parents": parents,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to encode payload: %w", err)
+	}
+
+	var commit struct {
+		SHA string `json:"sha"`
+	}
+	if err := client.Post(endpoint, body, &commit); err != nil {
+		return "", fmt.Errorf("failed to create commit: %w", err)
+	}
+	return commit.SHA, nil
+}
+
+// squashCommitMessage is the message of the commit replacing the merge of a single PR
+func squashCommitMessage(pull github.Pull) string {
+	return fmt.Sprintf("%s (#%d)\n\n%s", pull.Title, pull.Number, coAuthorTrailers(github.Pulls{pull}))
+}
+
+// singleCommitMessage is the message of the commit replacing the merges of all combined PRs
+func singleCommitMessage(pulls github.Pulls) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Combine %d pull requests\n\n", len(pulls))
+	for _, pull := range pulls {
+		fmt.Fprintf(&b, "- %s (#%d)\n", pull.Title, pull.Number)
+	}
+	b.WriteString("\n" + coAuthorTrailers(pulls))
+	return b.String()
+}
+
+// coAuthorTrailers returns a Co-authored-by trailer for each distinct PR author, using their noreply email
+func coAuthorTrailers(pulls github.Pulls) string {
+	var trailers []string
+	for _, pull := range pulls {
+		if pull.User.Login == "" {
+			continue
+		}
+		email := pull.User.Login + "@users.noreply.github.com"
+		if pull.User.ID > 0 {
+			email = fmt.Sprintf("%d+%s", pull.User.ID, email)
+		}
+		trailer := fmt.Sprintf("Co-authored-by: %s <%s>", pull.User.Login, email)
+		if !slices.Contains(trailers, trailer) {
+			trailers = append(trailers, trailer)
+		}
+	}
+	return strings.Join(trailers, "\n")
+}
+
+// ValidateCommitMode checks that --commit-mode is one of the supported modes
+func ValidateCommitMode(mode string) error {
+	if !slices.Contains([]string{commitModeMerge, commitModeSquashEach, commitModeSingle, commitModeSigned}, mode) {
+		return fmt.Errorf("%w: %q (must be %s, %s, %s or %s)", errInvalidCommitMode, mode, commitModeMerge, commitModeSquashEach, commitModeSingle, commitModeSigned)
+	}
+	return nil
+}
@@ -0,0 +1,170 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/github/gh-combine/internal/github"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCommitMessages(t *testing.T) {
+	t.Parallel()
+
+	pulls := github.Pulls{
+		{Number: 12, Title: "Bump lodash from 4.17.20 to 4.17.21", User: github.User{ID: 49699333, Login: "dependabot[bot]"}},
+		{Number: 13, Title: "Bump react from 18.2.0 to 18.3.0", User: github.User{ID: 49699333, Login: "dependabot[bot]"}},
+		{Number: 14, Title: "Fix typo", User: github.User{Login: "octocat"}},
+	}
+
+	assert.Equal(t,
+		"Bump lodash from 4.17.20 to 4.17.21 (#12)\n\nCo-authored-by: dependabot[bot] <49699333+dependabot[bot]@users.noreply.github.com>",
+		squashCommitMessage(pulls[0]))
+
+	assert.Equal(t,
+		"Combine 3 pull requests\n\n"+
+			"- Bump lodash from 4.17.20 to 4.17.21 (#12)\n"+
+			"- Bump react from 18.2.0 to 18.3.0 (#13)\n"+
+			"- Fix typo (#14)\n\n"+
+			"Co-authored-by: dependabot[bot] <49699333+dependabot[bot]@users.noreply.github.com>\n"+
+			"Co-authored-by: octocat <octocat@users.noreply.github.com>",
+		singleCommitMessage(pulls))
+}
+
+func TestBuildCombinedBranchCommitModes(t *testing.T) {
+	// Since this test changes global state, don't use t.Parallel()
+	origCommitMode := commitMode
+	defer func() { commitMode = origCommitMode }()
+
+	pulls := github.Pulls{
+		{Number: 1, Title: "First", Head: github.Ref{Ref: "feature-1"}},
+		{Number: 2, Title: "Conflict", Head: github.Ref{Ref: "conflicting-branch"}},
+		{Number: 3, Title: "Third", Head: github.Ref{Ref: "feature-3"}},
+	}
+
+	tests := []struct {
+		name        string
+		mode        string
+		rules       string
+		wantCommits []string
+		wantHeads   []string
+		wantErr     bool
+	}{
+		{
+			name: commitModeMerge,
+			mode: commitModeMerge,
+		},
+		{
+			name:        commitModeSquashEach,
+			mode:        commitModeSquashEach,
+			wantCommits: []string{"First (#1)|tree-1|base", "Third (#3)|tree-2|squash-1"},
+			wantHeads:   []string{"squash-1", "squash-2", "squash-2"},
+		},
+		{
+			name:        commitModeSingle,
+			mode:        commitModeSingle,
+			wantCommits: []string{"Combine 2 pull requests|tree-2|base"},
+			wantHeads:   []string{"squash-1", "squash-1"},
+		},
+		{
+			// Without a GraphQL client the signed commit fails and the merge commits are kept
+			name:  "signed falls back when signatures are not required",
+			mode:  commitModeSigned,
+			rules: `[{"type": "pull_request"}]`,
+		},
+		{
+			name:    "signed fails when a ruleset requires signatures",
+			mode:    commitModeSigned,
+			rules:   `[{"type": "required_signatures"}]`,
+			wantErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			commitMode = test.mode
+
+			merges := 0
+			var commits, heads []string
+			client := &MockRESTClient{
+				PostFunc: func(endpoint string, body interface{}, response interface{}) error {
+					data, _ := io.ReadAll(body.(io.Reader))
+					switch {
+					case strings.HasSuffix(endpoint, "/merges"):
+						if strings.Contains(string(data), "conflicting-branch") {
+							return errors.New("HTTP 409: Merge conflict")
+						}
+						merges++
+						return json.Unmarshal([]byte(fmt.Sprintf(`{"sha": "merge-%d", "commit": {"tree": {"sha": "tree-%d"}}}`, merges, merges)), response)
+					case strings.HasSuffix(endpoint, "/git/commits"):
+						var payload struct {
+							Message string   `json:"message"`
+							Tree    string   `json:"tree"`
+							Parents []string `json:"parents"`
+						}
+						if err := json.Unmarshal(data, &payload); err != nil {
+							return err
+						}
+						subject, _, _ := strings.Cut(payload.Message, "\n")
+						commits = append(commits, subject+"|"+payload.Tree+"|"+strings.Join(payload.Parents, ","))
+						return json.Unmarshal([]byte(fmt.Sprintf(`{"sha": "squash-%d"}`, len(commits))), response)
+					}
+					return nil
+				},
+				GetFunc: func(endpoint string, response interface{}) error {
+					switch {
+					case endpoint == "repos/owner/repo":
+						return json.Unmarshal([]byte(`{"default_branch": "main"}`), response)
+					case strings.Contains(endpoint, "/rules/branches/"):
+						return json.Unmarshal([]byte(test.rules), response)
+					}
+					// The working branch points to the last commit that was set
+					sha := "merge"
+					if len(heads) > 0 {
+						sha = heads[len(heads)-1]
+					}
+					return json.Unmarshal([]byte(fmt.Sprintf(`{"object": {"sha": %q}}`, sha)), response)
+				},
+				PatchFunc: func(endpoint string, body io.Reader, response interface{}) error {
+					var payload struct {
+						SHA string `json:"sha"`
+					}
+					if err := json.NewDecoder(body).Decode(&payload); err != nil {
+						return err
+					}
+					heads = append(heads, payload.SHA)
+					return nil
+				},
+			}
+
+			combined, conflicts, unsignedReason, err := buildCombinedBranch(context.Background(), nil, client, github.Repo{Owner: "owner", Repo: "repo"}, "base", pulls)
+
+			if test.wantErr {
+				assert.ErrorContains(t, err, "a ruleset requires signed commits")
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, test.mode == commitModeSigned, unsignedReason != "", "only a failed signed commit is reported as unsigned")
+			assert.Equal(t, github.Pulls{pulls[0], pulls[2]}, combined)
+			assert.Equal(t, github.Pulls{pulls[1]}, conflicts)
+			assert.Equal(t, test.wantCommits, commits)
+			if test.wantHeads != nil {
+				assert.Equal(t, test.wantHeads, heads)
+			}
+		})
+	}
+}
+
+func TestValidateCommitMode(t *testing.T) {
+	t.Parallel()
+
+	for _, mode := range []string{commitModeMerge, commitModeSquashEach, commitModeSingle, commitModeSigned} {
+		assert.NoError(t, ValidateCommitMode(mode))
+	}
+	assert.ErrorIs(t, ValidateCommitMode("rebase"), errInvalidCommitMode)
+}
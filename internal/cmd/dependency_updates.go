@@ -0,0 +1,296 @@
+package cmd
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/github/gh-combine/internal/github"
+)
+
+// Update types as classified by comparing the from and to versions
+const (
+	updateTypeMajor = "major"
+	updateTypeMinor = "minor"
+	updateTypePatch = "patch"
+)
+
+var validUpdateTypes = []string{updateTypeMajor, updateTypeMinor, updateTypePatch}
+
+// DependencyUpdate holds the metadata parsed out of a Dependabot or Renovate PR
+type DependencyUpdate struct {
+	Name       string `json:"name"`
+	From       string `json:"from,omitempty"`
+	To         string `json:"to"`
+	Ecosystem  string `json:"ecosystem,omitempty"`
+	Directory  string `json:"directory,omitempty"`
+	UpdateType string `json:"updateType,omitempty"`
+}
+
+var (
+	// Bump lodash from 4.17.20 to 4.17.21 in /frontend
+	dependabotBumpRegex = regexp.MustCompile(`(?i)\bbump (\S+) from (\S+) to (\S+?)(?: in (\S+))?$`)
+	// Update rails requirement from ~> 6.0 to ~> 7.0 in /app
+	dependabotRequirementRegex = regexp.MustCompile(`(?i)\bupdate (\S+) requirement from (.+?) to (.+?)(?: in (\S+))?$`)
+	// Update dependency react to v18.2.0 (major), anchored to the start of the title after an optional
+	// conventional commit prefix and ending in a version, so titles like "Update docs to v2 layout" do not match
+	renovateUpdateRegex = regexp.MustCompile(`(?i)^(?:[\w-]+(?:\([^)]*\))?!?:\s*)?update (?:(dependency|module|action|image|plugin|package) )?(\S+)(?: from v?(\S+))? to v?(\d[\w.+-]*)(?:\s+\(\w+\))?$`)
+
+	semverRegex = regexp.MustCompile(`^v?(\d+)(?:\.(\d+))?(?:\.(\d+))?`)
+
+	// renovate/eslint-9.2.x, the branch of a patch update with Renovate's separateMinorPatch
+	renovatePatchBranchRegex = regexp.MustCompile(`-\d+\.\d+\.x$`)
+)
+
+// renovateBranchPrefix is the default branch prefix of Renovate PRs
+const renovateBranchPrefix = "renovate/"
+
+// renovateManagers maps Renovate managers, which prefix the branch topic with additionalBranchPrefix "{{manager}}-",
+// to the package-ecosystem names used in dependabot.yml
+var renovateManagers = map[string]string{
+	"npm":              "npm",
+	"gomod":            "gomod",
+	"github-actions":   "github-actions",
+	"dockerfile":       "docker",
+	"docker-compose":   "docker",
+	"pip_requirements": "pip",
+	"pip-compile":      "pip",
+	"pipenv":           "pip",
+	"poetry":           "pip",
+	"bundler":          "bundler",
+	"cargo":            "cargo",
+	"composer":         "composer",
+	"maven":            "maven",
+	"gradle":           "gradle",
+	"nuget":            "nuget",
+	"terraform":        "terraform",
+}
+
+// ecosystemAliases maps Dependabot branch directories and Renovate title keywords
+// to the package-ecosystem names used in dependabot.yml
+var ecosystemAliases = map[string]string{
+	"npm_and_yarn":   "npm",
+	"go_modules":     "gomod",
+	"github_actions": "github-actions",
+	"submodules":     "gitsubmodule",
+	"hex":            "mix",
+	"module":         "gomod",
+	"action":         "github-actions",
+	"image":          "docker",
+}
+
+// normalizeEcosystem converts an ecosystem name to its dependabot.yml form
+func normalizeEcosystem(ecosystem string) string {
+	ecosystem = strings.ToLower(strings.TrimSpace(ecosystem))
+	if alias, ok := ecosystemAliases[ecosystem]; ok {
+		return alias
+	}
+	return strings.ReplaceAll(ecosystem, "_", "-")
+}
+
+// ParseDependencyUpdate extracts the dependency name, versions and ecosystem from a bot PR
+// title and branch name. It returns false if the PR does not look like a single dependency update
+func ParseDependencyUpdate(title, branch string) (DependencyUpdate, bool) {
+	title = strings.TrimSpace(title)
+	update := DependencyUpdate{}
+
+	if m := dependabotBumpRegex.FindStringSubmatch(title); m != nil {
+		update.Name, update.From, update.To, update.Directory = m[1], m[2], m[3], m[4]
+	} else if m := dependabotRequirementRegex.FindStringSubmatch(title); m != nil {
+		update.Name, update.From, update.To, update.Directory = m[1], m[2], m[3], m[4]
+	} else if m := renovateUpdateRegex.FindStringSubmatch(title); m != nil {
+		update.Name, update.From, update.To = m[2], m[3], m[4]
+		if m[1] != "" && m[1] != "dependency" && m[1] != "package" && m[1] != "plugin" {
+			update.Ecosystem = normalizeEcosystem(m[1])
+		}
+		if update.Ecosystem == "" {
+			update.Ecosystem = renovateEcosystem(update.Name, branch)
+		}
+	} else {
+		return DependencyUpdate{}, false
+	}
+
+	// Dependabot encodes the ecosystem in the branch name: dependabot/<ecosystem>/<dependency>-<version>
+	if parts := strings.Split(branch, "/"); len(parts) > 2 && parts[0] == "dependabot" {
+		update.Ecosystem = normalizeEcosystem(parts[1])
+	}
+
+	update.UpdateType = classifyUpdate(update.From, update.To)
+	if update.UpdateType == "" && strings.HasSuffix(strings.ToLower(title), "(major)") {
+		update.UpdateType = updateTypeMajor
+	}
+	if update.UpdateType == "" && strings.HasPrefix(branch, renovateBranchPrefix) {
+		update.UpdateType = renovateUpdateType(update.To, branch)
+	}
+
+	return update, true
+}
+
+// renovateEcosystem derives the ecosystem of a Renovate PR whose title does not name it, from the manager
+// prefix of the branch topic or from a scoped npm package name
+func renovateEcosystem(name, branch string) string {
+	if topic, ok := strings.CutPrefix(branch, renovateBranchPrefix); ok {
+		for manager, ecosystem := range renovateManagers {
+			if strings.HasPrefix(topic, manager+"-") {
+				return ecosystem
+			}
+		}
+	}
+	if strings.HasPrefix(name, "@") && strings.Contains(name, "/") {
+		return "npm"
+	}
+	return ""
+}
+
+// renovateUpdateType works out the update type of a Renovate PR without a from version, following Renovate's
+// defaults. Major updates are titled with the major version only ("to v9"). Patch updates have their own
+// "<dependency>-<major>.<minor>.x" branch with separateMinorPatch. Other updates share the "<dependency>-<major>.x"
+// branch, and are minor when they land on a .0 release and patch otherwise
+func renovateUpdateType(to, branch string) string {
+	if !strings.Contains(to, ".") {
+		if _, ok := parseSemver(to); ok {
+			return updateTypeMajor
+		}
+		return ""
+	}
+	if renovatePatchBranchRegex.MatchString(branch) {
+		return updateTypePatch
+	}
+
+	parts, ok := parseSemver(to)
+	if !ok {
+		return ""
+	}
+	if parts[2] == 0 {
+		return updateTypeMinor
+	}
+	return updateTypePatch
+}
+
+// classifyUpdate returns major, minor or patch depending on which semver component changed.
+// An empty string is returned when either version cannot be parsed
+func classifyUpdate(from, to string) string {
+	fromParts, ok := parseSemver(from)
+	if !ok {
+		return ""
+	}
+	toParts, ok := parseSemver(to)
+	if !ok {
+		return ""
+	}
+
+	switch {
+	case fromParts[0] != toParts[0]:
+		return updateTypeMajor
+	case fromParts[1] != toParts[1]:
+		return updateTypeMinor
+	default:
+		return updateTypePatch
+	}
+}
+
+// parseSemver parses the major, minor and patch components of a version, treating missing components as zero
+func parseSemver(version string) ([3]int, bool) {
+	var parts [3]int
+	// Strip requirement operators such as ~>, ^ or >=
+	version = strings.TrimLeft(strings.TrimSpace(version), "~^=<> ")
+
+	m := semverRegex.FindStringSubmatch(version)
+	if m == nil {
+		return parts, false
+	}
+
+	for i := range parts {
+		if m[i+1] == "" {
+			continue
+		}
+		n, err := strconv.Atoi(m[i+1])
+		if err != nil {
+			return parts, false
+		}
+		parts[i] = n
+	}
+
+	return parts, true
+}
+
+// dependencyMatchesCriteria checks a PR against the --update-types, --ecosystem and --ignore-deps filters
+func dependencyMatchesCriteria(title, branch string, updateTypes, ecosystems, ignoreDeps []string) bool {
+	// If no dependency filters are specified, all PRs pass this check
+	if len(updateTypes) == 0 && len(ecosystems) == 0 && len(ignoreDeps) == 0 {
+		return true
+	}
+
+	update, ok := ParseDependencyUpdate(title, branch)
+	if !ok {
+		Logger.Debug("PR is not a dependency update, skipping match", "title", title, "branch", branch)
+		return false
+	}
+
+	if len(updateTypes) > 0 && !slices.Contains(updateTypes, update.UpdateType) {
+		Logger.Debug("Dependency update type does not match", "dependency", update.Name, "updateType", update.UpdateType)
+		return false
+	}
+
+	if len(ecosystems) > 0 && !slices.ContainsFunc(ecosystems, func(e string) bool {
+		return normalizeEcosystem(e) == update.Ecosystem
+	}) {
+		Logger.Debug("Dependency ecosystem does not match", "dependency", update.Name, "ecosystem", update.Ecosystem)
+		return false
+	}
+
+	for _, pattern := range ignoreDeps {
+		if matched, _ := path.Match(pattern, update.Name); matched {
+			Logger.Debug("Dependency is ignored", "dependency", update.Name, "pattern", pattern)
+			return false
+		}
+	}
+
+	return true
+}
+
+// ValidateDependencyFilters checks that the --update-types and --ignore-deps values are valid
+func ValidateDependencyFilters(updateTypes, ignoreDeps []string) error {
+	for _, t := range updateTypes {
+		if !slices.Contains(validUpdateTypes, t) {
+			return fmt.Errorf("%w: %q (must be one of %s)", errInvalidUpdateType, t, strings.Join(validUpdateTypes, ", "))
+		}
+	}
+
+	for _, pattern := range ignoreDeps {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid --ignore-deps pattern %q: %w", pattern, err)
+		}
+	}
+
+	return nil
+}
+
+// generateDependencyTable renders the parsed dependency metadata of the combined PRs as a markdown table
+func generateDependencyTable(pulls github.Pulls) string {
+	rows := ""
+	for _, pull := range pulls {
+		update, ok := ParseDependencyUpdate(pull.Title, pull.Head.Ref)
+		if !ok {
+			continue
+		}
+		rows += fmt.Sprintf("| #%d | `%s` | %s | %s | %s | %s |\n", pull.Number, update.Name, valueOrDash(update.From), update.To, valueOrDash(update.UpdateType), valueOrDash(update.Ecosystem))
+	}
+
+	if rows == "" {
+		return ""
+	}
+
+	return "\n📦 Dependency updates:\n\n| PR | Dependency | From | To | Type | Ecosystem |\n| --- | --- | --- | --- | --- | --- |\n" + rows
+}
+
+// valueOrDash returns a dash for empty table cells
+func valueOrDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
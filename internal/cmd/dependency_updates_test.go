@@ -0,0 +1,325 @@
+package cmd
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/github/gh-combine/internal/github"
+)
+
+func TestParseDependencyUpdate(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		title  string
+		branch string
+		want   DependencyUpdate
+		ok     bool
+	}{
+		{
+			name:   "dependabot npm patch bump",
+			title:  "Bump lodash from 4.17.20 to 4.17.21",
+			branch: "dependabot/npm_and_yarn/lodash-4.17.21",
+			want:   DependencyUpdate{Name: "lodash", From: "4.17.20", To: "4.17.21", Ecosystem: "npm", UpdateType: "patch"},
+			ok:     true,
+		},
+		{
+			name:   "dependabot go modules minor bump with conventional commit prefix",
+			title:  "build(deps): bump github.com/cli/go-gh/v2 from 2.11.2 to 2.12.0",
+			branch: "dependabot/go_modules/github.com/cli/go-gh/v2-2.12.0",
+			want:   DependencyUpdate{Name: "github.com/cli/go-gh/v2", From: "2.11.2", To: "2.12.0", Ecosystem: "gomod", UpdateType: "minor"},
+			ok:     true,
+		},
+		{
+			name:   "dependabot major bump in a directory",
+			title:  "Bump react from 17.0.2 to 18.2.0 in /frontend",
+			branch: "dependabot/npm_and_yarn/frontend/react-18.2.0",
+			want:   DependencyUpdate{Name: "react", From: "17.0.2", To: "18.2.0", Ecosystem: "npm", Directory: "/frontend", UpdateType: "major"},
+			ok:     true,
+		},
+		{
+			name:   "dependabot github actions with v prefixed versions",
+			title:  "Bump actions/checkout from v3 to v4",
+			branch: "dependabot/github_actions/actions/checkout-4",
+			want:   DependencyUpdate{Name: "actions/checkout", From: "v3", To: "v4", Ecosystem: "github-actions", UpdateType: "major"},
+			ok:     true,
+		},
+		{
+			name:   "dependabot requirement update",
+			title:  "Update rails requirement from ~> 7.0.1 to ~> 7.1.0",
+			branch: "dependabot/bundler/rails-tw-7.1.0",
+			want:   DependencyUpdate{Name: "rails", From: "~> 7.0.1", To: "~> 7.1.0", Ecosystem: "bundler", UpdateType: "minor"},
+			ok:     true,
+		},
+		{
+			name:   "renovate dependency without a from version",
+			title:  "Update dependency eslint to v9 (major)",
+			branch: "renovate/eslint-9.x",
+			want:   DependencyUpdate{Name: "eslint", To: "9", UpdateType: "major"},
+			ok:     true,
+		},
+		{
+			name:   "renovate go module",
+			title:  "chore(deps): update module github.com/spf13/cobra to v1.10.1",
+			branch: "renovate/github.com-spf13-cobra-1.x",
+			want:   DependencyUpdate{Name: "github.com/spf13/cobra", To: "1.10.1", Ecosystem: "gomod", UpdateType: "patch"},
+			ok:     true,
+		},
+		{
+			name:   "renovate major update titled with the major version only",
+			title:  "Update module github.com/google/go-github to v75",
+			branch: "renovate/github.com-google-go-github-75.x",
+			want:   DependencyUpdate{Name: "github.com/google/go-github", To: "75", Ecosystem: "gomod", UpdateType: "major"},
+			ok:     true,
+		},
+		{
+			name:   "renovate minor update of a scoped npm package",
+			title:  "Update dependency @types/node to v20.12.0",
+			branch: "renovate/types-node-20.x",
+			want:   DependencyUpdate{Name: "@types/node", To: "20.12.0", Ecosystem: "npm", UpdateType: "minor"},
+			ok:     true,
+		},
+		{
+			name:   "renovate patch branch with separateMinorPatch and a manager prefix",
+			title:  "Update dependency express to v4.19.0",
+			branch: "renovate/npm-express-4.19.x",
+			want:   DependencyUpdate{Name: "express", To: "4.19.0", Ecosystem: "npm", UpdateType: "patch"},
+			ok:     true,
+		},
+		{
+			name:   "renovate title on a branch that is not from renovate",
+			title:  "Update dependency express to v4.19.0",
+			branch: "update-express",
+			want:   DependencyUpdate{Name: "express", To: "4.19.0"},
+			ok:     true,
+		},
+		{
+			name:   "renovate update with a from version",
+			title:  "fix(deps): update dependency lodash from 4.17.20 to v4.17.21",
+			branch: "renovate/lodash-4.x",
+			want:   DependencyUpdate{Name: "lodash", From: "4.17.20", To: "4.17.21", UpdateType: "patch"},
+			ok:     true,
+		},
+		{
+			name:   "update title without a version",
+			title:  "Fix: update README to mention X",
+			branch: "fix-readme",
+			ok:     false,
+		},
+		{
+			name:   "update title with text after the version",
+			title:  "Update docs to v2 layout",
+			branch: "docs-v2",
+			ok:     false,
+		},
+		{
+			name:   "update in the middle of a title",
+			title:  "Refactor and update lodash to v4.17.21",
+			branch: "refactor",
+			ok:     false,
+		},
+		{
+			name:   "dependabot grouped update is not a single dependency",
+			title:  "Bump the npm group across 1 directory with 3 updates",
+			branch: "dependabot/npm_and_yarn/npm-abc123",
+			ok:     false,
+		},
+		{
+			name:   "regular feature PR",
+			title:  "Add support for widgets",
+			branch: "feature/widgets",
+			ok:     false,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, ok := ParseDependencyUpdate(test.title, test.branch)
+			if ok != test.ok {
+				t.Fatalf("ParseDependencyUpdate(%q, %q) ok = %v; want %v", test.title, test.branch, ok, test.ok)
+			}
+			if got != test.want {
+				t.Errorf("ParseDependencyUpdate(%q, %q) = %+v; want %+v", test.title, test.branch, got, test.want)
+			}
+		})
+	}
+}
+
+func TestClassifyUpdate(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		from string
+		to   string
+		want string
+	}{
+		{from: "1.2.3", to: "2.0.0", want: "major"},
+		{from: "1.2.3", to: "1.3.0", want: "minor"},
+		{from: "1.2.3", to: "1.2.4", want: "patch"},
+		{from: "v1.2", to: "v1.2.1", want: "patch"},
+		{from: "3.12-alpine", to: "3.13-alpine", want: "minor"},
+		{from: "2.0.0-beta.1", to: "2.0.0", want: "patch"},
+		{from: "abc123", to: "def456", want: ""},
+		{from: "", to: "1.0.0", want: ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.from+"->"+test.to, func(t *testing.T) {
+			t.Parallel()
+
+			if got := classifyUpdate(test.from, test.to); got != test.want {
+				t.Errorf("classifyUpdate(%q, %q) = %q; want %q", test.from, test.to, got, test.want)
+			}
+		})
+	}
+}
+
+func TestDependencyMatchesCriteria(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		title       string
+		branch      string
+		updateTypes []string
+		ecosystems  []string
+		ignoreDeps  []string
+		want        bool
+	}{
+		{
+			name:   "no dependency filters",
+			title:  "Add support for widgets",
+			branch: "feature/widgets",
+			want:   true,
+		},
+		{
+			name:        "non dependency PR with filters",
+			title:       "Add support for widgets",
+			branch:      "feature/widgets",
+			updateTypes: []string{"patch"},
+			want:        false,
+		},
+		{
+			name:        "patch update allowed",
+			title:       "Bump lodash from 4.17.20 to 4.17.21",
+			branch:      "dependabot/npm_and_yarn/lodash-4.17.21",
+			updateTypes: []string{"patch", "minor"},
+			want:        true,
+		},
+		{
+			name:        "major update rejected",
+			title:       "Bump react from 17.0.2 to 18.2.0",
+			branch:      "dependabot/npm_and_yarn/react-18.2.0",
+			updateTypes: []string{"patch", "minor"},
+			want:        false,
+		},
+		{
+			name:        "renovate minor update without a from version matches",
+			title:       "Update dependency eslint to v9.1.0",
+			branch:      "renovate/eslint-9.x",
+			updateTypes: []string{"patch", "minor"},
+			want:        true,
+		},
+		{
+			name:        "renovate major update without a from version rejected",
+			title:       "Update dependency eslint to v9",
+			branch:      "renovate/eslint-9.x",
+			updateTypes: []string{"patch", "minor"},
+			want:        false,
+		},
+		{
+			name:        "unknown update type rejected",
+			title:       "Update dependency eslint to v9.1.0",
+			branch:      "update-eslint",
+			updateTypes: []string{"patch", "minor"},
+			want:        false,
+		},
+		{
+			name:       "renovate npm ecosystem from the manager prefix of the branch",
+			title:      "Update dependency eslint to v9.1.0",
+			branch:     "renovate/npm-eslint-9.x",
+			ecosystems: []string{"npm"},
+			want:       true,
+		},
+		{
+			name:       "ecosystem matches using the dependabot branch name",
+			title:      "Bump golang.org/x/sys from 0.36.0 to 0.37.0",
+			branch:     "dependabot/go_modules/golang.org/x/sys-0.37.0",
+			ecosystems: []string{"npm", "go_modules"},
+			want:       true,
+		},
+		{
+			name:       "ecosystem does not match",
+			title:      "Bump lodash from 4.17.20 to 4.17.21",
+			branch:     "dependabot/npm_and_yarn/lodash-4.17.21",
+			ecosystems: []string{"gomod"},
+			want:       false,
+		},
+		{
+			name:       "ignored dependency glob",
+			title:      "Bump react-dom from 18.2.0 to 18.2.1",
+			branch:     "dependabot/npm_and_yarn/react-dom-18.2.1",
+			ignoreDeps: []string{"react*"},
+			want:       false,
+		},
+		{
+			name:       "dependency not ignored",
+			title:      "Bump lodash from 4.17.20 to 4.17.21",
+			branch:     "dependabot/npm_and_yarn/lodash-4.17.21",
+			ignoreDeps: []string{"react*"},
+			want:       true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			got := dependencyMatchesCriteria(test.title, test.branch, test.updateTypes, test.ecosystems, test.ignoreDeps)
+			if got != test.want {
+				t.Errorf("dependencyMatchesCriteria(%q, %q) = %v; want %v", test.title, test.branch, got, test.want)
+			}
+		})
+	}
+}
+
+func TestValidateDependencyFilters(t *testing.T) {
+	t.Parallel()
+
+	if err := ValidateDependencyFilters([]string{"patch", "minor"}, []string{"react*"}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	if err := ValidateDependencyFilters([]string{"huge"}, nil); !errors.Is(err, errInvalidUpdateType) {
+		t.Errorf("want %v, got %v", errInvalidUpdateType, err)
+	}
+
+	if err := ValidateDependencyFilters(nil, []string{"[react"}); err == nil {
+		t.Error("expected an error for an invalid glob pattern")
+	}
+}
+
+func TestGenerateDependencyTable(t *testing.T) {
+	t.Parallel()
+
+	pulls := github.Pulls{
+		{Number: 1, Title: "Bump lodash from 4.17.20 to 4.17.21", Head: github.Ref{Ref: "dependabot/npm_and_yarn/lodash-4.17.21"}},
+		{Number: 2, Title: "Add support for widgets", Head: github.Ref{Ref: "feature/widgets"}},
+	}
+
+	got := generateDependencyTable(pulls)
+	if !strings.Contains(got, "| #1 | `lodash` | 4.17.20 | 4.17.21 | patch | npm |") {
+		t.Errorf("expected lodash row in table, got:\n%s", got)
+	}
+	if strings.Contains(got, "#2") {
+		t.Errorf("expected non dependency PR to be left out of the table, got:\n%s", got)
+	}
+
+	if got := generateDependencyTable(pulls[1:]); got != "" {
+		t.Errorf("expected empty table when no PRs are dependency updates, got:\n%s", got)
+	}
+}
@@ -0,0 +1,149 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// scratchBranchInfix separates the combined branch name from the unique suffix of dry-run scratch branches
+const scratchBranchInfix = "-dry-run-"
+
+// DryRunPlan describes what a combine would do, as predicted by trial merges on a scratch branch
+type DryRunPlan struct {
+	Combine        []PlannedPR
+	Conflicts      []PlannedPR
+	DeleteBranches []string
+	CreateBranches []string
+	Title          string
+	Body           string
+	Labels         []string
+}
+
+// PlannedPR is a source PR in a dry-run plan, with the reason it could not be merged if it conflicts
+type PlannedPR struct {
+	Number int
+	Title  string
+	Reason string
+}
+
+// planCombine performs trial merges of the PRs on a uniquely named scratch branch, which is always deleted
+// afterwards, and returns the predicted result along with a plan of the changes a real run would make
+func planCombine(ctx context.Context, restClient RESTClientInterface, opts CombineOpts, baseBranch, baseSHA string) (*CombineResult, error) {
+	result := &CombineResult{}
+	scratchBranch := combineBranchName + scratchBranchInfix + strconv.FormatInt(time.Now().UnixNano(), 36)
+
+	if err := createBranch(ctx, restClient, opts.Repo, scratchBranch, baseSHA); err != nil {
+		return result, fmt.Errorf("failed to create scratch branch: %w", err)
+	}
+	defer func() {
+		if err := deleteBranch(ctx, restClient, opts.Repo, scratchBranch); err != nil {
+			Logger.Warn("Failed to delete scratch branch", "repo", opts.Repo, "branch", scratchBranch, "error", err)
+		}
+	}()
+
+	plan := &DryRunPlan{}
+	for _, pr := range opts.Pulls {
+		// Check for cancellation
+		select {
+		case <-ctx.Done():
+			return result, ctx.Err()
+		default:
+			// Continue processing
+		}
+
+		if _, err := mergeBranch(ctx, restClient, opts.Repo, scratchBranch, mergeHead(opts.Repo, pr), mergeCommitMessage(pr)); err != nil {
+			Logger.Debug("Trial merge failed", "repo", opts.Repo, "branch", pr.Head.Ref, "error", err)
+			result.MergeConflicts = append(result.MergeConflicts, pr)
+			plan.Conflicts = append(plan.Conflicts, PlannedPR{Number: pr.Number, Title: pr.Title, Reason: mergeFailureReason(err)})
+			continue
+		}
+
+		Logger.Debug("Trial merge succeeded", "repo", opts.Repo, "branch", pr.Head.Ref)
+		result.Combined = append(result.Combined, pr)
+		plan.Combine = append(plan.Combine, PlannedPR{Number: pr.Number, Title: pr.Title})
+	}
+
+	// A real run replaces the combined and working branches if they are left over from a previous run
+	workingBranchName := combineBranchName + workingBranchSuffix
+	for _, branch := range []string{combineBranchName, workingBranchName} {
+		if _, err := getBranchSHA(ctx, restClient, opts.Repo, branch); err == nil {
+			plan.DeleteBranches = append(plan.DeleteBranches, branch)
+		}
+	}
+	plan.CreateBranches = []string{fmt.Sprintf("%s (from %s)", combineBranchName, baseBranch)}
+
+	var err error
+	plan.Title, plan.Body, err = renderPRTitleAndBody(newPRTemplateData(opts.Repo, result.Combined, result.MergeConflicts, nil, nil, opts.Command))
+	if err != nil {
+		return result, fmt.Errorf("failed to render combined PR: %w", err)
+	}
+	plan.Labels = combinedPRLabels(result.Combined).Names()
+
+	result.Plan = plan
+	return result, nil
+}
+
+// mergeFailureReason describes why a trial merge failed
+func mergeFailureReason(err error) string {
+	if isMergeConflictError(err) {
+		return "merge conflict with the base branch or a PR merged before it"
+	}
+	return err.Error()
+}
+
+// formatDryRunPlan renders a dry-run plan for a repository as indented text
+func formatDryRunPlan(repoName string, plan *DryRunPlan) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Plan for %s:\n", repoName)
+
+	fmt.Fprintf(&b, "  PRs that would be combined (%d):\n", len(plan.Combine))
+	for _, pr := range plan.Combine {
+		fmt.Fprintf(&b, "    - #%d %s\n", pr.Number, pr.Title)
+	}
+	if len(plan.Conflicts) > 0 {
+		fmt.Fprintf(&b, "  PRs that would conflict (%d):\n", len(plan.Conflicts))
+		for _, pr := range plan.Conflicts {
+			fmt.Fprintf(&b, "    - #%d %s: %s\n", pr.Number, pr.Title, pr.Reason)
+		}
+	}
+	for _, branch := range plan.DeleteBranches {
+		fmt.Fprintf(&b, "  Branch that would be deleted: %s\n", branch)
+	}
+	for _, branch := range plan.CreateBranches {
+		fmt.Fprintf(&b, "  Branch that would be created: %s\n", branch)
+	}
+
+	fmt.Fprintf(&b, "  PR title: %s\n", plan.Title)
+	if len(plan.Labels) > 0 {
+		fmt.Fprintf(&b, "  PR labels: %s\n", strings.Join(plan.Labels, ", "))
+	}
+	b.WriteString("  PR body:\n")
+	for _, line := range strings.Split(plan.Body, "\n") {
+		b.WriteString(strings.TrimRight("    "+line, " ") + "\n")
+	}
+	return b.String()
+}
+
+// displayDryRunPlans prints the dry-run plan of every repository that has one
+func displayDryRunPlans(stats *StatsCollector) {
+	writeDryRunPlans(os.Stdout, stats)
+}
+
+// writeDryRunPlans writes the dry-run plans sorted by repository name, so the output is the same on every run
+func writeDryRunPlans(w io.Writer, stats *StatsCollector) {
+	for _, name := range slices.Sorted(maps.Keys(stats.PerRepoStats)) {
+		repoStat := stats.PerRepoStats[name]
+		if repoStat.Plan == nil {
+			continue
+		}
+		fmt.Fprintln(w)
+		fmt.Fprint(w, formatDryRunPlan(repoStat.RepoName, repoStat.Plan))
+	}
+}
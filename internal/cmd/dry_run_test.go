@@ -0,0 +1,139 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/github/gh-combine/internal/github"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPlanCombine(t *testing.T) {
+	// Since this test changes global state, don't use t.Parallel()
+	origCombineBranchName, origWorkingBranchSuffix, origAddLabels := combineBranchName, workingBranchSuffix, addLabels
+	defer func() {
+		combineBranchName, workingBranchSuffix, addLabels = origCombineBranchName, origWorkingBranchSuffix, origAddLabels
+	}()
+	combineBranchName = "combined-prs"
+	workingBranchSuffix = "-working"
+	addLabels = []string{"dependencies"}
+
+	var created, deleted, mergedInto []string
+	client := &MockRESTClient{
+		PostFunc: func(endpoint string, body interface{}, response interface{}) error {
+			data, _ := io.ReadAll(body.(io.Reader))
+			var payload map[string]string
+			if err := json.Unmarshal(data, &payload); err != nil {
+				return err
+			}
+			switch {
+			case strings.HasSuffix(endpoint, "/git/refs"):
+				created = append(created, payload["ref"])
+			case strings.HasSuffix(endpoint, "/merges"):
+				if payload["head"] == "conflicting-branch" {
+					return errors.New("HTTP 409: Merge conflict")
+				}
+				mergedInto = append(mergedInto, payload["base"])
+			}
+			return nil
+		},
+		GetFunc: func(endpoint string, response interface{}) error {
+			// Only the combined branch is left over from a previous run
+			if strings.HasSuffix(endpoint, "/git/ref/heads/combined-prs") {
+				return json.Unmarshal([]byte(`{"object": {"sha": "old"}}`), response)
+			}
+			return errors.New("HTTP 404: Not Found")
+		},
+		DeleteFunc: func(endpoint string, response interface{}) error {
+			deleted = append(deleted, endpoint)
+			return nil
+		},
+	}
+
+	sameRepo := &github.RefRepo{FullName: "owner/repo"}
+	pulls := github.Pulls{
+		{Number: 1, Title: "Bump lodash", Head: github.Ref{Ref: "feature-1", Repo: sameRepo}},
+		{Number: 2, Title: "Bump react", Head: github.Ref{Ref: "conflicting-branch", Repo: sameRepo}},
+	}
+	opts := CombineOpts{Noop: true, Command: "gh combine owner/repo --dry-run", Repo: github.Repo{Owner: "owner", Repo: "repo"}, Pulls: pulls}
+
+	result, err := planCombine(context.Background(), client, opts, "main", "base")
+	assert.NoError(t, err)
+
+	// The trial merges happen on a scratch branch that is deleted afterwards
+	if assert.Len(t, created, 1) {
+		scratch := strings.TrimPrefix(created[0], "refs/heads/")
+		assert.True(t, strings.HasPrefix(scratch, "combined-prs-dry-run-"))
+		assert.Equal(t, []string{scratch}, mergedInto)
+		assert.Equal(t, []string{"repos/owner/repo/git/refs/heads/" + scratch}, deleted)
+	}
+
+	assert.Equal(t, github.Pulls{pulls[0]}, result.Combined)
+	assert.Equal(t, github.Pulls{pulls[1]}, result.MergeConflicts)
+
+	plan := result.Plan
+	if assert.NotNil(t, plan) {
+		assert.Equal(t, []PlannedPR{{Number: 1, Title: "Bump lodash"}}, plan.Combine)
+		assert.Equal(t, []PlannedPR{{Number: 2, Title: "Bump react", Reason: "merge conflict with the base branch or a PR merged before it"}}, plan.Conflicts)
+		assert.Equal(t, []string{"combined-prs"}, plan.DeleteBranches)
+		assert.Equal(t, []string{"combined-prs (from main)"}, plan.CreateBranches)
+		assert.Equal(t, []string{"dependencies"}, plan.Labels)
+		assert.Contains(t, plan.Body, "- closes: #1")
+
+		text := formatDryRunPlan("owner/repo", plan)
+		assert.Contains(t, text, "    - #2 Bump react: merge conflict")
+		assert.Contains(t, text, "  Branch that would be deleted: combined-prs\n")
+		assert.Contains(t, text, "  PR labels: dependencies\n")
+	}
+}
+
+func TestPlanCombineDeletesScratchBranchOnCancel(t *testing.T) {
+	// Since this test changes global state, don't use t.Parallel()
+	origCombineBranchName := combineBranchName
+	defer func() { combineBranchName = origCombineBranchName }()
+	combineBranchName = "combined-prs"
+
+	deleted := 0
+	client := &MockRESTClient{
+		PostFunc: func(endpoint string, body interface{}, response interface{}) error {
+			return nil
+		},
+		DeleteFunc: func(endpoint string, response interface{}) error {
+			deleted++
+			return nil
+		},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	opts := CombineOpts{Noop: true, Repo: github.Repo{Owner: "owner", Repo: "repo"}, Pulls: github.Pulls{{Number: 1}}}
+	_, err := planCombine(ctx, client, opts, "main", "base")
+
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, 1, deleted)
+}
+
+func TestWriteDryRunPlansSortsRepositories(t *testing.T) {
+	t.Parallel()
+
+	stats := &StatsCollector{PerRepoStats: map[string]*RepoStats{}}
+	for _, name := range []string{"owner/zeta", "owner/alpha", "owner/none", "owner/mid"} {
+		stats.PerRepoStats[name] = &RepoStats{RepoName: name}
+		if name != "owner/none" {
+			stats.PerRepoStats[name].Plan = &DryRunPlan{Title: "Combined PRs"}
+		}
+	}
+
+	var b strings.Builder
+	writeDryRunPlans(&b, stats)
+	text := b.String()
+
+	assert.NotContains(t, text, "owner/none")
+	alpha, mid, zeta := strings.Index(text, "Plan for owner/alpha"), strings.Index(text, "Plan for owner/mid"), strings.Index(text, "Plan for owner/zeta")
+	assert.True(t, alpha >= 0 && alpha < mid && mid < zeta, "plans should be sorted by repository name:\n%s", text)
+}
@@ -0,0 +1,426 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/github/gh-combine/internal/github"
+)
+
+var (
+	errInvalidFilter  = errors.New("invalid --filter expression")
+	errInvalidPattern = errors.New("invalid pattern")
+)
+
+// filterSubject holds the PR attributes a filter expression is evaluated against
+type filterSubject struct {
+	pull   github.Pull
+	labels []string
+	now    time.Time
+}
+
+// filterExpr is a node of a parsed filter expression
+type filterExpr interface {
+	eval(subject *filterSubject) bool
+	String() string
+}
+
+type andExpr struct{ left, right filterExpr }
+
+func (e andExpr) eval(s *filterSubject) bool { return e.left.eval(s) && e.right.eval(s) }
+func (e andExpr) String() string             { return "(" + e.left.String() + " AND " + e.right.String() + ")" }
+
+type orExpr struct{ left, right filterExpr }
+
+func (e orExpr) eval(s *filterSubject) bool { return e.left.eval(s) || e.right.eval(s) }
+func (e orExpr) String() string             { return "(" + e.left.String() + " OR " + e.right.String() + ")" }
+
+type notExpr struct{ expr filterExpr }
+
+func (e notExpr) eval(s *filterSubject) bool { return !e.expr.eval(s) }
+func (e notExpr) String() string             { return "NOT " + e.expr.String() }
+
+// predicateExpr is a leaf node that evaluates a single PR attribute
+type predicateExpr struct {
+	name  string
+	match func(s *filterSubject) bool
+}
+
+func (e predicateExpr) eval(s *filterSubject) bool { return e.match(s) }
+func (e predicateExpr) String() string             { return e.name }
+
+// andAll joins expressions with AND, ignoring nil expressions. It returns nil if there is nothing to join
+func andAll(exprs ...filterExpr) filterExpr {
+	var result filterExpr
+	for _, expr := range exprs {
+		if expr == nil {
+			continue
+		}
+		if result == nil {
+			result = expr
+		} else {
+			result = andExpr{result, expr}
+		}
+	}
+	return result
+}
+
+// evalFilter evaluates a filter expression against a pull request. A nil expression matches everything
+func evalFilter(expr filterExpr, pull github.Pull) bool {
+	if expr == nil {
+		return true
+	}
+	return expr.eval(&filterSubject{pull: pull, labels: pull.Labels.Names(), now: time.Now()})
+}
+
+// Filter expression tokens
+type filterTokenKind int
+
+const (
+	tokenTerm filterTokenKind = iota
+	tokenAnd
+	tokenOr
+	tokenNot
+	tokenLParen
+	tokenRParen
+)
+
+type filterToken struct {
+	kind  filterTokenKind
+	key   string
+	value string
+	regex bool
+	pos   int
+}
+
+// tokenizeFilter splits a filter expression into keywords, parentheses and key:value terms
+func tokenizeFilter(input string) ([]filterToken, error) {
+	var tokens []filterToken
+	runes := []rune(input)
+
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(':
+			tokens = append(tokens, filterToken{kind: tokenLParen, pos: i})
+			i++
+		case r == ')':
+			tokens = append(tokens, filterToken{kind: tokenRParen, pos: i})
+			i++
+		default:
+			start := i
+			for i < len(runes) && runes[i] != ':' && runes[i] != '(' && runes[i] != ')' && !unicode.IsSpace(runes[i]) {
+				i++
+			}
+			word := string(runes[start:i])
+
+			if i >= len(runes) || runes[i] != ':' {
+				switch strings.ToUpper(word) {
+				case "AND":
+					tokens = append(tokens, filterToken{kind: tokenAnd, pos: start})
+				case "OR":
+					tokens = append(tokens, filterToken{kind: tokenOr, pos: start})
+				case "NOT":
+					tokens = append(tokens, filterToken{kind: tokenNot, pos: start})
+				case "DRAFT":
+					tokens = append(tokens, filterToken{kind: tokenTerm, key: "draft", value: "true", pos: start})
+				default:
+					return nil, fmt.Errorf("%w: unexpected %q at position %d (expected key:value)", errInvalidFilter, word, start)
+				}
+				continue
+			}
+
+			// Skip the colon and read the value
+			i++
+			token := filterToken{kind: tokenTerm, key: strings.ToLower(word), pos: start}
+			value, next, regex, err := readFilterValue(runes, i)
+			if err != nil {
+				return nil, err
+			}
+			token.value, token.regex = value, regex
+			tokens = append(tokens, token)
+			i = next
+		}
+	}
+
+	return tokens, nil
+}
+
+// readFilterValue reads a quoted ("..."), regex (/.../) or bare value starting at position i
+func readFilterValue(runes []rune, i int) (value string, next int, regex bool, err error) {
+	if i < len(runes) && (runes[i] == '"' || runes[i] == '/') {
+		delim := runes[i]
+		var b strings.Builder
+		for j := i + 1; j < len(runes); j++ {
+			if runes[j] == '\\' && j+1 < len(runes) && runes[j+1] == delim {
+				b.WriteRune(delim)
+				j++
+				continue
+			}
+			if runes[j] == delim {
+				return b.String(), j + 1, delim == '/', nil
+			}
+			b.WriteRune(runes[j])
+		}
+		return "", 0, false, fmt.Errorf("%w: unterminated %c at position %d", errInvalidFilter, delim, i)
+	}
+
+	start := i
+	for i < len(runes) && runes[i] != ')' && !unicode.IsSpace(runes[i]) {
+		i++
+	}
+	if i == start {
+		return "", 0, false, fmt.Errorf("%w: missing value at position %d", errInvalidFilter, start)
+	}
+	return string(runes[start:i]), i, false, nil
+}
+
+// filterParser is a recursive descent parser for filter expressions:
+//
+//	expr    = and { "OR" and }
+//	and     = unary { ["AND"] unary }
+//	unary   = "NOT" unary | primary
+//	primary = "(" expr ")" | key ":" value
+type filterParser struct {
+	tokens        []filterToken
+	pos           int
+	caseSensitive bool
+}
+
+// parseFilter parses a filter expression. An empty expression returns nil, which matches everything
+func parseFilter(input string, caseSensitive bool) (filterExpr, error) {
+	tokens, err := tokenizeFilter(input)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return nil, nil
+	}
+
+	p := &filterParser{tokens: tokens, caseSensitive: caseSensitive}
+	expr, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("%w: unexpected token at position %d", errInvalidFilter, p.tokens[p.pos].pos)
+	}
+	return expr, nil
+}
+
+func (p *filterParser) peek() (filterToken, bool) {
+	if p.pos >= len(p.tokens) {
+		return filterToken{}, false
+	}
+	return p.tokens[p.pos], true
+}
+
+func (p *filterParser) parseOr() (filterExpr, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		token, ok := p.peek()
+		if !ok || token.kind != tokenOr {
+			return left, nil
+		}
+		p.pos++
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = orExpr{left, right}
+	}
+}
+
+func (p *filterParser) parseAnd() (filterExpr, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		token, ok := p.peek()
+		if !ok || token.kind == tokenOr || token.kind == tokenRParen {
+			return left, nil
+		}
+		// Adjacent terms are implicitly AND'd together
+		if token.kind == tokenAnd {
+			p.pos++
+		}
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = andExpr{left, right}
+	}
+}
+
+func (p *filterParser) parseUnary() (filterExpr, error) {
+	token, ok := p.peek()
+	if !ok {
+		return nil, fmt.Errorf("%w: unexpected end of expression", errInvalidFilter)
+	}
+	if token.kind == tokenNot {
+		p.pos++
+		expr, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return notExpr{expr}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *filterParser) parsePrimary() (filterExpr, error) {
+	token, _ := p.peek()
+	switch token.kind {
+	case tokenLParen:
+		p.pos++
+		expr, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		closing, ok := p.peek()
+		if !ok || closing.kind != tokenRParen {
+			return nil, fmt.Errorf("%w: missing closing parenthesis for position %d", errInvalidFilter, token.pos)
+		}
+		p.pos++
+		return expr, nil
+	case tokenTerm:
+		p.pos++
+		return p.compileTerm(token)
+	default:
+		return nil, fmt.Errorf("%w: unexpected token at position %d", errInvalidFilter, token.pos)
+	}
+}
+
+// compileTerm turns a key:value term into a predicate over the PR attributes
+func (p *filterParser) compileTerm(token filterToken) (filterExpr, error) {
+	name := token.key + ":" + token.value
+	if token.regex {
+		name = token.key + ":/" + token.value + "/"
+	}
+
+	switch token.key {
+	case "label":
+		match, err := newTextMatcher(token.value, token.regex, !p.caseSensitive, true)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", errInvalidFilter, err)
+		}
+		return predicateExpr{name, func(s *filterSubject) bool { return slices.ContainsFunc(s.labels, match) }}, nil
+	case "branch":
+		match, err := newTextMatcher(token.value, token.regex, false, true)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", errInvalidFilter, err)
+		}
+		return predicateExpr{name, func(s *filterSubject) bool { return match(s.pull.Head.Ref) }}, nil
+	case "author":
+		match, err := newTextMatcher(token.value, token.regex, true, true)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", errInvalidFilter, err)
+		}
+		return predicateExpr{name, func(s *filterSubject) bool { return match(s.pull.User.Login) }}, nil
+	case "title":
+		// Titles match anywhere rather than as a whole
+		match, err := newTextMatcher(token.value, token.regex, true, false)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", errInvalidFilter, err)
+		}
+		return predicateExpr{name, func(s *filterSubject) bool { return match(s.pull.Title) }}, nil
+	case "milestone":
+		if strings.EqualFold(token.value, "none") && !token.regex {
+			return predicateExpr{name, func(s *filterSubject) bool { return s.pull.Milestone == nil }}, nil
+		}
+		match, err := newTextMatcher(token.value, token.regex, true, true)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", errInvalidFilter, err)
+		}
+		return predicateExpr{name, func(s *filterSubject) bool {
+			return s.pull.Milestone != nil && match(s.pull.Milestone.Title)
+		}}, nil
+	case "draft":
+		draft, err := strconv.ParseBool(token.value)
+		if err != nil {
+			return nil, fmt.Errorf("%w: draft must be true or false, got %q", errInvalidFilter, token.value)
+		}
+		return predicateExpr{name, func(s *filterSubject) bool { return s.pull.Draft == draft }}, nil
+	case "age":
+		return compileAgeTerm(name, token.value)
+	default:
+		return nil, fmt.Errorf("%w: unknown key %q (must be one of label, branch, author, title, age, draft, milestone)", errInvalidFilter, token.key)
+	}
+}
+
+var ageRegex = regexp.MustCompile(`^(<=|>=|<|>)(\d+)([mhdw])$`)
+
+// compileAgeTerm compiles an age comparison such as age:>7d or age:<=12h
+func compileAgeTerm(name, value string) (filterExpr, error) {
+	m := ageRegex.FindStringSubmatch(value)
+	if m == nil {
+		return nil, fmt.Errorf("%w: age must be a comparison such as >7d, <12h or >=2w, got %q", errInvalidFilter, value)
+	}
+
+	n, _ := strconv.Atoi(m[2])
+	unit := map[string]time.Duration{"m": time.Minute, "h": time.Hour, "d": 24 * time.Hour, "w": 7 * 24 * time.Hour}[m[3]]
+	threshold := time.Duration(n) * unit
+	op := m[1]
+
+	return predicateExpr{name, func(s *filterSubject) bool {
+		age := s.now.Sub(s.pull.CreatedAt)
+		switch op {
+		case ">":
+			return age > threshold
+		case ">=":
+			return age >= threshold
+		case "<":
+			return age < threshold
+		default:
+			return age <= threshold
+		}
+	}}, nil
+}
+
+// newTextMatcher returns a matcher for a glob (* and ? wildcards) or regex value
+func newTextMatcher(value string, regex, caseInsensitive, anchored bool) (func(string) bool, error) {
+	pattern := value
+	if !regex {
+		pattern = globToRegex(value)
+		if anchored {
+			pattern = "^" + pattern + "$"
+		}
+	}
+	if caseInsensitive {
+		pattern = "(?i)" + pattern
+	}
+
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("%w %q: %v", errInvalidPattern, value, err)
+	}
+	return re.MatchString, nil
+}
+
+// globToRegex converts a glob with * and ? wildcards into a regular expression
+func globToRegex(glob string) string {
+	var b strings.Builder
+	for _, r := range glob {
+		switch r {
+		case '*':
+			b.WriteString(".*")
+		case '?':
+			b.WriteString(".")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,208 @@
+package cmd
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/github/gh-combine/internal/github"
+)
+
+func TestParseFilter(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	pull := github.Pull{
+		Number:    1,
+		Title:     "build(deps): Bump lodash from 4.17.20 to 4.17.21",
+		User:      github.User{Login: "renovate[bot]"},
+		Head:      github.Ref{Ref: "dependabot/npm_and_yarn/lodash-4.17.21"},
+		Labels:    github.Labels{{Name: "dependencies"}, {Name: "Security"}, {Name: "area/frontend"}},
+		Milestone: &github.Milestone{Title: "v2.0"},
+		CreatedAt: now.Add(-10 * 24 * time.Hour),
+	}
+
+	tests := []struct {
+		name   string
+		filter string
+		want   bool
+	}{
+		{name: "empty filter", filter: "", want: true},
+		{name: "single label", filter: "label:dependencies", want: true},
+		{name: "label is case insensitive", filter: "label:security", want: true},
+		{name: "missing label", filter: "label:wip", want: false},
+		{name: "label glob", filter: "label:area/*", want: true},
+		{name: "label regex", filter: "label:/^area\\/(front|back)end$/", want: true},
+		{name: "the example from the docs", filter: "label:dependencies AND (label:security OR author:renovate[bot]) AND NOT label:wip", want: true},
+		{name: "NOT negates", filter: "NOT label:dependencies", want: false},
+		{name: "OR with one side matching", filter: "label:wip OR label:dependencies", want: true},
+		{name: "AND binds tighter than OR", filter: "label:wip AND label:nope OR label:dependencies", want: true},
+		{name: "parentheses override precedence", filter: "label:wip AND (label:nope OR label:dependencies)", want: false},
+		{name: "implicit AND", filter: "label:dependencies label:wip", want: false},
+		{name: "lowercase keywords", filter: "label:dependencies and not label:wip", want: true},
+		{name: "branch glob", filter: "branch:dependabot/*", want: true},
+		{name: "branch is case sensitive", filter: "branch:Dependabot/*", want: false},
+		{name: "author exact", filter: "author:dependabot[bot]", want: false},
+		{name: "title matches anywhere", filter: "title:lodash", want: true},
+		{name: "quoted title", filter: `title:"bump lodash"`, want: true},
+		{name: "age greater than", filter: "age:>7d", want: true},
+		{name: "age less than", filter: "age:<1w", want: false},
+		{name: "draft keyword", filter: "NOT draft", want: true},
+		{name: "draft value", filter: "draft:true", want: false},
+		{name: "milestone glob", filter: "milestone:v2.*", want: true},
+		{name: "milestone none", filter: "milestone:none", want: false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			expr, err := parseFilter(test.filter, false)
+			if err != nil {
+				t.Fatalf("parseFilter(%q) returned error: %v", test.filter, err)
+			}
+
+			got := true
+			if expr != nil {
+				got = expr.eval(&filterSubject{pull: pull, labels: pull.Labels.Names(), now: now})
+			}
+			if got != test.want {
+				t.Errorf("filter %q = %v; want %v", test.filter, got, test.want)
+			}
+		})
+	}
+}
+
+func TestParseFilterCaseSensitiveLabels(t *testing.T) {
+	t.Parallel()
+
+	expr, err := parseFilter("label:security", true)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	pull := github.Pull{Labels: github.Labels{{Name: "Security"}}}
+	if evalFilter(expr, pull) {
+		t.Error("expected case-sensitive label match to fail")
+	}
+}
+
+func TestParseFilterErrors(t *testing.T) {
+	t.Parallel()
+
+	tests := []string{
+		"label",
+		"label:",
+		"unknown:value",
+		"label:a AND",
+		"(label:a",
+		"label:a)",
+		"NOT",
+		`title:"unterminated`,
+		"label:/[/",
+		"age:7d",
+		"age:>7y",
+		"draft:maybe",
+		"label:a OR OR label:b",
+	}
+
+	for _, filter := range tests {
+		t.Run(filter, func(t *testing.T) {
+			t.Parallel()
+
+			if _, err := parseFilter(filter, false); !errors.Is(err, errInvalidFilter) {
+				t.Errorf("parseFilter(%q) = %v; want %v", filter, err, errInvalidFilter)
+			}
+		})
+	}
+}
+
+func TestCompileCriteria(t *testing.T) {
+	// Since this test changes global state, don't use t.Parallel()
+	origBranchPrefix := branchPrefix
+	origSelectLabels := selectLabels
+	origCombineBranchName := combineBranchName
+	defer func() {
+		branchPrefix = origBranchPrefix
+		selectLabels = origSelectLabels
+		combineBranchName = origCombineBranchName
+	}()
+
+	branchPrefix = "dependabot/"
+	selectLabels = []string{"dependencies"}
+	combineBranchName = "combined-prs"
+
+	expr, err := compileCriteria("NOT label:wip")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		pull github.Pull
+		want bool
+	}{
+		{
+			name: "matches flags and filter",
+			pull: github.Pull{Head: github.Ref{Ref: "dependabot/npm/a"}, Labels: github.Labels{{Name: "dependencies"}}},
+			want: true,
+		},
+		{
+			name: "fails the branch flags",
+			pull: github.Pull{Head: github.Ref{Ref: "feature/a"}, Labels: github.Labels{{Name: "dependencies"}}},
+			want: false,
+		},
+		{
+			name: "fails the label flags",
+			pull: github.Pull{Head: github.Ref{Ref: "dependabot/npm/a"}},
+			want: false,
+		},
+		{
+			name: "fails the filter",
+			pull: github.Pull{Head: github.Ref{Ref: "dependabot/npm/a"}, Labels: github.Labels{{Name: "dependencies"}, {Name: "wip"}}},
+			want: false,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := evalFilter(expr, test.pull); got != test.want {
+				t.Errorf("evalFilter(%s) = %v; want %v", expr, got, test.want)
+			}
+		})
+	}
+}
+
+func TestLoadCriteria(t *testing.T) {
+	// Since this test changes global state, don't use t.Parallel()
+	origFilter := filterExpression
+	defer func() { filterExpression = origFilter }()
+
+	filterExpression = "label:dependencies"
+	first, err := loadCriteria()
+	if err != nil {
+		t.Fatalf("loadCriteria() error = %v", err)
+	}
+	second, err := loadCriteria()
+	if err != nil {
+		t.Fatalf("loadCriteria() error = %v", err)
+	}
+	if first.String() != second.String() {
+		t.Errorf("loadCriteria() compiled %s, then %s", first, second)
+	}
+
+	// A changed filter is compiled again
+	filterExpression = "label:dependencies AND NOT label:wip"
+	third, err := loadCriteria()
+	if err != nil {
+		t.Fatalf("loadCriteria() error = %v", err)
+	}
+	if third.String() == first.String() {
+		t.Errorf("loadCriteria() did not compile the changed filter, got %s", third)
+	}
+
+	filterExpression = "label:dependencies AND ("
+	if _, err := loadCriteria(); !errors.Is(err, errInvalidFilter) {
+		t.Errorf("loadCriteria() error = %v; want %v", err, errInvalidFilter)
+	}
+}
@@ -0,0 +1,224 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/cli/go-gh/v2/pkg/api"
+	"github.com/spf13/cobra"
+
+	"github.com/github/gh-combine/internal/github"
+)
+
+var (
+	keepBranches bool
+
+	errCombinedPRNotMerged = errors.New("combined PR is not merged")
+	errNoSourcePRs         = errors.New("no combined pull requests found in the PR body")
+
+	// Matches the entries of the combined pull request list, e.g. "- #12" or "- closes: #12"
+	combinedPREntryRegex = regexp.MustCompile(`^- (?:closes: )?#(\d+)`)
+)
+
+// FinalizeResult tracks what happened to the source PRs of a combined PR
+type FinalizeResult struct {
+	Closed          []int
+	AlreadyClosed   []int
+	DeletedBranches []string
+	Failed          []string
+}
+
+// newFinalizeCmd creates the finalize subcommand, which closes the source PRs of a merged combined PR
+func newFinalizeCmd() *cobra.Command {
+	finalizeCmd := &cobra.Command{
+		Use:   "finalize owner/repo#number",
+		Short: "Close the source PRs of a merged combined PR",
+		Long: `Close the source PRs of a merged combined PR with a comment linking to it, and delete their head branches.
+    This is useful when the closes keyword does not apply, e.g. with --no-autoclose or when the combined PR
+    targets a branch other than the default branch. It can be run from a workflow when the combined PR is merged.
+    Examples:
+      gh combine finalize owner/repo#42                  # Close the source PRs of owner/repo#42 and delete their branches
+      gh combine finalize owner/repo#42 --keep-branches  # Close the source PRs but keep their branches`,
+		Args: cobra.ExactArgs(1),
+		RunE: runFinalize,
+	}
+
+	finalizeCmd.Flags().BoolVar(&keepBranches, "keep-branches", false, "Do not delete the head branches of the source PRs")
+
+	return finalizeCmd
+}
+
+// runFinalize is the main execution function for the finalize command
+func runFinalize(cmd *cobra.Command, args []string) error {
+	ctx, cancel := SetupSignalContext()
+	defer cancel()
+
+	repo, number, err := github.ParsePullRequest(args[0])
+	if err != nil {
+		return err
+	}
+
+	restClient, err := api.DefaultRESTClient()
+	if err != nil {
+		return fmt.Errorf("failed to create REST client: %w", err)
+	}
+
+	restClientWrapper := struct {
+		RESTClientInterface
+	}{restClient}
+
+	result, err := finalizeCombinedPR(ctx, restClientWrapper, repo, number)
+	if err != nil {
+		return err
+	}
+
+	displayFinalizeResult(repo, number, result)
+	return nil
+}
+
+// finalizeCombinedPR closes the source PRs listed in the body of a merged combined PR and deletes their head branches
+func finalizeCombinedPR(ctx context.Context, client RESTClientInterface, repo github.Repo, number int) (*FinalizeResult, error) {
+	var combined github.Pull
+	if err := client.Get(fmt.Sprintf("repos/%s/%s/pulls/%d", repo.Owner, repo.Repo, number), &combined); err != nil {
+		return nil, fmt.Errorf("failed to get combined PR #%d: %w", number, err)
+	}
+	if !combined.Merged {
+		return nil, fmt.Errorf("%w: %s#%d", errCombinedPRNotMerged, repo, number)
+	}
+
+	sourcePRs := parseCombinedPRNumbers(combined.Body)
+	if len(sourcePRs) == 0 {
+		return nil, fmt.Errorf("%w: %s#%d", errNoSourcePRs, repo, number)
+	}
+
+	result := &FinalizeResult{}
+	for _, sourceNumber := range sourcePRs {
+		// Check for cancellation
+		select {
+		case <-ctx.Done():
+			return result, ctx.Err()
+		default:
+			// Continue processing
+		}
+
+		var source github.Pull
+		if err := client.Get(fmt.Sprintf("repos/%s/%s/pulls/%d", repo.Owner, repo.Repo, sourceNumber), &source); err != nil {
+			Logger.Warn("Failed to get source PR", "repo", repo, "pr", sourceNumber, "error", err)
+			result.Failed = append(result.Failed, fmt.Sprintf("#%d", sourceNumber))
+			continue
+		}
+
+		if source.State == "open" {
+			if err := closeSourcePR(ctx, client, repo, sourceNumber, number); err != nil {
+				Logger.Warn("Failed to close source PR", "repo", repo, "pr", sourceNumber, "error", err)
+				result.Failed = append(result.Failed, fmt.Sprintf("#%d", sourceNumber))
+				continue
+			}
+			result.Closed = append(result.Closed, sourceNumber)
+		} else {
+			Logger.Debug("Source PR is already closed", "repo", repo, "pr", sourceNumber)
+			result.AlreadyClosed = append(result.AlreadyClosed, sourceNumber)
+		}
+
+		if keepBranches || !canDeleteHeadBranch(repo, source) {
+			continue
+		}
+		if err := deleteBranch(ctx, client, repo, source.Head.Ref); err != nil {
+			// The branch may have already been deleted, or be protected
+			Logger.Warn("Failed to delete head branch of source PR", "repo", repo, "pr", sourceNumber, "branch", source.Head.Ref, "error", err)
+			continue
+		}
+		result.DeletedBranches = append(result.DeletedBranches, source.Head.Ref)
+	}
+
+	return result, nil
+}
+
+// closeSourcePR comments on a source PR with a link to the combined PR and closes it
+func closeSourcePR(ctx context.Context, client RESTClientInterface, repo github.Repo, sourceNumber, combinedNumber int) error {
+	if err := createIssueComment(ctx, client, repo, sourceNumber, finalizeComment(combinedNumber)); err != nil {
+		return err
+	}
+
+	return closePullRequest(ctx, client, repo, sourceNumber)
+}
+
+// createIssueComment posts a comment on an issue or pull request
+func createIssueComment(ctx context.Context, client RESTClientInterface, repo github.Repo, number int, body string) error {
+	payload, err := encodePayload(map[string]string{"body": body})
+	if err != nil {
+		return fmt.Errorf("failed to encode payload: %w", err)
+	}
+	return client.Post(fmt.Sprintf("repos/%s/%s/issues/%d/comments", repo.Owner, repo.Repo, number), payload, nil)
+}
+
+// finalizeComment is the comment left on source PRs when they are closed
+func finalizeComment(combinedNumber int) string {
+	return fmt.Sprintf("This pull request was combined into #%d, which has been merged, so it is being closed.\n\n%s", combinedNumber, generatedWithLink)
+}
+
+// canDeleteHeadBranch reports whether the head branch of a source PR lives in the repository itself.
+// Branches of forks and source PRs that were merged on their own are left alone
+func canDeleteHeadBranch(repo github.Repo, source github.Pull) bool {
+	if source.Merged || source.Head.Ref == "" || source.Head.Ref == source.Base.Ref {
+		return false
+	}
+	return !source.FromFork(repo)
+}
+
+// parseCombinedPRNumbers returns the numbers of the pull requests listed as combined in a combined PR body.
+// Bodies rendered from a --body-template carry a hidden marker with the numbers instead of the default list
+func parseCombinedPRNumbers(body string) []int {
+	var numbers []int
+	if match := combinedPRsMarkerRegex.FindStringSubmatch(body); match != nil {
+		for _, field := range strings.Split(match[1], ",") {
+			if number, err := strconv.Atoi(field); err == nil {
+				numbers = append(numbers, number)
+			}
+		}
+		return numbers
+	}
+
+	inList := false
+	for _, line := range strings.Split(strings.ReplaceAll(body, "\r\n", "\n"), "\n") {
+		line = strings.TrimSpace(line)
+		if line == combinedPRsHeader {
+			inList = true
+			continue
+		}
+		if !inList {
+			continue
+		}
+
+		match := combinedPREntryRegex.FindStringSubmatch(line)
+		if match == nil {
+			break
+		}
+		number, err := strconv.Atoi(match[1])
+		if err == nil {
+			numbers = append(numbers, number)
+		}
+	}
+	return numbers
+}
+
+// displayFinalizeResult prints the outcome of the finalize command
+func displayFinalizeResult(repo github.Repo, number int, result *FinalizeResult) {
+	fmt.Printf("Finalized %s:\n", colorize(fmt.Sprintf("%s#%d", repo, number), colorBlue))
+	for _, pr := range result.Closed {
+		fmt.Printf("- Closed #%d\n", pr)
+	}
+	for _, pr := range result.AlreadyClosed {
+		fmt.Printf("- #%d was already closed\n", pr)
+	}
+	for _, branch := range result.DeletedBranches {
+		fmt.Printf("- Deleted branch %s\n", branch)
+	}
+	for _, pr := range result.Failed {
+		fmt.Printf("- %s\n", colorize("Failed to close "+pr, colorYellow))
+	}
+}
@@ -0,0 +1,158 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/github/gh-combine/internal/github"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseCombinedPRNumbers(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		body string
+		want []int
+	}{
+		{
+			name: "closes keyword",
+			body: combinedPRsHeader + "\n- closes: #1\n- closes: #2\n\n⚠️ The following pull requests could not be merged due to conflicts:\n- #3\n",
+			want: []int{1, 2},
+		},
+		{
+			name: "without closes keyword",
+			body: combinedPRsHeader + "\r\n- #4\r\n- #5\r\n",
+			want: []int{4, 5},
+		},
+		{
+			name: "list followed by the dependency table",
+			body: combinedPRsHeader + "\n- #6\n\n| Dependency | From | To |\n| lodash | 1 | 2 |\n",
+			want: []int{6},
+		},
+		{
+			name: "hidden marker from a body template",
+			body: "Custom body mentioning #9\n\n<!-- gh-combine:prs=7,8 -->",
+			want: []int{7, 8},
+		},
+		{
+			name: "no list",
+			body: "Some other pull request\n- #7\n",
+			want: nil,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, test.want, parseCombinedPRNumbers(test.body))
+		})
+	}
+}
+
+func TestCanDeleteHeadBranch(t *testing.T) {
+	t.Parallel()
+
+	repo := github.Repo{Owner: "owner", Repo: "repo"}
+
+	tests := []struct {
+		name   string
+		source github.Pull
+		want   bool
+	}{
+		{
+			name:   "branch in the same repository",
+			source: github.Pull{Head: github.Ref{Ref: "dependabot/npm/a", Repo: &github.RefRepo{FullName: "Owner/Repo"}}, Base: github.Ref{Ref: "main"}},
+			want:   true,
+		},
+		{
+			name:   "branch in a fork",
+			source: github.Pull{Head: github.Ref{Ref: "patch-1", Repo: &github.RefRepo{FullName: "someone/repo"}}, Base: github.Ref{Ref: "main"}},
+			want:   false,
+		},
+		{
+			name:   "deleted fork",
+			source: github.Pull{Head: github.Ref{Ref: "patch-1"}, Base: github.Ref{Ref: "main"}},
+			want:   false,
+		},
+		{
+			name:   "merged on its own",
+			source: github.Pull{Merged: true, Head: github.Ref{Ref: "dependabot/npm/a", Repo: &github.RefRepo{FullName: "owner/repo"}}},
+			want:   false,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, test.want, canDeleteHeadBranch(repo, test.source))
+		})
+	}
+}
+
+func TestFinalizeCombinedPR(t *testing.T) {
+	// Since this test changes global state, don't use t.Parallel()
+	origKeepBranches := keepBranches
+	defer func() { keepBranches = origKeepBranches }()
+	keepBranches = false
+
+	repo := github.Repo{Owner: "owner", Repo: "repo"}
+	pulls := map[string]string{
+		"repos/owner/repo/pulls/42": `{"number": 42, "state": "closed", "merged": true, "body": "` + combinedPRsHeader + `\n- #1\n- #2\n- #3\n"}`,
+		"repos/owner/repo/pulls/1":  `{"number": 1, "state": "open", "head": {"ref": "dependabot/a", "repo": {"full_name": "owner/repo"}}, "base": {"ref": "main"}}`,
+		"repos/owner/repo/pulls/2":  `{"number": 2, "state": "closed", "head": {"ref": "dependabot/b", "repo": {"full_name": "owner/repo"}}, "base": {"ref": "main"}}`,
+		"repos/owner/repo/pulls/3":  `{"number": 3, "state": "open", "head": {"ref": "patch-1", "repo": {"full_name": "someone/repo"}}, "base": {"ref": "main"}}`,
+	}
+
+	var comments, closed, deleted []string
+	client := &MockRESTClient{
+		GetFunc: func(endpoint string, response interface{}) error {
+			pull, ok := pulls[endpoint]
+			if !ok {
+				return errors.New("HTTP 404: Not Found")
+			}
+			return json.Unmarshal([]byte(pull), response)
+		},
+		PostFunc: func(endpoint string, body interface{}, response interface{}) error {
+			comments = append(comments, endpoint)
+			return nil
+		},
+		PatchFunc: func(endpoint string, body io.Reader, response interface{}) error {
+			closed = append(closed, endpoint)
+			return nil
+		},
+		DeleteFunc: func(endpoint string, response interface{}) error {
+			deleted = append(deleted, endpoint)
+			return nil
+		},
+	}
+
+	result, err := finalizeCombinedPR(context.Background(), client, repo, 42)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []int{1, 3}, result.Closed)
+	assert.Equal(t, []int{2}, result.AlreadyClosed)
+	assert.Equal(t, []string{"dependabot/a", "dependabot/b"}, result.DeletedBranches)
+	assert.Equal(t, []string{"repos/owner/repo/issues/1/comments", "repos/owner/repo/issues/3/comments"}, comments)
+	assert.Equal(t, []string{"repos/owner/repo/pulls/1", "repos/owner/repo/pulls/3"}, closed)
+	assert.Equal(t, []string{"repos/owner/repo/git/refs/heads/dependabot/a", "repos/owner/repo/git/refs/heads/dependabot/b"}, deleted)
+}
+
+func TestFinalizeCombinedPRNotMerged(t *testing.T) {
+	t.Parallel()
+
+	client := &MockRESTClient{
+		GetFunc: func(endpoint string, response interface{}) error {
+			return json.Unmarshal([]byte(`{"number": 42, "state": "open", "merged": false}`), response)
+		},
+	}
+
+	_, err := finalizeCombinedPR(context.Background(), client, github.Repo{Owner: "owner", Repo: "repo"}, 42)
+	assert.ErrorIs(t, err, errCombinedPRNotMerged)
+}
@@ -6,7 +6,12 @@ import (
 	"slices"
 )
 
-var errLabelsConflict = errors.New("--ignore-labels contains a value which conflicts with --labels")
+var (
+	errLabelsConflict      = errors.New("--ignore-labels contains a value which conflicts with --labels")
+	errInvalidLabelsMode   = errors.New("invalid --labels-mode value")
+	errInvalidLabelPattern = errors.New("invalid label pattern")
+	errInvalidUpdateType   = errors.New("invalid --update-types value")
+)
 
 // validateInputs checks if the provided inputs are valid
 func ValidateInputs(args []string) error {
@@ -14,6 +19,60 @@ func ValidateInputs(args []string) error {
 		return err
 	}
 
+	if err := ValidateLabelsMode(labelsMode); err != nil {
+		return err
+	}
+
+	if err := ValidateLabelPatterns("--labels", selectLabels); err != nil {
+		return err
+	}
+	if err := ValidateLabelPatterns("--ignore-labels", ignoreLabels); err != nil {
+		return err
+	}
+	if err := ValidateLabelPatterns("--inherit-labels-include", inheritLabelsInclude); err != nil {
+		return err
+	}
+	if err := ValidateLabelPatterns("--inherit-labels-exclude", inheritLabelsExclude); err != nil {
+		return err
+	}
+
+	if err := ValidateDependencyFilters(updateTypes, ignoreDeps); err != nil {
+		return err
+	}
+
+	if err := ValidatePRNumbers(includePRs, excludePRs); err != nil {
+		return err
+	}
+
+	// Compile the criteria once, so an invalid --filter fails before any PR is fetched
+	if _, err := loadCriteria(); err != nil {
+		return err
+	}
+
+	if err := ValidateCIPending(ciPendingPolicy, waitForCITimeout); err != nil {
+		return err
+	}
+
+	if err := ValidateMergeMethod(autoMergeMethod); err != nil {
+		return err
+	}
+
+	if err := ValidateTemplates(titleTemplate, bodyTemplate); err != nil {
+		return err
+	}
+
+	if err := ValidatePRMetadata(reviewers, project); err != nil {
+		return err
+	}
+
+	if err := ValidateCommitMode(commitMode); err != nil {
+		return err
+	}
+
+	if err := ValidateUpdateMethod(updateMethod); err != nil {
+		return err
+	}
+
 	// If no args and no file, we can't proceed
 	if len(args) == 0 && reposFile == "" {
 		return errors.New("must specify repositories or provide a file containing a list of repositories with --file")
@@ -22,8 +81,10 @@ func ValidateInputs(args []string) error {
 	// Warn if no filtering options are provided at all
 	if branchPrefix == "" && branchSuffix == "" && branchRegex == "" &&
 		len(ignoreLabels) == 0 && len(selectLabels) == 0 &&
-		!requireCI && !mustBeApproved {
-		Logger.Warn("No filtering options specified. This will attempt to combine ALL open pull requests. Use  --labels, --ignore-labels, --branch-prefix, --branch-suffix, --branch-regex, --dependabot, etc to filter.")
+		len(updateTypes) == 0 && len(ecosystems) == 0 && len(ignoreDeps) == 0 &&
+		len(includePRs) == 0 && filterExpression == "" &&
+		!requireCI && len(requireChecks) == 0 && !mustBeApproved {
+		Logger.Warn("No filtering options specified. This will attempt to combine ALL open pull requests. Use  --labels, --ignore-labels, --filter, --branch-prefix, --branch-suffix, --branch-regex, --dependabot, etc to filter.")
 	}
 
 	return nil
@@ -39,3 +100,17 @@ func ValidateLabels(selectLabels []string, ignoreLabels []string) error {
 
 	return nil
 }
+
+// ValidateLabelsMode checks that --labels-mode is valid
+func ValidateLabelsMode(mode string) error {
+	if mode != labelsModeAny && mode != labelsModeAll {
+		return fmt.Errorf("%w: %q (must be %s or %s)", errInvalidLabelsMode, mode, labelsModeAny, labelsModeAll)
+	}
+	return nil
+}
+
+// ValidateLabelPatterns checks that every label pattern given with a flag compiles
+func ValidateLabelPatterns(flag string, patterns []string) error {
+	_, err := compileLabelPatterns(flag, patterns, true)
+	return err
+}
@@ -3,6 +3,7 @@ package cmd
 import (
 	"errors"
 	"os"
+	"strings"
 	"testing"
 )
 
@@ -47,6 +48,69 @@ func TestValidateLabels(t *testing.T) {
 	}
 }
 
+func TestValidateLabelsMode(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		mode    string
+		wantErr bool
+	}{
+		{mode: labelsModeAny},
+		{mode: labelsModeAll},
+		{mode: "some", wantErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.mode, func(t *testing.T) {
+			t.Parallel()
+
+			err := ValidateLabelsMode(test.mode)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("ValidateLabelsMode(%q) = %v; wantErr %v", test.mode, err, test.wantErr)
+			}
+		})
+	}
+
+	if err := ValidateLabelsMode("some"); !errors.Is(err, errInvalidLabelsMode) {
+		t.Errorf("want %v, got %v", errInvalidLabelsMode, err)
+	}
+}
+
+func TestValidateLabelPatterns(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		flag     string
+		patterns []string
+		wantErr  bool
+	}{
+		{flag: "--labels", patterns: []string{"area/*", "glob:area/*", "regex:^team-(a|b)$"}},
+		{flag: "--labels", patterns: []string{"/[/"}},
+		{flag: "--labels", patterns: []string{"regex:["}, wantErr: true},
+		{flag: "--ignore-labels", patterns: []string{"regex:("}, wantErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.flag, func(t *testing.T) {
+			t.Parallel()
+
+			err := ValidateLabelPatterns(test.flag, test.patterns)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("ValidateLabelPatterns(%q, %q) = %v; wantErr %v", test.flag, test.patterns, err, test.wantErr)
+			}
+			if err == nil {
+				return
+			}
+			if !errors.Is(err, errInvalidLabelPattern) || errors.Is(err, errInvalidFilter) {
+				t.Errorf("want %v, got %v", errInvalidLabelPattern, err)
+			}
+			if !strings.Contains(err.Error(), test.flag) {
+				t.Errorf("want the error to name %s, got %v", test.flag, err)
+			}
+		})
+	}
+}
+
 /*
 // mockLogger creates a test logger that writes to a bytes.Buffer
 func setupMockLogger() (*bytes.Buffer, func()) {
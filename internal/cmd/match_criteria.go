@@ -6,25 +6,86 @@ import (
 	"regexp"
 	"slices"
 	"strings"
+	"time"
 
 	"github.com/cli/go-gh/v2/pkg/api"
 	graphql "github.com/cli/shurcooL-graphql"
-	"github.com/github/gh-combine/internal/common"
+	"github.com/github/gh-combine/internal/github"
 )
 
+// compiledCriteria caches the compiled criteria, so the --filter expression and the label patterns are compiled once
+// per run. It is keyed by every input compileCriteria captures, the other flags are read when a PR is evaluated
+var compiledCriteria struct {
+	filter        string
+	caseSensitive bool
+	selectLabels  []string
+	ignoreLabels  []string
+	labelsMode    string
+	expr          filterExpr
+}
+
+// loadCriteria returns the compiled criteria, compiling them again only when one of their inputs changed
+func loadCriteria() (filterExpr, error) {
+	if compiledCriteria.expr != nil &&
+		compiledCriteria.filter == filterExpression &&
+		compiledCriteria.caseSensitive == caseSensitiveLabels &&
+		slices.Equal(compiledCriteria.selectLabels, selectLabels) &&
+		slices.Equal(compiledCriteria.ignoreLabels, ignoreLabels) &&
+		compiledCriteria.labelsMode == labelsMode {
+		return compiledCriteria.expr, nil
+	}
+
+	expr, err := compileCriteria(filterExpression)
+	if err != nil {
+		return nil, err
+	}
+	compiledCriteria.filter, compiledCriteria.caseSensitive = filterExpression, caseSensitiveLabels
+	compiledCriteria.selectLabels, compiledCriteria.ignoreLabels = slices.Clone(selectLabels), slices.Clone(ignoreLabels)
+	compiledCriteria.labelsMode, compiledCriteria.expr = labelsMode, expr
+	return expr, nil
+}
+
 // checks if a PR matches all filtering criteria
-func PrMatchesCriteria(branch string, prLabels []string) bool {
-	// Check branch criteria if any are specified
-	if !branchMatchesCriteria(branch, combineBranchName, branchPrefix, branchSuffix, branchRegex) {
+func PrMatchesCriteria(pull github.Pull) bool {
+	// The criteria are compiled by ValidateInputs, so this only fails if the inputs were not validated
+	expr, err := loadCriteria()
+	if err != nil {
+		Logger.Warn("Invalid filter expression", "filter", filterExpression, "error", err)
 		return false
 	}
 
-	// Check label criteria if any are specified
-	if !labelsMatch(prLabels, ignoreLabels, selectLabels, caseSensitiveLabels) {
-		return false
+	Logger.Debug("Checking PR against filter", "pr", pull.Number, "filter", expr)
+	return evalFilter(expr, pull)
+}
+
+// compileCriteria compiles the branch, label and dependency flags together with
+// the --filter expression into a single filter expression
+func compileCriteria(filter string) (filterExpr, error) {
+	userFilter, err := parseFilter(filter, caseSensitiveLabels)
+	if err != nil {
+		return nil, err
 	}
 
-	return true
+	branchFlags := predicateExpr{"branch-flags", func(s *filterSubject) bool {
+		return branchMatchesCriteria(s.pull.Head.Ref, combineBranchName, branchPrefix, branchSuffix, branchRegex)
+	}}
+	ignoreMatchers, err := compileLabelPatterns("--ignore-labels", ignoreLabels, caseSensitiveLabels)
+	if err != nil {
+		return nil, err
+	}
+	selectMatchers, err := compileLabelPatterns("--labels", selectLabels, caseSensitiveLabels)
+	if err != nil {
+		return nil, err
+	}
+	mode := labelsMode
+	labelFlags := predicateExpr{"label-flags", func(s *filterSubject) bool {
+		return matchLabels(s.labels, ignoreMatchers, selectMatchers, mode)
+	}}
+	dependencyFlags := predicateExpr{"dependency-flags", func(s *filterSubject) bool {
+		return dependencyMatchesCriteria(s.pull.Title, s.pull.Head.Ref, updateTypes, ecosystems, ignoreDeps)
+	}}
+
+	return andAll(branchFlags, labelFlags, dependencyFlags, userFilter), nil
 }
 
 // checks if a branch matches the branch filtering criteria
@@ -72,40 +133,118 @@ func branchMatchesCriteria(branch, combineBranchName, branchPrefix, branchSuffix
 	return true
 }
 
-func labelsMatch(prLabels, ignoreLabels, selectLabels []string, caseSensitive bool) bool {
-	// If no ignoreLabels or selectLabels are specified, all labels pass this check
-	if len(ignoreLabels) == 0 && len(selectLabels) == 0 {
-		return true
+// Label matching modes for --labels-mode
+const (
+	labelsModeAny = "any"
+	labelsModeAll = "all"
+)
+
+// labelsMatch checks the PR labels against the ignore and select label patterns.
+// Patterns can be exact names, globs (glob:area/*) or regular expressions (regex:^area/.*).
+// With the all mode every select pattern must match a label, otherwise any one of them is enough
+func labelsMatch(prLabels, ignoreLabels, selectLabels []string, caseSensitive bool, mode string) bool {
+	ignoreMatchers, err := compileLabelPatterns("--ignore-labels", ignoreLabels, caseSensitive)
+	if err != nil {
+		Logger.Warn("Invalid label pattern", "error", err)
+		return false
+	}
+	selectMatchers, err := compileLabelPatterns("--labels", selectLabels, caseSensitive)
+	if err != nil {
+		Logger.Warn("Invalid label pattern", "error", err)
+		return false
 	}
+	return matchLabels(prLabels, ignoreMatchers, selectMatchers, mode)
+}
 
-	// Normalize labels for case-insensitive matching if caseSensitive is false
-	if !caseSensitive {
-		prLabels = common.NormalizeArray(prLabels)
-		ignoreLabels = common.NormalizeArray(ignoreLabels)
-		selectLabels = common.NormalizeArray(selectLabels)
+// matchLabels checks the labels of a PR against compiled ignore and select label patterns
+func matchLabels(prLabels []string, ignoreMatchers, selectMatchers []func(string) bool, mode string) bool {
+	// If no ignoreLabels or selectLabels are specified, all labels pass this check
+	if len(ignoreMatchers) == 0 && len(selectMatchers) == 0 {
+		return true
 	}
 
 	// If the pull request contains any of the ignore labels, it doesn't match
-	for _, l := range ignoreLabels {
-		if slices.Contains(prLabels, l) {
+	for _, match := range ignoreMatchers {
+		if slices.ContainsFunc(prLabels, match) {
 			return false
 		}
 	}
 
 	// If selectLabels are specified but the pull request has no labels, it doesn't match
-	if len(selectLabels) > 0 && len(prLabels) == 0 {
+	if len(selectMatchers) > 0 && len(prLabels) == 0 {
 		return false
 	}
 
+	// If the pull request must contain all of the select labels, any missing label means it doesn't match
+	if mode == labelsModeAll {
+		for _, match := range selectMatchers {
+			if !slices.ContainsFunc(prLabels, match) {
+				return false
+			}
+		}
+		return true
+	}
+
 	// If the pull request contains any of the select labels, it matches
-	for _, l := range selectLabels {
-		if slices.Contains(prLabels, l) {
+	for _, match := range selectMatchers {
+		if slices.ContainsFunc(prLabels, match) {
 			return true
 		}
 	}
 
 	// If none of the select labels are found, it doesn't match
-	return len(selectLabels) == 0
+	return len(selectMatchers) == 0
+}
+
+// compileLabelPatterns compiles label patterns into matchers, so they are compiled once rather than for every PR
+func compileLabelPatterns(flag string, patterns []string, caseSensitive bool) ([]func(string) bool, error) {
+	matchers := make([]func(string) bool, 0, len(patterns))
+	for _, pattern := range patterns {
+		match, err := newLabelMatcher(pattern, caseSensitive)
+		if err != nil {
+			return nil, fmt.Errorf("%w in %s: %w", errInvalidLabelPattern, flag, err)
+		}
+		matchers = append(matchers, match)
+	}
+	return matchers, nil
+}
+
+// hasMatchingLabel checks if any of the PR labels matches a label pattern
+func hasMatchingLabel(prLabels []string, pattern string, caseSensitive bool) bool {
+	match, err := newLabelMatcher(pattern, caseSensitive)
+	if err != nil {
+		Logger.Warn("Invalid label pattern", "pattern", pattern, "error", err)
+		return false
+	}
+	return slices.ContainsFunc(prLabels, match)
+}
+
+// Prefixes that mark a label pattern as a glob or a regular expression, since label names can contain
+// wildcard characters and slashes themselves
+const (
+	globLabelPrefix  = "glob:"
+	regexLabelPrefix = "regex:"
+)
+
+// newLabelMatcher returns a matcher for a label name, a glob prefixed with glob: or a regex prefixed with regex:.
+// Label names are matched exactly, so names with wildcard characters or slashes keep working
+func newLabelMatcher(pattern string, caseSensitive bool) (func(string) bool, error) {
+	if regex, ok := strings.CutPrefix(pattern, regexLabelPrefix); ok {
+		return newTextMatcher(regex, true, !caseSensitive, true)
+	}
+	if glob, ok := strings.CutPrefix(pattern, globLabelPrefix); ok {
+		return newTextMatcher(glob, false, !caseSensitive, true)
+	}
+	if caseSensitive {
+		return func(label string) bool { return label == pattern }, nil
+	}
+	return func(label string) bool { return strings.EqualFold(label, pattern) }, nil
+}
+
+// checkContext is a single check run or commit status reported on a commit
+type checkContext struct {
+	Name  string `json:"name"`
+	State string `json:"state"`
 }
 
 // GraphQL response structure for PR status info
@@ -118,7 +257,8 @@ type prStatusResponse struct {
 					Nodes []struct {
 						Commit struct {
 							StatusCheckRollup *struct {
-								State string `json:"state"`
+								State    string         `json:"state"`
+								Contexts []checkContext `json:"contexts"`
 							} `json:"statusCheckRollup"`
 						} `json:"commit"`
 					} `json:"nodes"`
@@ -131,7 +271,40 @@ type prStatusResponse struct {
 	} `json:"errors,omitempty"`
 }
 
-// GetPRStatusInfo fetches both CI status and approval status using GitHub's GraphQL API
+// checkContextConnection is a page of the check runs and commit statuses reported on a commit
+type checkContextConnection struct {
+	Nodes []struct {
+		CheckRun struct {
+			Name       string
+			Status     string
+			Conclusion string
+		} `graphql:"... on CheckRun"`
+		StatusContext struct {
+			Context string
+			State   string
+		} `graphql:"... on StatusContext"`
+	}
+	PageInfo struct {
+		HasNextPage bool
+		EndCursor   string
+	}
+}
+
+// checkContexts converts a page of check runs and commit statuses to check contexts
+func (c checkContextConnection) checkContexts() []checkContext {
+	contexts := []checkContext{}
+	for _, node := range c.Nodes {
+		if node.CheckRun.Name != "" {
+			contexts = append(contexts, checkContext{Name: node.CheckRun.Name, State: checkRunState(node.CheckRun.Status, node.CheckRun.Conclusion)})
+		} else if node.StatusContext.Context != "" {
+			contexts = append(contexts, checkContext{Name: node.StatusContext.Context, State: node.StatusContext.State})
+		}
+	}
+	return contexts
+}
+
+// GetPRStatusInfo fetches the CI status, the individual check contexts and the approval status using GitHub's GraphQL API.
+// The check contexts are paginated, so commits with more than 100 checks are evaluated completely
 func GetPRStatusInfo(ctx context.Context, graphQlClient *api.GraphQLClient, owner, repo string, prNumber int) (*prStatusResponse, error) {
 	// Check for context cancellation
 	select {
@@ -150,7 +323,8 @@ func GetPRStatusInfo(ctx context.Context, graphQlClient *api.GraphQLClient, owne
 					Nodes []struct {
 						Commit struct {
 							StatusCheckRollup *struct {
-								State string
+								State    string
+								Contexts checkContextConnection `graphql:"contexts(first: 100, after: $contextsCursor)"`
 							}
 						}
 					}
@@ -161,62 +335,131 @@ func GetPRStatusInfo(ctx context.Context, graphQlClient *api.GraphQLClient, owne
 
 	// Prepare GraphQL query variables
 	variables := map[string]interface{}{
-		"owner":    graphql.String(owner),
-		"repo":     graphql.String(repo),
-		"prNumber": graphql.Int(prNumber),
+		"owner":          graphql.String(owner),
+		"repo":           graphql.String(repo),
+		"prNumber":       graphql.Int(prNumber),
+		"contextsCursor": (*graphql.String)(nil),
 	}
 
-	// Execute GraphQL query
-	err := graphQlClient.Query("PullRequestStatus", &query, variables)
-	if err != nil {
-		return nil, fmt.Errorf("GraphQL query failed: %w", err)
-	}
-
-	// Convert to our response format
 	response := &prStatusResponse{}
-	response.Data.Repository.PullRequest.ReviewDecision = query.Repository.PullRequest.ReviewDecision
+	for {
+		// Execute GraphQL query
+		err := graphQlClient.QueryWithContext(ctx, "PullRequestStatus", &query, variables)
+		if err != nil {
+			return nil, fmt.Errorf("GraphQL query failed: %w", err)
+		}
+
+		// Convert to our response format
+		response.Data.Repository.PullRequest.ReviewDecision = query.Repository.PullRequest.ReviewDecision
+
+		commits := query.Repository.PullRequest.Commits.Nodes
+		if len(commits) == 0 {
+			return response, nil
+		}
+
+		rollup := commits[0].Commit.StatusCheckRollup
+		if rollup == nil {
+			response.Data.Repository.PullRequest.Commits.Nodes = make([]struct {
+				Commit struct {
+					StatusCheckRollup *struct {
+						State    string         `json:"state"`
+						Contexts []checkContext `json:"contexts"`
+					} `json:"statusCheckRollup"`
+				} `json:"commit"`
+			}, 1)
+			return response, nil
+		}
 
-	if len(query.Repository.PullRequest.Commits.Nodes) > 0 {
-		response.Data.Repository.PullRequest.Commits.Nodes = make([]struct {
+		setStatusCheckRollup(response, rollup.State, rollup.Contexts.checkContexts())
+		if !rollup.Contexts.PageInfo.HasNextPage {
+			return response, nil
+		}
+		variables["contextsCursor"] = graphql.NewString(graphql.String(rollup.Contexts.PageInfo.EndCursor))
+	}
+}
+
+// setStatusCheckRollup sets the rollup state of the last commit of a status response, appending contexts to the ones
+// from earlier pages
+func setStatusCheckRollup(response *prStatusResponse, state string, contexts []checkContext) {
+	commits := &response.Data.Repository.PullRequest.Commits.Nodes
+	if len(*commits) == 0 {
+		*commits = make([]struct {
 			Commit struct {
 				StatusCheckRollup *struct {
-					State string `json:"state"`
+					State    string         `json:"state"`
+					Contexts []checkContext `json:"contexts"`
 				} `json:"statusCheckRollup"`
 			} `json:"commit"`
-		}, len(query.Repository.PullRequest.Commits.Nodes))
-
-		for i, node := range query.Repository.PullRequest.Commits.Nodes {
-			if node.Commit.StatusCheckRollup != nil {
-				response.Data.Repository.PullRequest.Commits.Nodes[i].Commit.StatusCheckRollup = &struct {
-					State string `json:"state"`
-				}{
-					State: node.Commit.StatusCheckRollup.State,
-				}
-			}
-		}
+		}, 1)
 	}
 
-	return response, nil
+	commit := &(*commits)[0].Commit
+	if commit.StatusCheckRollup == nil {
+		commit.StatusCheckRollup = &struct {
+			State    string         `json:"state"`
+			Contexts []checkContext `json:"contexts"`
+		}{Contexts: []checkContext{}}
+	}
+	commit.StatusCheckRollup.State = state
+	commit.StatusCheckRollup.Contexts = append(commit.StatusCheckRollup.Contexts, contexts...)
 }
 
+// Reasons why a PR does not meet the additional requirements
+const (
+	requirementCIFailing   = "CI failing"
+	requirementCIPending   = "CI pending"
+	requirementNoChecks    = "no CI checks"
+	requirementNotApproved = "not approved"
+)
+
 // PrMeetsRequirements checks if a PR meets additional requirements beyond basic criteria
-func PrMeetsRequirements(ctx context.Context, graphQlClient *api.GraphQLClient, owner, repo string, prNumber int) (bool, error) {
+func PrMeetsRequirements(ctx context.Context, graphQlClient *api.GraphQLClient, spinner *Spinner, owner, repo string, prNumber int) (bool, error) {
+	reason, err := unmetRequirement(ctx, graphQlClient, spinner, owner, repo, prNumber)
+	return reason == "" && err == nil, err
+}
+
+// unmetRequirement returns the first additional requirement a PR does not meet, or an empty string if it meets them all
+func unmetRequirement(ctx context.Context, graphQlClient *api.GraphQLClient, spinner *Spinner, owner, repo string, prNumber int) (string, error) {
+	checkCI := requireCI || len(requireChecks) > 0
+
 	// If no additional requirements are specified, the PR meets requirements
-	if !requireCI && !mustBeApproved {
-		return true, nil
+	if !checkCI && !mustBeApproved {
+		return "", nil
 	}
 
 	// Fetch PR status info once
 	response, err := GetPRStatusInfo(ctx, graphQlClient, owner, repo, prNumber)
 	if err != nil {
-		return false, err
+		return "", err
 	}
 
 	// Check CI status if required
-	if requireCI {
-		passing := isCIPassing(response)
-		if !passing {
-			return false, nil
+	if checkCI {
+		state := ciState(response, requireChecks, ignoreChecks)
+
+		if state == ciStatePending {
+			switch ciPendingPolicy {
+			case ciPendingAllow:
+				Logger.Debug("PR has pending CI checks, allowing", "pr", prNumber)
+				state = ciStatePassing
+			case ciPendingWait:
+				response, state, err = waitForCI(ctx, graphQlClient, spinner, owner, repo, prNumber, prCIDeadline(time.Now()))
+				if err != nil {
+					return "", err
+				}
+			}
+		}
+
+		if !ciPassing(state) {
+			Logger.Debug("PR CI is not passing", "pr", prNumber, "state", state)
+			switch state {
+			case ciStatePending:
+				return requirementCIPending, nil
+			case ciStateNone:
+				return requirementNoChecks, nil
+			default:
+				return requirementCIFailing, nil
+			}
 		}
 	}
 
@@ -224,33 +467,16 @@ func PrMeetsRequirements(ctx context.Context, graphQlClient *api.GraphQLClient,
 	if mustBeApproved {
 		approved := isPRApproved(response)
 		if !approved {
-			return false, nil
+			return requirementNotApproved, nil
 		}
 	}
 
-	return true, nil
+	return "", nil
 }
 
 // isCIPassing checks if the CI status is passing based on the response
 func isCIPassing(response *prStatusResponse) bool {
-	commits := response.Data.Repository.PullRequest.Commits.Nodes
-	if len(commits) == 0 {
-		Logger.Debug("No commits found for PR")
-		return false
-	}
-
-	statusCheckRollup := commits[0].Commit.StatusCheckRollup
-	if statusCheckRollup == nil {
-		Logger.Debug("No status checks found for PR")
-		return true // If no checks defined, consider it passing
-	}
-
-	if statusCheckRollup.State != "SUCCESS" {
-		Logger.Debug("PR failed CI check", "status", statusCheckRollup.State)
-		return false
-	}
-
-	return true
+	return ciPassing(ciState(response, requireChecks, ignoreChecks))
 }
 
 // isPRApproved checks if the PR is approved based on the response
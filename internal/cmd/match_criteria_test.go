@@ -3,6 +3,8 @@ package cmd
 import (
 	"sync"
 	"testing"
+
+	"github.com/github/gh-combine/internal/github"
 )
 
 func TestLabelsMatch(t *testing.T) {
@@ -12,6 +14,7 @@ func TestLabelsMatch(t *testing.T) {
 		ignoreLabels  []string
 		selectLabels  []string
 		caseSensitive bool
+		mode          string
 		want          bool
 	}{
 		{
@@ -145,12 +148,102 @@ func TestLabelsMatch(t *testing.T) {
 			want:          false,
 			caseSensitive: true,
 		},
+		{
+			name:         "all mode with every select label present so it matches",
+			prLabels:     []string{"security", "dependencies"},
+			selectLabels: []string{"security", "dependencies"},
+			mode:         labelsModeAll,
+			want:         true,
+		},
+		{
+			name:         "all mode with one select label missing so it doesn't match",
+			prLabels:     []string{"security"},
+			selectLabels: []string{"security", "dependencies"},
+			mode:         labelsModeAll,
+			want:         false,
+		},
+		{
+			name:         "any mode with one select label missing so it matches",
+			prLabels:     []string{"security"},
+			selectLabels: []string{"security", "dependencies"},
+			mode:         labelsModeAny,
+			want:         true,
+		},
+		{
+			name:         "select label glob matches a prefixed label",
+			prLabels:     []string{"area/frontend"},
+			selectLabels: []string{"glob:area/*"},
+			want:         true,
+		},
+		{
+			name:         "select label glob does not match",
+			prLabels:     []string{"team/frontend"},
+			selectLabels: []string{"glob:area/*"},
+			want:         false,
+		},
+		{
+			name:         "ignore label glob matches",
+			prLabels:     []string{"dependencies", "wip/blocked"},
+			selectLabels: []string{"dependencies"},
+			ignoreLabels: []string{"glob:wip*"},
+			want:         false,
+		},
+		{
+			name:         "select label regex matches case insensitively",
+			prLabels:     []string{"Team-A"},
+			selectLabels: []string{"regex:^team-(a|b)$"},
+			want:         true,
+		},
+		{
+			name:          "select label regex is case sensitive when requested",
+			prLabels:      []string{"Team-A"},
+			selectLabels:  []string{"regex:^team-(a|b)$"},
+			caseSensitive: true,
+			want:          false,
+		},
+		{
+			name:         "label names with slashes are matched exactly",
+			prLabels:     []string{"/deps/"},
+			selectLabels: []string{"/deps/"},
+			want:         true,
+		},
+		{
+			name:         "label names with slashes are not regular expressions",
+			prLabels:     []string{"deps"},
+			selectLabels: []string{"/deps/"},
+			want:         false,
+		},
+		{
+			name:         "all mode with globs",
+			prLabels:     []string{"area/frontend", "kind/bug"},
+			selectLabels: []string{"glob:area/*", "glob:kind/*"},
+			mode:         labelsModeAll,
+			want:         true,
+		},
+		{
+			name:         "plain select labels are matched exactly rather than as globs",
+			prLabels:     []string{"area/frontend"},
+			selectLabels: []string{"area/*"},
+			want:         false,
+		},
+		{
+			name:         "labels with wildcard characters are matched literally",
+			prLabels:     []string{"needs*review"},
+			selectLabels: []string{"needs*review"},
+			want:         true,
+		},
+		{
+			name:         "labels with regex metacharacters are matched literally",
+			prLabels:     []string{"c++"},
+			selectLabels: []string{"c++"},
+			want:         true,
+		},
 	}
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
 
 			// Run the function, passing the caseSensitive parameter directly
-			got := labelsMatch(test.prLabels, test.ignoreLabels, test.selectLabels, test.caseSensitive)
+			got := labelsMatch(test.prLabels, test.ignoreLabels, test.selectLabels, test.caseSensitive, test.mode)
 			if got != test.want {
 				t.Errorf("Test %q failed: want %v, got %v", test.name, test.want, got)
 			}
@@ -473,7 +566,12 @@ func TestPrMatchesCriteria(t *testing.T) {
 			branchSuffix = test.branchSuffixVal
 			branchRegex = test.branchRegexVal
 
-			got := PrMatchesCriteria(test.branch, test.prLabels)
+			labels := github.Labels{}
+			for _, label := range test.prLabels {
+				labels = append(labels, github.Label{Name: label})
+			}
+
+			got := PrMatchesCriteria(github.Pull{Head: github.Ref{Ref: test.branch}, Labels: labels})
 			if got != test.want {
 				t.Errorf("PrMatchesCriteria(%q, %v) = %v; want %v", test.branch, test.prLabels, got, test.want)
 			}
@@ -481,6 +579,41 @@ func TestPrMatchesCriteria(t *testing.T) {
 	}
 }
 
+func TestPrMatchesCriteriaRecompilesChangedLabels(t *testing.T) {
+	// Since this test changes global state, don't use t.Parallel()
+	origIgnoreLabels, origSelectLabels, origLabelsMode := ignoreLabels, selectLabels, labelsMode
+	origFilter, origBranchPrefix := filterExpression, branchPrefix
+	defer func() {
+		ignoreLabels, selectLabels, labelsMode = origIgnoreLabels, origSelectLabels, origLabelsMode
+		filterExpression, branchPrefix = origFilter, origBranchPrefix
+	}()
+	filterExpression, branchPrefix, ignoreLabels, labelsMode = "", "", nil, labelsModeAny
+
+	pull := github.Pull{Head: github.Ref{Ref: "feature"}, Labels: github.Labels{{Name: "bug"}, {Name: "dependencies"}}}
+
+	selectLabels = []string{"bug"}
+	if !PrMatchesCriteria(pull) {
+		t.Errorf("PrMatchesCriteria with --labels bug = false; want true")
+	}
+
+	selectLabels = []string{"enhancement"}
+	if PrMatchesCriteria(pull) {
+		t.Errorf("PrMatchesCriteria with --labels enhancement = true; want false, the criteria must be compiled again")
+	}
+
+	selectLabels = []string{"bug", "security"}
+	labelsMode = labelsModeAll
+	if PrMatchesCriteria(pull) {
+		t.Errorf("PrMatchesCriteria with --labels bug,security --labels-mode all = true; want false, the criteria must be compiled again")
+	}
+
+	selectLabels, labelsMode = nil, labelsModeAny
+	ignoreLabels = []string{"dependencies"}
+	if PrMatchesCriteria(pull) {
+		t.Errorf("PrMatchesCriteria with --ignore-labels dependencies = true; want false, the criteria must be compiled again")
+	}
+}
+
 func TestIsCIPassing(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -498,7 +631,8 @@ func TestIsCIPassing(t *testing.T) {
 								Nodes []struct {
 									Commit struct {
 										StatusCheckRollup *struct {
-											State string `json:"state"`
+											State    string         `json:"state"`
+											Contexts []checkContext `json:"contexts"`
 										} `json:"statusCheckRollup"`
 									} `json:"commit"`
 								} `json:"nodes"`
@@ -513,7 +647,8 @@ func TestIsCIPassing(t *testing.T) {
 								Nodes []struct {
 									Commit struct {
 										StatusCheckRollup *struct {
-											State string `json:"state"`
+											State    string         `json:"state"`
+											Contexts []checkContext `json:"contexts"`
 										} `json:"statusCheckRollup"`
 									} `json:"commit"`
 								} `json:"nodes"`
@@ -526,7 +661,8 @@ func TestIsCIPassing(t *testing.T) {
 								Nodes []struct {
 									Commit struct {
 										StatusCheckRollup *struct {
-											State string `json:"state"`
+											State    string         `json:"state"`
+											Contexts []checkContext `json:"contexts"`
 										} `json:"statusCheckRollup"`
 									} `json:"commit"`
 								} `json:"nodes"`
@@ -536,7 +672,8 @@ func TestIsCIPassing(t *testing.T) {
 								Nodes []struct {
 									Commit struct {
 										StatusCheckRollup *struct {
-											State string `json:"state"`
+											State    string         `json:"state"`
+											Contexts []checkContext `json:"contexts"`
 										} `json:"statusCheckRollup"`
 									} `json:"commit"`
 								} `json:"nodes"`
@@ -544,18 +681,21 @@ func TestIsCIPassing(t *testing.T) {
 								Nodes: []struct {
 									Commit struct {
 										StatusCheckRollup *struct {
-											State string `json:"state"`
+											State    string         `json:"state"`
+											Contexts []checkContext `json:"contexts"`
 										} `json:"statusCheckRollup"`
 									} `json:"commit"`
 								}{
 									{
 										Commit: struct {
 											StatusCheckRollup *struct {
-												State string `json:"state"`
+												State    string         `json:"state"`
+												Contexts []checkContext `json:"contexts"`
 											} `json:"statusCheckRollup"`
 										}{
 											StatusCheckRollup: &struct {
-												State string `json:"state"`
+												State    string         `json:"state"`
+												Contexts []checkContext `json:"contexts"`
 											}{
 												State: "SUCCESS",
 											},
@@ -580,7 +720,8 @@ func TestIsCIPassing(t *testing.T) {
 								Nodes []struct {
 									Commit struct {
 										StatusCheckRollup *struct {
-											State string `json:"state"`
+											State    string         `json:"state"`
+											Contexts []checkContext `json:"contexts"`
 										} `json:"statusCheckRollup"`
 									} `json:"commit"`
 								} `json:"nodes"`
@@ -595,7 +736,8 @@ func TestIsCIPassing(t *testing.T) {
 								Nodes []struct {
 									Commit struct {
 										StatusCheckRollup *struct {
-											State string `json:"state"`
+											State    string         `json:"state"`
+											Contexts []checkContext `json:"contexts"`
 										} `json:"statusCheckRollup"`
 									} `json:"commit"`
 								} `json:"nodes"`
@@ -608,7 +750,8 @@ func TestIsCIPassing(t *testing.T) {
 								Nodes []struct {
 									Commit struct {
 										StatusCheckRollup *struct {
-											State string `json:"state"`
+											State    string         `json:"state"`
+											Contexts []checkContext `json:"contexts"`
 										} `json:"statusCheckRollup"`
 									} `json:"commit"`
 								} `json:"nodes"`
@@ -618,7 +761,8 @@ func TestIsCIPassing(t *testing.T) {
 								Nodes []struct {
 									Commit struct {
 										StatusCheckRollup *struct {
-											State string `json:"state"`
+											State    string         `json:"state"`
+											Contexts []checkContext `json:"contexts"`
 										} `json:"statusCheckRollup"`
 									} `json:"commit"`
 								} `json:"nodes"`
@@ -626,18 +770,21 @@ func TestIsCIPassing(t *testing.T) {
 								Nodes: []struct {
 									Commit struct {
 										StatusCheckRollup *struct {
-											State string `json:"state"`
+											State    string         `json:"state"`
+											Contexts []checkContext `json:"contexts"`
 										} `json:"statusCheckRollup"`
 									} `json:"commit"`
 								}{
 									{
 										Commit: struct {
 											StatusCheckRollup *struct {
-												State string `json:"state"`
+												State    string         `json:"state"`
+												Contexts []checkContext `json:"contexts"`
 											} `json:"statusCheckRollup"`
 										}{
 											StatusCheckRollup: &struct {
-												State string `json:"state"`
+												State    string         `json:"state"`
+												Contexts []checkContext `json:"contexts"`
 											}{
 												State: "FAILING",
 											},
@@ -662,7 +809,8 @@ func TestIsCIPassing(t *testing.T) {
 								Nodes []struct {
 									Commit struct {
 										StatusCheckRollup *struct {
-											State string `json:"state"`
+											State    string         `json:"state"`
+											Contexts []checkContext `json:"contexts"`
 										} `json:"statusCheckRollup"`
 									} `json:"commit"`
 								} `json:"nodes"`
@@ -677,7 +825,8 @@ func TestIsCIPassing(t *testing.T) {
 								Nodes []struct {
 									Commit struct {
 										StatusCheckRollup *struct {
-											State string `json:"state"`
+											State    string         `json:"state"`
+											Contexts []checkContext `json:"contexts"`
 										} `json:"statusCheckRollup"`
 									} `json:"commit"`
 								} `json:"nodes"`
@@ -690,7 +839,8 @@ func TestIsCIPassing(t *testing.T) {
 								Nodes []struct {
 									Commit struct {
 										StatusCheckRollup *struct {
-											State string `json:"state"`
+											State    string         `json:"state"`
+											Contexts []checkContext `json:"contexts"`
 										} `json:"statusCheckRollup"`
 									} `json:"commit"`
 								} `json:"nodes"`
@@ -700,7 +850,8 @@ func TestIsCIPassing(t *testing.T) {
 								Nodes []struct {
 									Commit struct {
 										StatusCheckRollup *struct {
-											State string `json:"state"`
+											State    string         `json:"state"`
+											Contexts []checkContext `json:"contexts"`
 										} `json:"statusCheckRollup"`
 									} `json:"commit"`
 								} `json:"nodes"`
@@ -708,14 +859,16 @@ func TestIsCIPassing(t *testing.T) {
 								Nodes: []struct {
 									Commit struct {
 										StatusCheckRollup *struct {
-											State string `json:"state"`
+											State    string         `json:"state"`
+											Contexts []checkContext `json:"contexts"`
 										} `json:"statusCheckRollup"`
 									} `json:"commit"`
 								}{
 									{
 										Commit: struct {
 											StatusCheckRollup *struct {
-												State string `json:"state"`
+												State    string         `json:"state"`
+												Contexts []checkContext `json:"contexts"`
 											} `json:"statusCheckRollup"`
 										}{
 											StatusCheckRollup: nil,
@@ -727,7 +880,7 @@ func TestIsCIPassing(t *testing.T) {
 					},
 				},
 			},
-			want: true,
+			want: false,
 		},
 		{
 			name: "No commits",
@@ -740,7 +893,8 @@ func TestIsCIPassing(t *testing.T) {
 								Nodes []struct {
 									Commit struct {
 										StatusCheckRollup *struct {
-											State string `json:"state"`
+											State    string         `json:"state"`
+											Contexts []checkContext `json:"contexts"`
 										} `json:"statusCheckRollup"`
 									} `json:"commit"`
 								} `json:"nodes"`
@@ -755,7 +909,8 @@ func TestIsCIPassing(t *testing.T) {
 								Nodes []struct {
 									Commit struct {
 										StatusCheckRollup *struct {
-											State string `json:"state"`
+											State    string         `json:"state"`
+											Contexts []checkContext `json:"contexts"`
 										} `json:"statusCheckRollup"`
 									} `json:"commit"`
 								} `json:"nodes"`
@@ -768,7 +923,8 @@ func TestIsCIPassing(t *testing.T) {
 								Nodes []struct {
 									Commit struct {
 										StatusCheckRollup *struct {
-											State string `json:"state"`
+											State    string         `json:"state"`
+											Contexts []checkContext `json:"contexts"`
 										} `json:"statusCheckRollup"`
 									} `json:"commit"`
 								} `json:"nodes"`
@@ -778,7 +934,8 @@ func TestIsCIPassing(t *testing.T) {
 								Nodes []struct {
 									Commit struct {
 										StatusCheckRollup *struct {
-											State string `json:"state"`
+											State    string         `json:"state"`
+											Contexts []checkContext `json:"contexts"`
 										} `json:"statusCheckRollup"`
 									} `json:"commit"`
 								} `json:"nodes"`
@@ -786,7 +943,8 @@ func TestIsCIPassing(t *testing.T) {
 								Nodes: []struct {
 									Commit struct {
 										StatusCheckRollup *struct {
-											State string `json:"state"`
+											State    string         `json:"state"`
+											Contexts []checkContext `json:"contexts"`
 										} `json:"statusCheckRollup"`
 									} `json:"commit"`
 								}{},
@@ -826,7 +984,8 @@ func TestIsPRApproved(t *testing.T) {
 								Nodes []struct {
 									Commit struct {
 										StatusCheckRollup *struct {
-											State string `json:"state"`
+											State    string         `json:"state"`
+											Contexts []checkContext `json:"contexts"`
 										} `json:"statusCheckRollup"`
 									} `json:"commit"`
 								} `json:"nodes"`
@@ -841,7 +1000,8 @@ func TestIsPRApproved(t *testing.T) {
 								Nodes []struct {
 									Commit struct {
 										StatusCheckRollup *struct {
-											State string `json:"state"`
+											State    string         `json:"state"`
+											Contexts []checkContext `json:"contexts"`
 										} `json:"statusCheckRollup"`
 									} `json:"commit"`
 								} `json:"nodes"`
@@ -854,7 +1014,8 @@ func TestIsPRApproved(t *testing.T) {
 								Nodes []struct {
 									Commit struct {
 										StatusCheckRollup *struct {
-											State string `json:"state"`
+											State    string         `json:"state"`
+											Contexts []checkContext `json:"contexts"`
 										} `json:"statusCheckRollup"`
 									} `json:"commit"`
 								} `json:"nodes"`
@@ -878,7 +1039,8 @@ func TestIsPRApproved(t *testing.T) {
 								Nodes []struct {
 									Commit struct {
 										StatusCheckRollup *struct {
-											State string `json:"state"`
+											State    string         `json:"state"`
+											Contexts []checkContext `json:"contexts"`
 										} `json:"statusCheckRollup"`
 									} `json:"commit"`
 								} `json:"nodes"`
@@ -893,7 +1055,8 @@ func TestIsPRApproved(t *testing.T) {
 								Nodes []struct {
 									Commit struct {
 										StatusCheckRollup *struct {
-											State string `json:"state"`
+											State    string         `json:"state"`
+											Contexts []checkContext `json:"contexts"`
 										} `json:"statusCheckRollup"`
 									} `json:"commit"`
 								} `json:"nodes"`
@@ -906,7 +1069,8 @@ func TestIsPRApproved(t *testing.T) {
 								Nodes []struct {
 									Commit struct {
 										StatusCheckRollup *struct {
-											State string `json:"state"`
+											State    string         `json:"state"`
+											Contexts []checkContext `json:"contexts"`
 										} `json:"statusCheckRollup"`
 									} `json:"commit"`
 								} `json:"nodes"`
@@ -930,7 +1094,8 @@ func TestIsPRApproved(t *testing.T) {
 								Nodes []struct {
 									Commit struct {
 										StatusCheckRollup *struct {
-											State string `json:"state"`
+											State    string         `json:"state"`
+											Contexts []checkContext `json:"contexts"`
 										} `json:"statusCheckRollup"`
 									} `json:"commit"`
 								} `json:"nodes"`
@@ -945,7 +1110,8 @@ func TestIsPRApproved(t *testing.T) {
 								Nodes []struct {
 									Commit struct {
 										StatusCheckRollup *struct {
-											State string `json:"state"`
+											State    string         `json:"state"`
+											Contexts []checkContext `json:"contexts"`
 										} `json:"statusCheckRollup"`
 									} `json:"commit"`
 								} `json:"nodes"`
@@ -958,7 +1124,8 @@ func TestIsPRApproved(t *testing.T) {
 								Nodes []struct {
 									Commit struct {
 										StatusCheckRollup *struct {
-											State string `json:"state"`
+											State    string         `json:"state"`
+											Contexts []checkContext `json:"contexts"`
 										} `json:"statusCheckRollup"`
 									} `json:"commit"`
 								} `json:"nodes"`
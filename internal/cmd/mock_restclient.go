@@ -11,6 +11,7 @@ type MockRESTClient struct {
 	GetFunc    func(endpoint string, response interface{}) error
 	DeleteFunc func(endpoint string, response interface{}) error
 	PatchFunc  func(endpoint string, body io.Reader, response interface{}) error
+	PutFunc    func(endpoint string, body io.Reader, response interface{}) error
 }
 
 // Updated the Post method to match the RESTClientInterface signature
@@ -60,5 +61,8 @@ func (m *MockRESTClient) Do(method string, path string, body io.Reader, response
 }
 
 func (m *MockRESTClient) Put(path string, body io.Reader, resp interface{}) error {
+	if m.PutFunc != nil {
+		return m.PutFunc(path, body, resp)
+	}
 	return nil
 }
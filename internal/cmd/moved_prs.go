@@ -0,0 +1,91 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/cli/go-gh/v2/pkg/api"
+
+	"github.com/github/gh-combine/internal/github"
+)
+
+// mergeHead returns what a PR is merged by: the head SHA that was evaluated when it was selected, so commits
+// pushed afterwards are not combined without being checked. If the SHA is unknown, the branch is used, or the
+// pull request ref for PRs from forks since their branch does not exist in the repository
+func mergeHead(repo github.Repo, pull github.Pull) string {
+	if pull.Head.SHA != "" {
+		return pull.Head.SHA
+	}
+	if pull.FromFork(repo) {
+		return fmt.Sprintf("refs/pull/%d/head", pull.Number)
+	}
+	return pull.Head.Ref
+}
+
+// mergeCommitMessage is the message of the merge commit of a PR, which names the branch since it is merged by SHA
+func mergeCommitMessage(pull github.Pull) string {
+	return fmt.Sprintf("Merge pull request #%d from %s", pull.Number, pull.Head.Ref)
+}
+
+// checkMovedPRs compares the current head of every PR with the SHA that was evaluated. A PR that moved is still
+// combined at the evaluated SHA, unless --reevaluate-moved is set: then it is combined at its new head if that
+// meets the requirements again, and skipped otherwise. The PRs to combine are returned with a description of
+// every PR that moved, and the PRs combined at an outdated SHA, which the combined PR must not close since
+// their new commits are not part of it
+func checkMovedPRs(ctx context.Context, graphQlClient *api.GraphQLClient, restClient RESTClientInterface, repo github.Repo, pulls github.Pulls) (github.Pulls, []string, github.Pulls, error) {
+	var kept, outdated github.Pulls
+	var moved []string
+
+	// Moved PRs are re-evaluated after the combined branch was built, which can be long after the selection
+	// started waiting, so they get a fresh deadline for pending checks instead of an expired one
+	resetPRCIDeadline()
+
+	for _, pull := range pulls {
+		// Check for cancellation
+		select {
+		case <-ctx.Done():
+			return nil, nil, nil, ctx.Err()
+		default:
+			// Continue processing
+		}
+
+		if pull.Head.SHA == "" {
+			kept = append(kept, pull)
+			continue
+		}
+
+		var current github.Pull
+		if err := restClient.Get(fmt.Sprintf("repos/%s/%s/pulls/%d", repo.Owner, repo.Repo, pull.Number), &current); err != nil {
+			Logger.Warn("Failed to check the head of PR, combining the evaluated commit", "repo", repo, "pr", pull.Number, "error", err)
+			kept = append(kept, pull)
+			continue
+		}
+		if current.Head.SHA == "" || current.Head.SHA == pull.Head.SHA {
+			kept = append(kept, pull)
+			continue
+		}
+
+		change := fmt.Sprintf("#%d moved from %s to %s", pull.Number, shortSHA(pull.Head.SHA), shortSHA(current.Head.SHA))
+		Logger.Debug("PR moved since it was evaluated", "repo", repo, "pr", pull.Number, "evaluated", pull.Head.SHA, "current", current.Head.SHA)
+
+		if !reevaluateMoved {
+			moved = append(moved, fmt.Sprintf("%s, combined at %s and not closed", change, shortSHA(pull.Head.SHA)))
+			kept = append(kept, pull)
+			outdated = append(outdated, pull)
+			continue
+		}
+
+		unmet, err := unmetRequirement(ctx, graphQlClient, nil, repo.Owner, repo.Repo, pull.Number)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("failed to re-evaluate #%d: %w", pull.Number, err)
+		}
+		if unmet != "" {
+			moved = append(moved, fmt.Sprintf("%s, skipped: %s", change, unmet))
+			continue
+		}
+		moved = append(moved, fmt.Sprintf("%s, re-evaluated and combined at %s", change, shortSHA(current.Head.SHA)))
+		kept = append(kept, current)
+	}
+
+	return kept, moved, outdated, nil
+}
@@ -0,0 +1,99 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/github/gh-combine/internal/github"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMergeHead(t *testing.T) {
+	t.Parallel()
+
+	repo := github.Repo{Owner: "owner", Repo: "repo"}
+	sameRepo := &github.RefRepo{FullName: "owner/repo"}
+	fork := &github.RefRepo{FullName: "contributor/repo"}
+
+	assert.Equal(t, "abc123", mergeHead(repo, github.Pull{Head: github.Ref{Ref: "feature", SHA: "abc123", Repo: sameRepo}}))
+	assert.Equal(t, "abc123", mergeHead(repo, github.Pull{Head: github.Ref{Ref: "feature", SHA: "abc123", Repo: fork}}))
+	assert.Equal(t, "feature", mergeHead(repo, github.Pull{Head: github.Ref{Ref: "feature", Repo: sameRepo}}))
+	assert.Equal(t, "refs/pull/7/head", mergeHead(repo, github.Pull{Number: 7, Head: github.Ref{Ref: "feature", Repo: fork}}))
+	assert.Equal(t, "Merge pull request #12 from feature", mergeCommitMessage(github.Pull{Number: 12, Head: github.Ref{Ref: "feature"}}))
+}
+
+func TestCheckMovedPRs(t *testing.T) {
+	// Since this test changes global state, don't use t.Parallel()
+	origReevaluateMoved, origRequireCI, origMustBeApproved := reevaluateMoved, requireCI, mustBeApproved
+	origDeadline := prCIWaitDeadline
+	defer func() {
+		reevaluateMoved, requireCI, mustBeApproved = origReevaluateMoved, origRequireCI, origMustBeApproved
+		prCIWaitDeadline = origDeadline
+	}()
+	// Without requirements, re-evaluating does not need the GraphQL client
+	requireCI, mustBeApproved = false, false
+
+	heads := map[int]string{1: "aaaaaaaaaa", 2: "cccccccccc"}
+	client := &MockRESTClient{
+		GetFunc: func(endpoint string, response interface{}) error {
+			var number int
+			if _, err := fmt.Sscanf(endpoint, "repos/owner/repo/pulls/%d", &number); err != nil {
+				return err
+			}
+			return json.Unmarshal([]byte(fmt.Sprintf(`{"number": %d, "head": {"ref": "feature-%d", "sha": %q}}`, number, number, heads[number])), response)
+		},
+	}
+
+	repo := github.Repo{Owner: "owner", Repo: "repo"}
+	pulls := github.Pulls{
+		{Number: 1, Head: github.Ref{Ref: "feature-1", SHA: "aaaaaaaaaa"}},
+		{Number: 2, Head: github.Ref{Ref: "feature-2", SHA: "bbbbbbbbbb"}},
+	}
+
+	tests := []struct {
+		reevaluate   bool
+		wantHeads    []string
+		wantMoved    []string
+		wantOutdated []int
+	}{
+		{
+			reevaluate:   false,
+			wantHeads:    []string{"aaaaaaaaaa", "bbbbbbbbbb"},
+			wantMoved:    []string{"#2 moved from bbbbbbb to ccccccc, combined at bbbbbbb and not closed"},
+			wantOutdated: []int{2},
+		},
+		{
+			reevaluate: true,
+			wantHeads:  []string{"aaaaaaaaaa", "cccccccccc"},
+			wantMoved:  []string{"#2 moved from bbbbbbb to ccccccc, re-evaluated and combined at ccccccc"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("reevaluate=%t", test.reevaluate), func(t *testing.T) {
+			reevaluateMoved = test.reevaluate
+			// The deadline of an earlier wait in the run has expired
+			prCIWaitDeadline = time.Now().Add(-time.Hour)
+
+			kept, moved, outdated, err := checkMovedPRs(context.Background(), nil, client, repo, pulls)
+			assert.NoError(t, err)
+			assert.True(t, prCIWaitDeadline.IsZero(), "re-evaluation should not inherit an expired CI deadline")
+
+			gotHeads := []string{}
+			for _, pull := range kept {
+				gotHeads = append(gotHeads, pull.Head.SHA)
+			}
+			assert.Equal(t, test.wantHeads, gotHeads)
+			assert.Equal(t, test.wantMoved, moved)
+
+			var gotOutdated []int
+			for _, pull := range outdated {
+				gotOutdated = append(gotOutdated, pull.Number)
+			}
+			assert.Equal(t, test.wantOutdated, gotOutdated)
+		})
+	}
+}
@@ -64,6 +64,42 @@ func displayTableStats(stats *StatsCollector) {
 	// Print PR links
 	displayPRLinks(stats.CombinedPRLinks)
 
+	// Print explicitly listed PRs that could not be combined
+	displayInvalidPRs(stats)
+
+	// Print PRs that were ejected from combined PRs
+	displayEjectedPRs(stats)
+
+	// Print PRs from forks that were skipped
+	displayForkPRs(stats)
+
+	// Print PRs that are part of another open combined PR
+	displayAlreadyCombined(stats)
+
+	// Print PRs that got new commits during the run
+	displayMovedPRs(stats)
+
+	// Print the CI outcome of combined PRs verified with --verify-ci
+	displayCIStatuses(stats)
+
+	// Print whether combined PRs were merged or set to auto-merge
+	displayMergeStatuses(stats)
+
+	// Print why combined PRs could not be merged or set to auto-merge
+	displayMergeErrors(stats)
+
+	// Print why combined PRs have unsigned commits with --commit-mode signed
+	displayUnsignedReasons(stats)
+
+	// Print metadata that could not be set on combined PRs
+	displayMetadataErrors(stats)
+
+	// Print whether open combined PRs were updated with --update-branch
+	displayUpdateStatuses(stats)
+
+	// Print how to undo the run
+	displayRunID(stats)
+
 	fmt.Println()
 }
 
@@ -277,6 +313,120 @@ func displayPRLinks(links []string) {
 	}
 }
 
+// displayInvalidPRs prints the PRs listed with --prs that were closed, missing or targeting another base
+func displayInvalidPRs(stats *StatsCollector) {
+	displayRepoPRList(stats, "Listed PRs that could not be combined:", func(repoStat *RepoStats) []string { return repoStat.InvalidPRs })
+}
+
+// displayEjectedPRs prints the PRs that were removed from combined PRs because they failed CI
+func displayEjectedPRs(stats *StatsCollector) {
+	displayRepoPRList(stats, "PRs ejected because CI failed on the combined PR:", func(repoStat *RepoStats) []string { return repoStat.EjectedPRs })
+}
+
+// displayForkPRs prints the PRs from forks that would have been combined without --include-forks
+func displayForkPRs(stats *StatsCollector) {
+	displayRepoPRList(stats, "PRs from forks that were skipped (use --include-forks to combine them):", func(repoStat *RepoStats) []string { return repoStat.ForkPRs })
+}
+
+// displayAlreadyCombined prints the PRs that were skipped because another open combined PR includes them
+func displayAlreadyCombined(stats *StatsCollector) {
+	displayRepoPRList(stats, "PRs skipped because they are already combined:", func(repoStat *RepoStats) []string { return repoStat.AlreadyCombined })
+}
+
+// displayMovedPRs prints the PRs whose head moved between their evaluation and the merge into the combined branch
+func displayMovedPRs(stats *StatsCollector) {
+	displayRepoPRList(stats, "PRs that got new commits during the run:", func(repoStat *RepoStats) []string { return repoStat.MovedPRs })
+}
+
+// displayCIStatuses prints the CI outcome of the combined PRs verified with --verify-ci
+func displayCIStatuses(stats *StatsCollector) {
+	displayRepoPRList(stats, "Combined PR CI status:", func(repoStat *RepoStats) []string {
+		if repoStat.CIStatus == "" {
+			return nil
+		}
+		return []string{repoStat.CIStatus}
+	})
+}
+
+// displayMergeStatuses prints whether the combined PRs were merged or set to auto-merge
+func displayMergeStatuses(stats *StatsCollector) {
+	displayRepoPRList(stats, "Combined PR merge status:", func(repoStat *RepoStats) []string {
+		if repoStat.MergeStatus == "" {
+			return nil
+		}
+		return []string{repoStat.MergeStatus}
+	})
+}
+
+// displayMergeErrors prints why the combined PRs could not be merged or set to auto-merge
+func displayMergeErrors(stats *StatsCollector) {
+	displayRepoPRList(stats, "Combined PRs that could not be merged:", func(repoStat *RepoStats) []string {
+		if repoStat.MergeError == "" {
+			return nil
+		}
+		return []string{repoStat.MergeError}
+	})
+}
+
+// displayUnsignedReasons prints why the combined PRs have unsigned merge commits instead of a signed commit
+func displayUnsignedReasons(stats *StatsCollector) {
+	displayRepoPRList(stats, "Combined PRs with unsigned commits (signed commit failed):", func(repoStat *RepoStats) []string {
+		if repoStat.UnsignedReason == "" {
+			return nil
+		}
+		return []string{repoStat.UnsignedReason}
+	})
+}
+
+// displayUpdateStatuses prints whether the open combined PRs were brought up to date with --update-branch
+func displayUpdateStatuses(stats *StatsCollector) {
+	displayRepoPRList(stats, "Combined PR update status:", func(repoStat *RepoStats) []string {
+		if repoStat.UpdateStatus == "" {
+			return nil
+		}
+		return []string{repoStat.UpdateStatus}
+	})
+}
+
+// displayMetadataErrors prints the metadata that could not be set on the combined PRs
+func displayMetadataErrors(stats *StatsCollector) {
+	displayRepoPRList(stats, "Combined PR metadata that could not be set:", func(repoStat *RepoStats) []string { return repoStat.MetadataErrors })
+}
+
+// displayRunID prints the ID of the run and how to undo it, if the run changed any repository
+func displayRunID(stats *StatsCollector) {
+	id := recordedRunID(stats)
+	if id == "" {
+		return
+	}
+	fmt.Printf("\nRun ID: %s (undo with: gh combine undo %s)\n", colorize(id, colorBlue), id)
+}
+
+// recordedRunID returns the ID of the run if it changed any repository, or an empty string
+func recordedRunID(stats *StatsCollector) string {
+	if stats.Journal == nil || len(stats.Journal.Repos) == 0 {
+		return ""
+	}
+	return stats.Journal.ID
+}
+
+// displayRepoPRList prints a per-repository list of PRs under a header, if there are any
+func displayRepoPRList(stats *StatsCollector, header string, prs func(*RepoStats) []string) {
+	printed := false
+	for _, repoStat := range stats.PerRepoStats {
+		if len(prs(repoStat)) == 0 {
+			continue
+		}
+		if !printed {
+			fmt.Println("\n" + header)
+			printed = true
+		}
+		for _, pr := range prs(repoStat) {
+			fmt.Printf("- %s %s\n", repoStat.RepoName, colorize(pr, colorYellow))
+		}
+	}
+}
+
 // displayJSONStats displays stats in JSON format
 func displayJSONStats(stats *StatsCollector) {
 	output := map[string]interface{}{
@@ -288,6 +438,9 @@ func displayJSONStats(stats *StatsCollector) {
 		"combinedPRLinks":         stats.CombinedPRLinks,
 		"perRepoStats":            stats.PerRepoStats,
 	}
+	if id := recordedRunID(stats); id != "" {
+		output["runId"] = id
+	}
 	jsonData, _ := json.MarshalIndent(output, "", "  ")
 	fmt.Println(string(jsonData))
 }
@@ -313,6 +466,15 @@ func displayPlainStats(stats *StatsCollector) {
 	fmt.Println("\nPer-Repository Details:")
 	for _, repoStat := range stats.PerRepoStats {
 		fmt.Printf("  %s\n", repoStat.RepoName)
+		if len(repoStat.InvalidPRs) > 0 {
+			fmt.Printf("    Listed PRs that could not be combined: %s\n", strings.Join(repoStat.InvalidPRs, ", "))
+		}
+		if len(repoStat.ForkPRs) > 0 {
+			fmt.Printf("    Skipped (From Forks): %s\n", strings.Join(repoStat.ForkPRs, ", "))
+		}
+		if len(repoStat.AlreadyCombined) > 0 {
+			fmt.Printf("    Skipped (Already Combined): %s\n", strings.Join(repoStat.AlreadyCombined, ", "))
+		}
 		if repoStat.NotEnoughPRs {
 			fmt.Println("    Not enough PRs to combine.")
 			continue
@@ -320,9 +482,37 @@ func displayPlainStats(stats *StatsCollector) {
 		fmt.Printf("    Combined: %d\n", repoStat.CombinedCount)
 		fmt.Printf("    Skipped (Merge Conflicts): %d\n", repoStat.SkippedMergeConf)
 		fmt.Printf("    Skipped (Did Not Match): %d\n", repoStat.SkippedCriteria)
+		if len(repoStat.EjectedPRs) > 0 {
+			fmt.Printf("    Ejected (CI Failed): %s\n", strings.Join(repoStat.EjectedPRs, ", "))
+		}
+		if len(repoStat.MovedPRs) > 0 {
+			fmt.Printf("    Moved During the Run: %s\n", strings.Join(repoStat.MovedPRs, "; "))
+		}
 		if repoStat.CombinedPRLink != "" {
 			fmt.Printf("    Combined PR: %s\n", repoStat.CombinedPRLink)
 		}
+		if len(repoStat.MetadataErrors) > 0 {
+			fmt.Printf("    Metadata Errors: %s\n", strings.Join(repoStat.MetadataErrors, "; "))
+		}
+		if repoStat.CIStatus != "" {
+			fmt.Printf("    CI Status: %s\n", repoStat.CIStatus)
+		}
+		if repoStat.MergeStatus != "" {
+			fmt.Printf("    Merge Status: %s\n", repoStat.MergeStatus)
+		}
+		if repoStat.MergeError != "" {
+			fmt.Printf("    Merge Error: %s\n", repoStat.MergeError)
+		}
+		if repoStat.UnsignedReason != "" {
+			fmt.Printf("    Unsigned Commits: %s\n", repoStat.UnsignedReason)
+		}
+		if repoStat.UpdateStatus != "" {
+			fmt.Printf("    Update Status: %s\n", repoStat.UpdateStatus)
+		}
+	}
+
+	if id := recordedRunID(stats); id != "" {
+		fmt.Printf("\nRun ID: %s (undo with: gh combine undo %s)\n", id, id)
 	}
 }
 
@@ -21,6 +21,7 @@ func TestDisplayTableStats(t *testing.T) {
 				CombinedPRLink:   "http://example.com/pr1",
 				NotEnoughPRs:     false,
 				TotalPRs:         5,
+				MergeError:       "failed to enable auto-merge: HTTP 403",
 			},
 			"repo2": {
 				RepoName:         "repo2",
@@ -56,6 +57,7 @@ func TestDisplayJSONStats(t *testing.T) {
 				CombinedPRLink:   "http://example.com/pr1",
 				NotEnoughPRs:     false,
 				TotalPRs:         5,
+				MergeError:       "failed to enable auto-merge: HTTP 403",
 			},
 			"repo2": {
 				RepoName:         "repo2",
@@ -91,6 +93,7 @@ func TestDisplayPlainStats(t *testing.T) {
 				CombinedPRLink:   "http://example.com/pr1",
 				NotEnoughPRs:     false,
 				TotalPRs:         5,
+				MergeError:       "failed to enable auto-merge: HTTP 403",
 			},
 			"repo2": {
 				RepoName:         "repo2",
@@ -0,0 +1,514 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/cli/go-gh/v2/pkg/api"
+	"github.com/spf13/cobra"
+
+	"github.com/github/gh-combine/internal/github"
+)
+
+// planFileVersion is the version of the plan file format written by the plan command
+const planFileVersion = 1
+
+var (
+	planOut    string
+	forceApply bool
+
+	errPlanVersion  = errors.New("unsupported plan file version")
+	errPlanOutdated = errors.New("source PRs changed since planning")
+)
+
+// CombinePlanFile is a reviewable record of the PRs that apply will combine and how
+type CombinePlanFile struct {
+	Version   int           `json:"version"`
+	CreatedAt time.Time     `json:"createdAt"`
+	Options   PlanOptions   `json:"options"`
+	Repos     []PlannedRepo `json:"repos"`
+}
+
+// PlanOptions are the settings that control how the PRs of a plan are combined and what the combined PR looks like
+type PlanOptions struct {
+	CombineBranchName    string   `json:"combineBranchName"`
+	WorkingBranchSuffix  string   `json:"workingBranchSuffix"`
+	CommitMode           string   `json:"commitMode"`
+	NoAutoclose          bool     `json:"noAutoclose"`
+	NoReleaseNotes       bool     `json:"noReleaseNotes"`
+	TitleTemplate        string   `json:"titleTemplate,omitempty"`
+	BodyTemplate         string   `json:"bodyTemplate,omitempty"`
+	AddLabels            []string `json:"addLabels,omitempty"`
+	InheritLabels        bool     `json:"inheritLabels"`
+	InheritLabelsInclude []string `json:"inheritLabelsInclude,omitempty"`
+	InheritLabelsExclude []string `json:"inheritLabelsExclude,omitempty"`
+	CreateLabels         bool     `json:"createLabels"`
+	AddAssignees         []string `json:"addAssignees,omitempty"`
+	Reviewers            []string `json:"reviewers,omitempty"`
+	InheritReviewers     bool     `json:"inheritReviewers"`
+	Milestone            string   `json:"milestone,omitempty"`
+	Project              string   `json:"project,omitempty"`
+	Draft                bool     `json:"draft"`
+	VerifyCI             bool     `json:"verifyCI"`
+	RequireChecks        []string `json:"requireChecks,omitempty"`
+	IgnoreChecks         []string `json:"ignoreChecks,omitempty"`
+	WaitForCI            string   `json:"waitForCI,omitempty"`
+	AutoMerge            string   `json:"autoMerge,omitempty"`
+	MergeWhenGreen       bool     `json:"mergeWhenGreen"`
+	CommentOnSources     bool     `json:"commentOnSources"`
+	ReevaluateMoved      bool     `json:"reevaluateMoved"`
+}
+
+// PlannedRepo is a repository in a plan with the PRs that will be combined
+type PlannedRepo struct {
+	Repo    string        `json:"repo"`
+	Command string        `json:"command"`
+	Pulls   []PlannedPull `json:"pulls"`
+}
+
+// PlannedPull is a source PR in a plan, pinned to the head SHA it had when it was selected
+type PlannedPull struct {
+	Number  int    `json:"number"`
+	Title   string `json:"title"`
+	HeadRef string `json:"headRef"`
+	HeadSHA string `json:"headSha"`
+}
+
+// newPlanCmd creates the plan subcommand, which takes the same selection flags as the root command
+func newPlanCmd(rootCmd *cobra.Command) *cobra.Command {
+	planCmd := &cobra.Command{
+		Use:   "plan owner/repo",
+		Short: "Save the PRs that would be combined to a plan file",
+		Long: `Select the PRs to combine like gh combine does, but only record the repositories, PR numbers, head SHAs
+    and combined PR settings in a plan file without changing anything. Use gh combine apply to execute the plan.
+    Examples:
+      gh combine plan owner/repo --dependabot --out plan.json                 # Plan to combine Dependabot PRs
+      gh combine plan --file repos.txt --labels dependencies --out plan.json  # Plan across multiple repositories`,
+		Args: cobra.ArbitraryArgs,
+		RunE: runPlan,
+	}
+
+	planCmd.Flags().StringVar(&planOut, "out", "", "File to write the plan to (default: standard output)")
+
+	// The plan selects PRs with the same flags as the root command
+	planCmd.Flags().AddFlagSet(rootCmd.Flags())
+	_ = planCmd.Flags().MarkHidden("version")
+
+	return planCmd
+}
+
+// newApplyCmd creates the apply subcommand, which executes a plan file
+func newApplyCmd() *cobra.Command {
+	applyCmd := &cobra.Command{
+		Use:   "apply plan.json",
+		Short: "Combine the PRs recorded in a plan file",
+		Long: `Combine exactly the PRs recorded by gh combine plan, with the settings recorded in the plan.
+    Repositories in which a source PR was closed or got new commits since planning are refused, unless --force is used.
+    Examples:
+      gh combine apply plan.json          # Execute the plan
+      gh combine apply plan.json --force  # Execute the plan even if source PRs changed since planning`,
+		Args: cobra.ExactArgs(1),
+		RunE: runApply,
+	}
+
+	applyCmd.Flags().BoolVar(&forceApply, "force", false, "Apply the plan with a warning when source PRs changed since planning")
+	applyCmd.Flags().StringVar(&outputFormat, "output", "table", "Output format: table, plain, or json")
+
+	return applyCmd
+}
+
+// runPlan is the main execution function for the plan command
+func runPlan(cmd *cobra.Command, args []string) error {
+	ctx, cancel := SetupSignalContext()
+	defer cancel()
+
+	normalizeInputs()
+
+	if err := ValidateInputs(args); err != nil {
+		return err
+	}
+
+	repos, err := ParseRepositories(args, reposFile)
+	if err != nil {
+		return fmt.Errorf("failed to parse repositories: %w", err)
+	}
+	if len(repos) == 0 {
+		return errors.New("no repositories specified")
+	}
+	if len(includePRs) > 0 && len(repos) > 1 {
+		return errPRsMultipleRepos
+	}
+
+	restClient, err := api.DefaultRESTClient()
+	if err != nil {
+		return fmt.Errorf("failed to create REST client: %w", err)
+	}
+
+	graphQlClient, err := api.DefaultGraphQLClient()
+	if err != nil {
+		return fmt.Errorf("failed to create GraphQLClient client: %w", err)
+	}
+
+	plan, err := newCombinePlanFile()
+	if err != nil {
+		return err
+	}
+
+	resetPRCIDeadline()
+	spinner := NewSpinner("")
+	defer spinner.Stop()
+
+	for _, repo := range repos {
+		spinner.UpdateMessage("Planning " + repo.String())
+
+		planned, err := planRepository(ctx, restClient, graphQlClient, spinner, repo)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			Logger.Warn("Failed to plan repository", "repo", repo, "error", err)
+			continue
+		}
+		if planned != nil {
+			plan.Repos = append(plan.Repos, *planned)
+		}
+	}
+	spinner.Stop()
+
+	if err := writePlanFile(plan, planOut); err != nil {
+		return err
+	}
+
+	if planOut != "" {
+		prs := 0
+		for _, planned := range plan.Repos {
+			prs += len(planned.Pulls)
+		}
+		fmt.Printf("Planned to combine %d PRs in %d repositories, written to %s\n", prs, len(plan.Repos), planOut)
+	}
+	return nil
+}
+
+// planRepository selects the PRs to combine in a repository, or returns nil if there are not enough of them
+func planRepository(ctx context.Context, client *api.RESTClient, graphQlClient *api.GraphQLClient, spinner *Spinner, repo github.Repo) (*PlannedRepo, error) {
+	pulls, err := fetchOpenPullRequests(ctx, client, repo)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch open pull requests: %w", err)
+	}
+
+	restClientWrapper := struct {
+		RESTClientInterface
+	}{client}
+
+	// The selection stats are not displayed, the plan file is the output
+	repoStats := &RepoStats{RepoName: repo.String()}
+	stats := &StatsCollector{}
+
+	matchedPRs, _, err := selectPullRequests(ctx, restClientWrapper, graphQlClient, spinner, repo, pulls, repoStats, stats)
+	if err != nil {
+		return nil, err
+	}
+	if len(matchedPRs) < minimum {
+		Logger.Debug("Not enough PRs match criteria", "repo", repo, "matched", len(matchedPRs), "required", minimum)
+		return nil, nil
+	}
+
+	planned := &PlannedRepo{
+		Repo:    repo.String(),
+		Command: buildCommandString([]string{repo.String()}),
+	}
+	for _, pull := range matchedPRs {
+		planned.Pulls = append(planned.Pulls, PlannedPull{
+			Number:  pull.Number,
+			Title:   pull.Title,
+			HeadRef: pull.Head.Ref,
+			HeadSHA: pull.Head.SHA,
+		})
+	}
+	return planned, nil
+}
+
+// newCombinePlanFile records the current settings in a new plan. Templates read from files are inlined,
+// so the plan is complete on its own
+func newCombinePlanFile() (*CombinePlanFile, error) {
+	title, err := inlineTemplate("title", titleTemplate)
+	if err != nil {
+		return nil, err
+	}
+	body, err := inlineTemplate("body", bodyTemplate)
+	if err != nil {
+		return nil, err
+	}
+
+	options := PlanOptions{
+		CombineBranchName:    combineBranchName,
+		WorkingBranchSuffix:  workingBranchSuffix,
+		CommitMode:           commitMode,
+		NoAutoclose:          noAutoclose,
+		NoReleaseNotes:       noReleaseNotes,
+		TitleTemplate:        title,
+		BodyTemplate:         body,
+		AddLabels:            addLabels,
+		InheritLabels:        inheritLabels,
+		InheritLabelsInclude: inheritLabelsInclude,
+		InheritLabelsExclude: inheritLabelsExclude,
+		CreateLabels:         createLabels,
+		AddAssignees:         addAssignees,
+		Reviewers:            reviewers,
+		InheritReviewers:     inheritReviewers,
+		Milestone:            milestone,
+		Project:              project,
+		Draft:                draft,
+		VerifyCI:             verifyCI,
+		RequireChecks:        requireChecks,
+		IgnoreChecks:         ignoreChecks,
+		AutoMerge:            autoMergeMethod,
+		MergeWhenGreen:       mergeWhenGreen,
+		CommentOnSources:     commentOnSources,
+		ReevaluateMoved:      reevaluateMoved,
+	}
+	if waitForCITimeout > 0 {
+		options.WaitForCI = waitForCITimeout.String()
+	}
+
+	return &CombinePlanFile{
+		Version:   planFileVersion,
+		CreatedAt: time.Now().UTC(),
+		Options:   options,
+	}, nil
+}
+
+// inlineTemplate returns the text of a template, reading it from the file of an @path value
+func inlineTemplate(name, value string) (string, error) {
+	path, ok := strings.CutPrefix(value, "@")
+	if !ok {
+		return value, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("%w: failed to read %s template: %w", errInvalidTemplate, name, err)
+	}
+	return string(data), nil
+}
+
+// restore sets the flags of the combine command to the settings recorded in the plan
+func (o PlanOptions) restore() error {
+	combineBranchName = o.CombineBranchName
+	workingBranchSuffix = o.WorkingBranchSuffix
+	commitMode = o.CommitMode
+	noAutoclose = o.NoAutoclose
+	noReleaseNotes = o.NoReleaseNotes
+	titleTemplate = o.TitleTemplate
+	bodyTemplate = o.BodyTemplate
+	addLabels = o.AddLabels
+	inheritLabels = o.InheritLabels
+	inheritLabelsInclude = o.InheritLabelsInclude
+	inheritLabelsExclude = o.InheritLabelsExclude
+	createLabels = o.CreateLabels
+	addAssignees = o.AddAssignees
+	reviewers = o.Reviewers
+	inheritReviewers = o.InheritReviewers
+	milestone = o.Milestone
+	project = o.Project
+	draft = o.Draft
+	verifyCI = o.VerifyCI
+	requireChecks = o.RequireChecks
+	ignoreChecks = o.IgnoreChecks
+	autoMergeMethod = o.AutoMerge
+	mergeWhenGreen = o.MergeWhenGreen
+	commentOnSources = o.CommentOnSources
+	reevaluateMoved = o.ReevaluateMoved
+
+	waitForCITimeout = 0
+	if o.WaitForCI != "" {
+		timeout, err := time.ParseDuration(o.WaitForCI)
+		if err != nil {
+			return fmt.Errorf("invalid waitForCI in plan: %w", err)
+		}
+		waitForCITimeout = timeout
+	}
+
+	if combineBranchName == "" || workingBranchSuffix == "" {
+		return errors.New("plan is missing the combined branch name")
+	}
+	if err := ValidateCommitMode(commitMode); err != nil {
+		return err
+	}
+	if err := ValidateMergeMethod(autoMergeMethod); err != nil {
+		return err
+	}
+	if err := ValidateTemplates(titleTemplate, bodyTemplate); err != nil {
+		return err
+	}
+	return ValidatePRMetadata(reviewers, project)
+}
+
+// writePlanFile writes a plan as indented JSON to path, or to standard output if path is empty
+func writePlanFile(plan *CombinePlanFile, path string) error {
+	data, err := json.MarshalIndent(plan, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode plan: %w", err)
+	}
+	data = append(data, '\n')
+
+	if path == "" {
+		_, err = os.Stdout.Write(data)
+		return err
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write plan: %w", err)
+	}
+	return nil
+}
+
+// readPlanFile reads a plan written by the plan command
+func readPlanFile(path string) (*CombinePlanFile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read plan: %w", err)
+	}
+
+	var plan CombinePlanFile
+	if err := json.Unmarshal(data, &plan); err != nil {
+		return nil, fmt.Errorf("failed to parse plan: %w", err)
+	}
+	if plan.Version != planFileVersion {
+		return nil, fmt.Errorf("%w: %d", errPlanVersion, plan.Version)
+	}
+	return &plan, nil
+}
+
+// runApply is the main execution function for the apply command
+func runApply(cmd *cobra.Command, args []string) error {
+	ctx, cancel := SetupSignalContext()
+	defer cancel()
+
+	plan, err := readPlanFile(args[0])
+	if err != nil {
+		return err
+	}
+	if err := plan.Options.restore(); err != nil {
+		return err
+	}
+
+	restClient, err := api.DefaultRESTClient()
+	if err != nil {
+		return fmt.Errorf("failed to create REST client: %w", err)
+	}
+
+	graphQlClient, err := api.DefaultGraphQLClient()
+	if err != nil {
+		return fmt.Errorf("failed to create GraphQLClient client: %w", err)
+	}
+
+	restClientWrapper := struct {
+		RESTClientInterface
+	}{restClient}
+
+	stats := &StatsCollector{
+		PerRepoStats: make(map[string]*RepoStats),
+		Journal:      newRunJournal(),
+		StartTime:    time.Now(),
+	}
+
+	spinner := NewSpinner("")
+	defer spinner.Stop()
+
+	refused := 0
+	for _, planned := range plan.Repos {
+		// Check for cancellation
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+			// Continue processing
+		}
+
+		spinner.UpdateMessage("Applying plan to " + planned.Repo)
+
+		repoStats := &RepoStats{RepoName: planned.Repo}
+		stats.PerRepoStats[planned.Repo] = repoStats
+
+		if err := applyPlannedRepo(ctx, graphQlClient, restClientWrapper, planned, repoStats, stats); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if errors.Is(err, errPlanOutdated) {
+				refused++
+			}
+			Logger.Warn("Failed to apply plan", "repo", planned.Repo, "error", err)
+			continue
+		}
+		stats.ReposProcessed++
+	}
+	stats.EndTime = time.Now()
+
+	spinner.Stop()
+	displayStatsSummary(stats, outputFormat)
+
+	if refused > 0 {
+		return fmt.Errorf("%w in %d repositories (use --force to apply anyway)", errPlanOutdated, refused)
+	}
+	return nil
+}
+
+// applyPlannedRepo combines the planned PRs of a repository after checking that they did not change since planning
+func applyPlannedRepo(ctx context.Context, graphQlClient *api.GraphQLClient, restClient RESTClientInterface, planned PlannedRepo, repoStats *RepoStats, stats *StatsCollector) error {
+	repo, err := github.ParseRepo(planned.Repo)
+	if err != nil {
+		return err
+	}
+
+	pulls, changes := verifyPlannedPulls(ctx, restClient, repo, planned.Pulls)
+	if len(changes) > 0 {
+		if !forceApply {
+			return fmt.Errorf("%w: %s", errPlanOutdated, strings.Join(changes, "; "))
+		}
+		Logger.Warn("Source PRs changed since planning, applying anyway", "repo", repo, "changes", strings.Join(changes, "; "))
+	}
+
+	if len(pulls) == 0 {
+		repoStats.NotEnoughPRs = true
+		return nil
+	}
+
+	return combineSelectedPRs(ctx, graphQlClient, restClient, repo, pulls, nil, planned.Command, repoStats, stats)
+}
+
+// verifyPlannedPulls fetches the planned PRs and describes every PR that was closed or whose head moved since
+// planning. The PRs that are still open are returned
+func verifyPlannedPulls(ctx context.Context, client RESTClientInterface, repo github.Repo, planned []PlannedPull) (github.Pulls, []string) {
+	var pulls github.Pulls
+	var changes []string
+
+	for _, p := range planned {
+		var pull github.Pull
+		if err := client.Get(fmt.Sprintf("repos/%s/%s/pulls/%d", repo.Owner, repo.Repo, p.Number), &pull); err != nil {
+			changes = append(changes, fmt.Sprintf("#%d could not be fetched: %v", p.Number, err))
+			continue
+		}
+		if pull.State != "open" {
+			changes = append(changes, fmt.Sprintf("#%d is %s", p.Number, pull.State))
+			continue
+		}
+		if pull.Head.SHA != p.HeadSHA {
+			changes = append(changes, fmt.Sprintf("#%d moved from %s to %s", p.Number, shortSHA(p.HeadSHA), shortSHA(pull.Head.SHA)))
+		}
+		pulls = append(pulls, pull)
+	}
+
+	return pulls, changes
+}
+
+// shortSHA abbreviates a commit SHA for display
+func shortSHA(sha string) string {
+	if len(sha) > 7 {
+		return sha[:7]
+	}
+	return sha
+}
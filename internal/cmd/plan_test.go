@@ -0,0 +1,128 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/github/gh-combine/internal/github"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPlanFileRoundTrip(t *testing.T) {
+	// Since this test changes global state, don't use t.Parallel()
+	origBranch, origSuffix, origCommitMode := combineBranchName, workingBranchSuffix, commitMode
+	origAddLabels, origBodyTemplate, origWaitForCI := addLabels, bodyTemplate, waitForCITimeout
+	defer func() {
+		combineBranchName, workingBranchSuffix, commitMode = origBranch, origSuffix, origCommitMode
+		addLabels, bodyTemplate, waitForCITimeout = origAddLabels, origBodyTemplate, origWaitForCI
+	}()
+
+	dir := t.TempDir()
+	templatePath := filepath.Join(dir, "body.md")
+	assert.NoError(t, os.WriteFile(templatePath, []byte("Combines {{ .Count }} PRs"), 0o644))
+
+	combineBranchName = "deps"
+	workingBranchSuffix = "-working"
+	commitMode = commitModeSingle
+	addLabels = []string{"dependencies"}
+	bodyTemplate = "@" + templatePath
+	waitForCITimeout = 10 * time.Minute
+
+	plan, err := newCombinePlanFile()
+	assert.NoError(t, err)
+	plan.Repos = []PlannedRepo{{Repo: "owner/repo", Command: "gh combine owner/repo", Pulls: []PlannedPull{{Number: 1, HeadRef: "feature-1", HeadSHA: "abc"}}}}
+
+	path := filepath.Join(dir, "plan.json")
+	assert.NoError(t, writePlanFile(plan, path))
+
+	// Reset the settings, so that restoring the plan has to bring them back
+	combineBranchName, commitMode, addLabels, bodyTemplate, waitForCITimeout = "other", commitModeMerge, nil, "", 0
+
+	read, err := readPlanFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, plan.Repos, read.Repos)
+	assert.NoError(t, read.Options.restore())
+
+	assert.Equal(t, "deps", combineBranchName)
+	assert.Equal(t, commitModeSingle, commitMode)
+	assert.Equal(t, []string{"dependencies"}, addLabels)
+	// Templates read from files are inlined in the plan
+	assert.Equal(t, "Combines {{ .Count }} PRs", bodyTemplate)
+	assert.Equal(t, 10*time.Minute, waitForCITimeout)
+}
+
+func TestReadPlanFileRejectsUnknownVersions(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "plan.json")
+	assert.NoError(t, os.WriteFile(path, []byte(`{"version": 2, "repos": []}`), 0o644))
+
+	_, err := readPlanFile(path)
+	assert.ErrorIs(t, err, errPlanVersion)
+}
+
+func TestVerifyPlannedPulls(t *testing.T) {
+	t.Parallel()
+
+	current := map[string]string{
+		"repos/owner/repo/pulls/1": `{"number": 1, "state": "open", "head": {"ref": "feature-1", "sha": "aaaaaaaaaa"}}`,
+		"repos/owner/repo/pulls/2": `{"number": 2, "state": "open", "head": {"ref": "feature-2", "sha": "cccccccccc"}}`,
+		"repos/owner/repo/pulls/3": `{"number": 3, "state": "closed", "head": {"ref": "feature-3", "sha": "dddddddddd"}}`,
+	}
+	client := &MockRESTClient{
+		GetFunc: func(endpoint string, response interface{}) error {
+			data, ok := current[endpoint]
+			if !ok {
+				return errors.New("HTTP 404: Not Found")
+			}
+			return json.Unmarshal([]byte(data), response)
+		},
+	}
+
+	planned := []PlannedPull{
+		{Number: 1, HeadSHA: "aaaaaaaaaa"},
+		{Number: 2, HeadSHA: "bbbbbbbbbb"},
+		{Number: 3, HeadSHA: "dddddddddd"},
+		{Number: 4, HeadSHA: "eeeeeeeeee"},
+	}
+
+	pulls, changes := verifyPlannedPulls(context.Background(), client, github.Repo{Owner: "owner", Repo: "repo"}, planned)
+
+	numbers := []int{}
+	for _, pull := range pulls {
+		numbers = append(numbers, pull.Number)
+	}
+	assert.Equal(t, []int{1, 2}, numbers)
+	assert.Equal(t, []string{
+		"#2 moved from bbbbbbb to ccccccc",
+		"#3 is closed",
+		"#4 could not be fetched: HTTP 404: Not Found",
+	}, changes)
+}
+
+func TestApplyPlannedRepoRefusesChangedPRs(t *testing.T) {
+	// Since this test reads global state, don't use t.Parallel()
+	var posted []string
+	client := &MockRESTClient{
+		GetFunc: func(endpoint string, response interface{}) error {
+			return json.Unmarshal([]byte(`{"number": 1, "state": "open", "head": {"sha": "moved"}}`), response)
+		},
+		PostFunc: func(endpoint string, body interface{}, response interface{}) error {
+			posted = append(posted, endpoint)
+			return nil
+		},
+	}
+
+	planned := PlannedRepo{Repo: "owner/repo", Pulls: []PlannedPull{{Number: 1, HeadSHA: "planned"}}}
+	err := applyPlannedRepo(context.Background(), nil, client, planned, &RepoStats{}, &StatsCollector{})
+
+	assert.ErrorIs(t, err, errPlanOutdated)
+	assert.True(t, strings.Contains(err.Error(), "#1 moved from planned to moved"))
+	assert.Empty(t, posted)
+}
@@ -0,0 +1,404 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/cli/go-gh/v2/pkg/api"
+	graphql "github.com/cli/shurcooL-graphql"
+
+	"github.com/github/gh-combine/internal/github"
+)
+
+var (
+	errInvalidReviewer   = errors.New("invalid reviewer")
+	errInvalidProject    = errors.New("invalid --project value")
+	errMilestoneNotFound = errors.New("milestone not found")
+	errProjectNotFound   = errors.New("project not found")
+	errLabelsNotFound    = errors.New("labels not found in the repository (use --create-labels to create them)")
+)
+
+// AddProjectV2ItemByIdInput is the GraphQL input type of the addProjectV2ItemById mutation.
+// The name has to match the GraphQL type, so it does not follow the Go initialism convention
+type AddProjectV2ItemByIdInput struct {
+	ProjectID graphql.ID `json:"projectId"`
+	ContentID graphql.ID `json:"contentId"`
+}
+
+// applyPRMetadata adds labels, assignees, reviewers, the milestone and the project to the combined PR.
+// Every step is attempted even if an earlier one fails, and the failures are returned so they can be reported
+func applyPRMetadata(ctx context.Context, graphQlClient *api.GraphQLClient, restClient RESTClientInterface, repo github.Repo, prNumber int, combined github.Pulls) []string {
+	var failures []string
+	report := func(step string, err error) {
+		if err == nil {
+			return
+		}
+		Logger.Warn("Failed to set metadata on combined PR", "repo", repo, "pr", prNumber, "step", step, "error", err)
+		failures = append(failures, fmt.Sprintf("%s: %v", step, err))
+	}
+
+	inherited := inheritedLabels(combined)
+	if len(addLabels) > 0 || len(inherited) > 0 {
+		report("labels", addLabelsToPR(ctx, restClient, repo, prNumber, addLabels, inherited))
+	}
+
+	if len(addAssignees) > 0 {
+		report("assignees", addIssueList(ctx, restClient, repo, prNumber, "assignees", addAssignees))
+	}
+
+	reviewerList := slices.Clone(reviewers)
+	if inheritReviewers {
+		reviewerList = append(reviewerList, inheritedReviewers(repo, combined)...)
+	}
+	if len(reviewerList) > 0 {
+		report("reviewers", requestReviewers(ctx, restClient, repo, prNumber, reviewerList))
+	}
+
+	if milestone != "" {
+		report("milestone", setMilestone(ctx, restClient, repo, prNumber, milestone))
+	}
+
+	if project != "" {
+		report("project", addToProject(ctx, graphQlClient, restClient, repo, prNumber, project))
+	}
+
+	return failures
+}
+
+// addIssueList adds labels or assignees to an issue or pull request
+func addIssueList(ctx context.Context, client RESTClientInterface, repo github.Repo, number int, kind string, values []string) error {
+	endpoint := fmt.Sprintf("repos/%s/%s/issues/%d/%s", repo.Owner, repo.Repo, number, kind)
+	payload, err := encodePayload(map[string][]string{kind: values})
+	if err != nil {
+		return fmt.Errorf("failed to encode %s payload: %w", kind, err)
+	}
+	return client.Post(endpoint, payload, nil)
+}
+
+// defaultLabelColor is the color of created labels that are not inherited from a source PR
+const defaultLabelColor = "ededed"
+
+// combinedPRLabels returns the --add-labels and, with --inherit-labels, the labels of the source PRs
+// that pass the include and exclude patterns, without duplicates
+func combinedPRLabels(pulls github.Pulls) github.Labels {
+	var labels github.Labels
+	for _, name := range addLabels {
+		if !slices.ContainsFunc(labels, func(l github.Label) bool { return strings.EqualFold(l.Name, name) }) {
+			labels = append(labels, github.Label{Name: name})
+		}
+	}
+	return append(labels, inheritedLabels(pulls)...)
+}
+
+// inheritedLabels returns, with --inherit-labels, the labels of the source PRs that pass the include and
+// exclude patterns and are not already added by --add-labels, without duplicates
+func inheritedLabels(pulls github.Pulls) github.Labels {
+	var labels github.Labels
+	if !inheritLabels {
+		return labels
+	}
+
+	add := func(label github.Label) {
+		same := func(name string) bool { return strings.EqualFold(name, label.Name) }
+		if !slices.ContainsFunc(addLabels, same) && !slices.ContainsFunc(labels, func(l github.Label) bool { return same(l.Name) }) {
+			labels = append(labels, label)
+		}
+	}
+
+	for _, pull := range pulls {
+		for _, label := range pull.Labels {
+			if len(inheritLabelsInclude) > 0 && !hasMatchingPattern(label.Name, inheritLabelsInclude) {
+				continue
+			}
+			if hasMatchingPattern(label.Name, inheritLabelsExclude) {
+				continue
+			}
+			add(label)
+		}
+	}
+	return labels
+}
+
+// hasMatchingPattern checks if a label name matches any of the label names, globs or regex: patterns
+func hasMatchingPattern(name string, patterns []string) bool {
+	return slices.ContainsFunc(patterns, func(pattern string) bool {
+		return hasMatchingLabel([]string{name}, pattern, caseSensitiveLabels)
+	})
+}
+
+// addLabelsToPR adds the --add-labels and the inherited labels to a pull request. The added labels are sent as they
+// are, so GitHub creates them if needed. Inherited labels that do not exist in the repository are created with
+// --create-labels, and left out otherwise
+func addLabelsToPR(ctx context.Context, client RESTClientInterface, repo github.Repo, number int, added []string, inherited github.Labels) error {
+	names := slices.Clone(added)
+	var missing []string
+
+	var existing []string
+	if len(inherited) > 0 {
+		labels, err := fetchRepoLabels(ctx, client, repo)
+		if err != nil {
+			return err
+		}
+		existing = labels
+	}
+
+	for _, label := range inherited {
+		if slices.ContainsFunc(existing, func(name string) bool { return strings.EqualFold(name, label.Name) }) {
+			names = append(names, label.Name)
+			continue
+		}
+		if !createLabels {
+			missing = append(missing, label.Name)
+			continue
+		}
+		if err := createLabel(ctx, client, repo, label); err != nil {
+			return fmt.Errorf("failed to create label %q: %w", label.Name, err)
+		}
+		names = append(names, label.Name)
+	}
+
+	if len(names) > 0 {
+		if err := addIssueList(ctx, client, repo, number, "labels", names); err != nil {
+			return err
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("%w: %s", errLabelsNotFound, strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// fetchRepoLabels returns the names of all labels in a repository, handling pagination
+func fetchRepoLabels(ctx context.Context, client RESTClientInterface, repo github.Repo) ([]string, error) {
+	var names []string
+	page := 1
+
+	for {
+		// Check for cancellation
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		default:
+			// Continue processing
+		}
+
+		var labels github.Labels
+		endpoint := fmt.Sprintf("repos/%s/%s/labels?page=%d&per_page=100", repo.Owner, repo.Repo, page)
+		if err := client.Get(endpoint, &labels); err != nil {
+			return nil, fmt.Errorf("failed to fetch labels from page %d: %w", page, err)
+		}
+		names = append(names, labels.Names()...)
+
+		// If fewer than 100 labels are returned, we've reached the last page
+		if len(labels) < 100 {
+			return names, nil
+		}
+
+		page++
+	}
+}
+
+// createLabel creates a label in the repository, keeping the color of inherited labels
+func createLabel(ctx context.Context, client RESTClientInterface, repo github.Repo, label github.Label) error {
+	color := label.Color
+	if color == "" {
+		color = defaultLabelColor
+	}
+
+	endpoint := fmt.Sprintf("repos/%s/%s/labels", repo.Owner, repo.Repo)
+	payload, err := encodePayload(map[string]string{"name": label.Name, "color": color})
+	if err != nil {
+		return fmt.Errorf("failed to encode label payload: %w", err)
+	}
+	return client.Post(endpoint, payload, nil)
+}
+
+// inheritedReviewers returns the users and teams already requested to review the source PRs.
+// Teams are returned in the org/team format
+func inheritedReviewers(repo github.Repo, pulls github.Pulls) []string {
+	var result []string
+	for _, pull := range pulls {
+		for _, user := range pull.RequestedReviewers {
+			result = append(result, user.Login)
+		}
+		for _, team := range pull.RequestedTeams {
+			result = append(result, repo.Owner+"/"+team.Slug)
+		}
+	}
+	return result
+}
+
+// requestReviewers requests reviews from users and org/team teams on a pull request.
+// Only teams of the repository owner can review, so teams of other orgs are rejected
+func requestReviewers(ctx context.Context, client RESTClientInterface, repo github.Repo, number int, reviewerList []string) error {
+	users, teams := []string{}, []string{}
+	for _, reviewer := range reviewerList {
+		if org, team, isTeam := strings.Cut(reviewer, "/"); isTeam {
+			if !strings.EqualFold(org, repo.Owner) {
+				return fmt.Errorf("%w: %q (team is not in the %s org)", errInvalidReviewer, reviewer, repo.Owner)
+			}
+			if !slices.Contains(teams, team) {
+				teams = append(teams, team)
+			}
+		} else if !slices.ContainsFunc(users, func(u string) bool { return strings.EqualFold(u, reviewer) }) {
+			users = append(users, reviewer)
+		}
+	}
+
+	endpoint := fmt.Sprintf("repos/%s/%s/pulls/%d/requested_reviewers", repo.Owner, repo.Repo, number)
+	payload, err := encodePayload(map[string][]string{"reviewers": users, "team_reviewers": teams})
+	if err != nil {
+		return fmt.Errorf("failed to encode reviewers payload: %w", err)
+	}
+	return client.Post(endpoint, payload, nil)
+}
+
+// setMilestone sets the milestone of an issue or pull request, given by number or title
+func setMilestone(ctx context.Context, client RESTClientInterface, repo github.Repo, number int, value string) error {
+	milestoneNumber, err := resolveMilestone(ctx, client, repo, value)
+	if err != nil {
+		return err
+	}
+
+	endpoint := fmt.Sprintf("repos/%s/%s/issues/%d", repo.Owner, repo.Repo, number)
+	payload, err := encodePayload(map[string]int{"milestone": milestoneNumber})
+	if err != nil {
+		return fmt.Errorf("failed to encode milestone payload: %w", err)
+	}
+	return client.Patch(endpoint, payload, nil)
+}
+
+// resolveMilestone returns the number of a milestone given by number or by the title of an open milestone
+func resolveMilestone(ctx context.Context, client RESTClientInterface, repo github.Repo, value string) (int, error) {
+	if number, err := strconv.Atoi(value); err == nil {
+		return number, nil
+	}
+
+	page := 1
+	for {
+		// Check for cancellation
+		select {
+		case <-ctx.Done():
+			return 0, ctx.Err()
+		default:
+			// Continue processing
+		}
+
+		var milestones []github.Milestone
+		endpoint := fmt.Sprintf("repos/%s/%s/milestones?state=open&page=%d&per_page=100", repo.Owner, repo.Repo, page)
+		if err := client.Get(endpoint, &milestones); err != nil {
+			return 0, fmt.Errorf("failed to fetch milestones from page %d: %w", page, err)
+		}
+
+		for _, m := range milestones {
+			if strings.EqualFold(m.Title, value) {
+				return m.Number, nil
+			}
+		}
+
+		// If fewer than 100 milestones are returned, we've reached the last page
+		if len(milestones) < 100 {
+			return 0, fmt.Errorf("%w: %s", errMilestoneNotFound, value)
+		}
+
+		page++
+	}
+}
+
+// addToProject adds a pull request to a Projects (v2) project of the repository owner or another owner
+func addToProject(ctx context.Context, graphQlClient *api.GraphQLClient, restClient RESTClientInterface, repo github.Repo, number int, value string) error {
+	owner, projectNumber, err := parseProjectRef(value, repo)
+	if err != nil {
+		return err
+	}
+
+	var query struct {
+		RepositoryOwner struct {
+			Organization struct {
+				ProjectV2 *struct {
+					ID string
+				} `graphql:"projectV2(number: $number)"`
+			} `graphql:"... on Organization"`
+			User struct {
+				ProjectV2 *struct {
+					ID string
+				} `graphql:"projectV2(number: $number)"`
+			} `graphql:"... on User"`
+		} `graphql:"repositoryOwner(login: $login)"`
+	}
+
+	variables := map[string]interface{}{
+		"login":  graphql.String(owner),
+		"number": graphql.Int(projectNumber),
+	}
+	if err := graphQlClient.QueryWithContext(ctx, "ProjectID", &query, variables); err != nil {
+		return fmt.Errorf("failed to find project: %w", err)
+	}
+
+	projectID := ""
+	if p := query.RepositoryOwner.Organization.ProjectV2; p != nil {
+		projectID = p.ID
+	} else if p := query.RepositoryOwner.User.ProjectV2; p != nil {
+		projectID = p.ID
+	}
+	if projectID == "" {
+		return fmt.Errorf("%w: %s/%d", errProjectNotFound, owner, projectNumber)
+	}
+
+	nodeID, err := getPullRequestNodeID(ctx, restClient, repo, number)
+	if err != nil {
+		return err
+	}
+
+	var mutation struct {
+		AddProjectV2Item struct {
+			Item struct {
+				ID string
+			}
+		} `graphql:"addProjectV2ItemById(input: $input)"`
+	}
+
+	input := map[string]interface{}{
+		"input": AddProjectV2ItemByIdInput{
+			ProjectID: graphql.ID(projectID),
+			ContentID: graphql.ID(nodeID),
+		},
+	}
+	return graphQlClient.MutateWithContext(ctx, "AddToProject", &mutation, input)
+}
+
+// parseProjectRef parses a project given as a number, owned by the repository owner, or as owner/number
+func parseProjectRef(value string, repo github.Repo) (string, int, error) {
+	owner := repo.Owner
+	numberPart := value
+	if o, n, found := strings.Cut(value, "/"); found {
+		owner, numberPart = o, n
+	}
+
+	number, err := strconv.Atoi(numberPart)
+	if err != nil || number <= 0 || owner == "" {
+		return "", 0, fmt.Errorf("%w: %q (must be a project number or owner/number)", errInvalidProject, value)
+	}
+	return owner, number, nil
+}
+
+// ValidatePRMetadata checks the format of --reviewers and --project
+func ValidatePRMetadata(reviewerList []string, projectRef string) error {
+	for _, reviewer := range reviewerList {
+		org, team, isTeam := strings.Cut(reviewer, "/")
+		if reviewer == "" || (isTeam && (org == "" || team == "" || strings.Contains(team, "/"))) {
+			return fmt.Errorf("%w: %q (must be a user or org/team)", errInvalidReviewer, reviewer)
+		}
+	}
+
+	if projectRef != "" {
+		if _, _, err := parseProjectRef(projectRef, github.Repo{Owner: "owner"}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,307 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/github/gh-combine/internal/github"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequestReviewers(t *testing.T) {
+	t.Parallel()
+
+	var gotEndpoint string
+	var gotPayload map[string][]string
+	client := &MockRESTClient{
+		PostFunc: func(endpoint string, body interface{}, response interface{}) error {
+			gotEndpoint = endpoint
+			return json.NewDecoder(body.(io.Reader)).Decode(&gotPayload)
+		},
+	}
+
+	repo := github.Repo{Owner: "owner", Repo: "repo"}
+	err := requestReviewers(context.Background(), client, repo, 42, []string{"octocat", "owner/core", "Octocat", "hubot", "owner/core"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "repos/owner/repo/pulls/42/requested_reviewers", gotEndpoint)
+	assert.Equal(t, map[string][]string{"reviewers": {"octocat", "hubot"}, "team_reviewers": {"core"}}, gotPayload)
+
+	gotEndpoint = ""
+	err = requestReviewers(context.Background(), client, repo, 42, []string{"octocat", "other-org/core"})
+	assert.ErrorIs(t, err, errInvalidReviewer, "a team of another org should be rejected")
+	assert.Empty(t, gotEndpoint)
+}
+
+func TestInheritedReviewers(t *testing.T) {
+	t.Parallel()
+
+	pulls := github.Pulls{
+		{Number: 1, RequestedReviewers: []github.User{{Login: "octocat"}}, RequestedTeams: []github.Team{{Slug: "core"}}},
+		{Number: 2, RequestedReviewers: []github.User{{Login: "hubot"}}},
+		{Number: 3},
+	}
+
+	got := inheritedReviewers(github.Repo{Owner: "owner", Repo: "repo"}, pulls)
+	assert.Equal(t, []string{"octocat", "owner/core", "hubot"}, got)
+}
+
+func TestResolveMilestone(t *testing.T) {
+	t.Parallel()
+
+	// The first page is full, so the milestone on the second page must be found too
+	firstPage := make([]github.Milestone, 100)
+	for i := range firstPage {
+		firstPage[i] = github.Milestone{Number: 100 + i, Title: fmt.Sprintf("sprint-%d", i)}
+	}
+	client := &MockRESTClient{
+		GetFunc: func(endpoint string, response interface{}) error {
+			switch endpoint {
+			case "repos/owner/repo/milestones?state=open&page=1&per_page=100":
+				data, _ := json.Marshal(firstPage)
+				return json.Unmarshal(data, response)
+			case "repos/owner/repo/milestones?state=open&page=2&per_page=100":
+				return json.Unmarshal([]byte(`[{"number": 3, "title": "v1.0"}, {"number": 7, "title": "v2.0"}]`), response)
+			}
+			return fmt.Errorf("unexpected endpoint %s", endpoint)
+		},
+	}
+	repo := github.Repo{Owner: "owner", Repo: "repo"}
+
+	number, err := resolveMilestone(context.Background(), client, repo, "V2.0")
+	assert.NoError(t, err)
+	assert.Equal(t, 7, number)
+
+	number, err = resolveMilestone(context.Background(), client, repo, "12")
+	assert.NoError(t, err)
+	assert.Equal(t, 12, number)
+
+	_, err = resolveMilestone(context.Background(), client, repo, "v3.0")
+	assert.ErrorIs(t, err, errMilestoneNotFound)
+}
+
+func TestParseProjectRef(t *testing.T) {
+	t.Parallel()
+
+	repo := github.Repo{Owner: "owner", Repo: "repo"}
+
+	tests := []struct {
+		value      string
+		wantOwner  string
+		wantNumber int
+		wantErr    bool
+	}{
+		{value: "5", wantOwner: "owner", wantNumber: 5},
+		{value: "my-org/12", wantOwner: "my-org", wantNumber: 12},
+		{value: "my-org/project", wantErr: true},
+		{value: "/5", wantErr: true},
+		{value: "0", wantErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.value, func(t *testing.T) {
+			t.Parallel()
+
+			owner, number, err := parseProjectRef(test.value, repo)
+			if test.wantErr {
+				assert.ErrorIs(t, err, errInvalidProject)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, test.wantOwner, owner)
+			assert.Equal(t, test.wantNumber, number)
+		})
+	}
+}
+
+func TestValidatePRMetadata(t *testing.T) {
+	t.Parallel()
+
+	assert.NoError(t, ValidatePRMetadata([]string{"octocat", "my-org/my-team"}, "my-org/5"))
+	assert.ErrorIs(t, ValidatePRMetadata([]string{"my-org/"}, ""), errInvalidReviewer)
+	assert.ErrorIs(t, ValidatePRMetadata([]string{"a/b/c"}, ""), errInvalidReviewer)
+	assert.ErrorIs(t, ValidatePRMetadata(nil, "project"), errInvalidProject)
+}
+
+func TestApplyPRMetadataReportsFailures(t *testing.T) {
+	// Since this test changes global state, don't use t.Parallel()
+	origAddLabels, origAddAssignees, origReviewers, origMilestone := addLabels, addAssignees, reviewers, milestone
+	defer func() {
+		addLabels, addAssignees, reviewers, milestone = origAddLabels, origAddAssignees, origReviewers, origMilestone
+	}()
+
+	addLabels = []string{"dependencies"}
+	addAssignees = []string{"octocat"}
+	reviewers = []string{"hubot"}
+	milestone = "4"
+
+	var calls []string
+	client := &MockRESTClient{
+		GetFunc: func(endpoint string, response interface{}) error {
+			return json.Unmarshal([]byte(`[{"name": "dependencies"}]`), response)
+		},
+		PostFunc: func(endpoint string, body interface{}, response interface{}) error {
+			calls = append(calls, endpoint)
+			if endpoint == "repos/owner/repo/issues/42/assignees" {
+				return errors.New("HTTP 422: Validation Failed")
+			}
+			return nil
+		},
+		PatchFunc: func(endpoint string, body io.Reader, response interface{}) error {
+			calls = append(calls, endpoint)
+			return nil
+		},
+	}
+
+	failures := applyPRMetadata(context.Background(), nil, client, github.Repo{Owner: "owner", Repo: "repo"}, 42, nil)
+
+	assert.Equal(t, []string{"assignees: HTTP 422: Validation Failed"}, failures)
+	assert.Equal(t, []string{
+		"repos/owner/repo/issues/42/labels",
+		"repos/owner/repo/issues/42/assignees",
+		"repos/owner/repo/pulls/42/requested_reviewers",
+		"repos/owner/repo/issues/42",
+	}, calls)
+}
+
+func TestCombinedPRLabels(t *testing.T) {
+	// Since this test changes global state, don't use t.Parallel()
+	origAddLabels, origInheritLabels := addLabels, inheritLabels
+	origInclude, origExclude := inheritLabelsInclude, inheritLabelsExclude
+	defer func() {
+		addLabels, inheritLabels = origAddLabels, origInheritLabels
+		inheritLabelsInclude, inheritLabelsExclude = origInclude, origExclude
+	}()
+
+	pulls := github.Pulls{
+		{Number: 1, Labels: github.Labels{{Name: "dependencies", Color: "0366d6"}, {Name: "security", Color: "ee0701"}, {Name: "javascript"}}},
+		{Number: 2, Labels: github.Labels{{Name: "Dependencies"}, {Name: "go"}, {Name: "size/S"}}},
+	}
+
+	tests := []struct {
+		name          string
+		addLabels     []string
+		inheritLabels bool
+		include       []string
+		exclude       []string
+		want          []string
+	}{
+		{
+			name:      "only added labels",
+			addLabels: []string{"combined"},
+			want:      []string{"combined"},
+		},
+		{
+			name:          "union of added and inherited labels",
+			addLabels:     []string{"combined", "security"},
+			inheritLabels: true,
+			want:          []string{"combined", "security", "dependencies", "javascript", "go", "size/S"},
+		},
+		{
+			name:          "include patterns",
+			inheritLabels: true,
+			include:       []string{"security", "regex:^(go|javascript)$"},
+			want:          []string{"security", "javascript", "go"},
+		},
+		{
+			name:          "exclude patterns",
+			inheritLabels: true,
+			exclude:       []string{"glob:size/*", "dependencies"},
+			want:          []string{"security", "javascript", "go"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			addLabels = test.addLabels
+			inheritLabels = test.inheritLabels
+			inheritLabelsInclude = test.include
+			inheritLabelsExclude = test.exclude
+
+			assert.Equal(t, test.want, combinedPRLabels(pulls).Names())
+		})
+	}
+}
+
+func TestAddLabelsToPR(t *testing.T) {
+	// Since this test changes global state, don't use t.Parallel()
+	origCreateLabels := createLabels
+	defer func() { createLabels = origCreateLabels }()
+
+	repo := github.Repo{Owner: "owner", Repo: "repo"}
+	inherited := github.Labels{{Name: "Dependencies"}, {Name: "security", Color: "ee0701"}}
+
+	for _, create := range []bool{false, true} {
+		createLabels = create
+
+		created := map[string]string{}
+		var added []string
+		client := &MockRESTClient{
+			GetFunc: func(endpoint string, response interface{}) error {
+				return json.Unmarshal([]byte(`[{"name": "dependencies"}]`), response)
+			},
+			PostFunc: func(endpoint string, body interface{}, response interface{}) error {
+				switch endpoint {
+				case "repos/owner/repo/labels":
+					var payload map[string]string
+					if err := json.NewDecoder(body.(io.Reader)).Decode(&payload); err != nil {
+						return err
+					}
+					created[payload["name"]] = payload["color"]
+				case "repos/owner/repo/issues/42/labels":
+					var payload map[string][]string
+					if err := json.NewDecoder(body.(io.Reader)).Decode(&payload); err != nil {
+						return err
+					}
+					added = payload["labels"]
+				}
+				return nil
+			},
+		}
+
+		err := addLabelsToPR(context.Background(), client, repo, 42, []string{"combined"}, inherited)
+
+		if create {
+			assert.NoError(t, err)
+			assert.Equal(t, map[string]string{"security": "ee0701"}, created)
+			assert.Equal(t, []string{"combined", "Dependencies", "security"}, added)
+		} else {
+			assert.ErrorIs(t, err, errLabelsNotFound)
+			assert.ErrorContains(t, err, "security")
+			assert.Empty(t, created)
+			assert.Equal(t, []string{"combined", "Dependencies"}, added)
+		}
+	}
+}
+
+func TestAddLabelsToPRSendsAddedLabels(t *testing.T) {
+	// Since this test changes global state, don't use t.Parallel()
+	origCreateLabels := createLabels
+	defer func() { createLabels = origCreateLabels }()
+	createLabels = false
+
+	var added []string
+	client := &MockRESTClient{
+		GetFunc: func(endpoint string, response interface{}) error {
+			return errors.New("unexpected request " + endpoint)
+		},
+		PostFunc: func(endpoint string, body interface{}, response interface{}) error {
+			assert.Equal(t, "repos/owner/repo/issues/42/labels", endpoint, "missing --add-labels must not be created by gh-combine")
+			var payload map[string][]string
+			if err := json.NewDecoder(body.(io.Reader)).Decode(&payload); err != nil {
+				return err
+			}
+			added = payload["labels"]
+			return nil
+		},
+	}
+
+	// The repository has no "combined" label yet, GitHub creates it when it is added
+	err := addLabelsToPR(context.Background(), client, github.Repo{Owner: "owner", Repo: "repo"}, 42, []string{"combined"}, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"combined"}, added)
+}
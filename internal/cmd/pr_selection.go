@@ -0,0 +1,119 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/github/gh-combine/internal/github"
+)
+
+var (
+	errPRsConflict      = errors.New("--exclude-prs contains a value which conflicts with --prs")
+	errPRsMultipleRepos = errors.New("--prs can only be used with a single repository")
+	errInvalidPRNumber  = errors.New("invalid pull request number")
+)
+
+// ValidatePRNumbers checks that the --prs and --exclude-prs values are valid and do not overlap
+func ValidatePRNumbers(includePRs, excludePRs []int) error {
+	for _, number := range slices.Concat(includePRs, excludePRs) {
+		if number <= 0 {
+			return fmt.Errorf("%w: %d", errInvalidPRNumber, number)
+		}
+	}
+
+	for _, excluded := range excludePRs {
+		if slices.Contains(includePRs, excluded) {
+			return fmt.Errorf("%w: %d", errPRsConflict, excluded)
+		}
+	}
+
+	return nil
+}
+
+// selectExplicitPullRequests resolves the --prs numbers against the open pull requests of a repository.
+// Listed PRs that are closed, merged, missing or that do not target the default branch are returned
+// as invalid (e.g. "#12 (closed)") so that they can be reported instead of silently dropped
+func selectExplicitPullRequests(ctx context.Context, client RESTClientInterface, repo github.Repo, openPulls github.Pulls, numbers []int) (selected github.Pulls, invalid []string, err error) {
+	defaultBranch, err := getDefaultBranch(ctx, client, repo)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for _, number := range numbers {
+		// Check for cancellation
+		select {
+		case <-ctx.Done():
+			return nil, nil, ctx.Err()
+		default:
+			// Continue processing
+		}
+
+		index := slices.IndexFunc(openPulls, func(pull github.Pull) bool { return pull.Number == number })
+		if index != -1 {
+			pull := openPulls[index]
+			if pull.Base.Ref != defaultBranch {
+				invalid = append(invalid, fmt.Sprintf("#%d (targets %s, not %s)", number, pull.Base.Ref, defaultBranch))
+				continue
+			}
+			selected = append(selected, pull)
+			continue
+		}
+
+		// The PR is not open, fetch it to find out why
+		reason, err := closedPullRequestReason(client, repo, number)
+		if err != nil {
+			return nil, nil, err
+		}
+		invalid = append(invalid, fmt.Sprintf("#%d (%s)", number, reason))
+	}
+
+	return selected, invalid, nil
+}
+
+// closedPullRequestReason returns why a pull request that is not in the open list cannot be combined
+func closedPullRequestReason(client RESTClientInterface, repo github.Repo, number int) (string, error) {
+	var pull github.Pull
+	endpoint := fmt.Sprintf("repos/%s/%s/pulls/%d", repo.Owner, repo.Repo, number)
+	if err := client.Get(endpoint, &pull); err != nil {
+		if isNotFoundError(err) {
+			return "not found", nil
+		}
+		return "", fmt.Errorf("failed to fetch pull request #%d: %w", number, err)
+	}
+
+	if pull.Merged {
+		return "merged", nil
+	}
+	if pull.State != "" && pull.State != "open" {
+		return pull.State, nil
+	}
+
+	return "not open", nil
+}
+
+// isNotFoundError checks if the error is a 404 Not Found
+func isNotFoundError(err error) bool {
+	return err != nil && strings.Contains(err.Error(), "HTTP 404")
+}
+
+// combinedInOtherPRs maps the source PRs of the open combined PRs of a repository to the combined PR that includes
+// them. The combined PR of --combine-branch-name is left out, since the run replaces it
+func combinedInOtherPRs(repo github.Repo, pulls github.Pulls) map[int]int {
+	replaced := findCombinedPR(pulls, repo)
+
+	combinedIn := make(map[int]int)
+	for _, pull := range pulls {
+		if pull.FromFork(repo) || !isCombinedPRBody(pull.Body) || (replaced != nil && pull.Number == replaced.Number) {
+			continue
+		}
+		for _, number := range parseCombinedPRNumbers(pull.Body) {
+			if _, ok := combinedIn[number]; !ok {
+				combinedIn[number] = pull.Number
+			}
+		}
+	}
+	return combinedIn
+}
@@ -0,0 +1,201 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/github/gh-combine/internal/github"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidatePRNumbers(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		includePRs []int
+		excludePRs []int
+		want       error
+	}{
+		{
+			want: nil,
+		},
+		{
+			includePRs: []int{1, 2},
+			excludePRs: []int{3},
+			want:       nil,
+		},
+		{
+			includePRs: []int{1, 2},
+			excludePRs: []int{2},
+			want:       errPRsConflict,
+		},
+		{
+			includePRs: []int{0},
+			want:       errInvalidPRNumber,
+		},
+		{
+			excludePRs: []int{-4},
+			want:       errInvalidPRNumber,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run("", func(t *testing.T) {
+			t.Parallel()
+
+			got := ValidatePRNumbers(test.includePRs, test.excludePRs)
+			if !errors.Is(got, test.want) {
+				t.Fatalf("want %v, but got %v", test.want, got)
+			}
+		})
+	}
+}
+
+func TestSelectExplicitPullRequests(t *testing.T) {
+	t.Parallel()
+
+	repo := github.Repo{Owner: "owner", Repo: "repo"}
+	openPulls := github.Pulls{
+		{Number: 12, State: "open", Base: github.Ref{Ref: "main"}},
+		{Number: 15, State: "open", Base: github.Ref{Ref: "main"}},
+		{Number: 16, State: "open", Base: github.Ref{Ref: "release"}},
+	}
+
+	client := &MockRESTClient{
+		GetFunc: func(endpoint string, response interface{}) error {
+			switch {
+			case endpoint == "repos/owner/repo":
+				response.(*struct {
+					DefaultBranch string `json:"default_branch"`
+				}).DefaultBranch = "main"
+			case strings.HasSuffix(endpoint, "/pulls/19"):
+				*response.(*github.Pull) = github.Pull{Number: 19, State: "closed"}
+			case strings.HasSuffix(endpoint, "/pulls/20"):
+				*response.(*github.Pull) = github.Pull{Number: 20, State: "closed", Merged: true}
+			case strings.HasSuffix(endpoint, "/pulls/404"):
+				return errors.New("HTTP 404: Not Found (https://api.github.com/repos/owner/repo/pulls/404)")
+			}
+			return nil
+		},
+	}
+
+	selected, invalid, err := selectExplicitPullRequests(context.Background(), client, repo, openPulls, []int{12, 15, 16, 19, 20, 404})
+	assert.NoError(t, err)
+
+	var numbers []int
+	for _, pull := range selected {
+		numbers = append(numbers, pull.Number)
+	}
+	assert.Equal(t, []int{12, 15}, numbers)
+	assert.Equal(t, []string{
+		"#16 (targets release, not main)",
+		"#19 (closed)",
+		"#20 (merged)",
+		"#404 (not found)",
+	}, invalid)
+}
+
+func TestSelectExplicitPullRequestsFetchError(t *testing.T) {
+	t.Parallel()
+
+	client := &MockRESTClient{
+		GetFunc: func(endpoint string, response interface{}) error {
+			if strings.Contains(endpoint, "/pulls/") {
+				return errors.New("HTTP 500: Internal Server Error")
+			}
+			return nil
+		},
+	}
+
+	_, _, err := selectExplicitPullRequests(context.Background(), client, github.Repo{Owner: "owner", Repo: "repo"}, nil, []int{1})
+	assert.Error(t, err)
+}
+
+func TestSelectPullRequestsSkipsForks(t *testing.T) {
+	// Since this test changes global state, don't use t.Parallel()
+	origIncludeForks, origBranchPrefix := includeForks, branchPrefix
+	defer func() { includeForks, branchPrefix = origIncludeForks, origBranchPrefix }()
+	branchPrefix = "dependabot/"
+
+	repo := github.Repo{Owner: "owner", Repo: "repo"}
+	pulls := github.Pulls{
+		{Number: 1, Head: github.Ref{Ref: "dependabot/npm_and_yarn/lodash-4.17.21", Repo: &github.RefRepo{FullName: "owner/repo"}}},
+		{Number: 2, Head: github.Ref{Ref: "dependabot/npm_and_yarn/react-18.3.0", Repo: &github.RefRepo{FullName: "contributor/repo"}}},
+		{Number: 3, Head: github.Ref{Ref: "dependabot/npm_and_yarn/left-pad-1.3.0"}},
+		{Number: 4, Head: github.Ref{Ref: "add-feature", Repo: &github.RefRepo{FullName: "contributor/repo"}}},
+	}
+
+	for _, include := range []bool{false, true} {
+		includeForks = include
+		repoStats := &RepoStats{}
+		stats := &StatsCollector{}
+
+		// No CI or approval requirements are set, so the GraphQL client is not used
+		matched, _, err := selectPullRequests(context.Background(), &MockRESTClient{}, nil, nil, repo, pulls, repoStats, stats)
+		assert.NoError(t, err)
+
+		numbers := []int{}
+		for _, pull := range matched {
+			numbers = append(numbers, pull.Number)
+		}
+
+		// PRs from forks that do not match the criteria are not reported as forks
+		if include {
+			assert.Equal(t, []int{1, 2, 3}, numbers)
+			assert.Empty(t, repoStats.ForkPRs)
+		} else {
+			assert.Equal(t, []int{1}, numbers)
+			assert.Equal(t, []string{"#2", "#3"}, repoStats.ForkPRs)
+		}
+		assert.Equal(t, 1, repoStats.SkippedCriteria)
+	}
+}
+
+func TestSelectPullRequestsSkipsAlreadyCombined(t *testing.T) {
+	// Since this test changes global state, don't use t.Parallel()
+	origAllowRecombine, origBranchPrefix, origBranch := allowRecombine, branchPrefix, combineBranchName
+	defer func() {
+		allowRecombine, branchPrefix, combineBranchName = origAllowRecombine, origBranchPrefix, origBranch
+	}()
+	branchPrefix = "dependabot/"
+	combineBranchName = "combined-prs"
+
+	repo := github.Repo{Owner: "owner", Repo: "repo"}
+	sameRepo := &github.RefRepo{FullName: "owner/repo"}
+	pulls := github.Pulls{
+		{Number: 1, Head: github.Ref{Ref: "dependabot/npm_and_yarn/lodash-4.17.21", Repo: sameRepo}},
+		{Number: 2, Head: github.Ref{Ref: "dependabot/npm_and_yarn/react-18.3.0", Repo: sameRepo}},
+		{Number: 3, Head: github.Ref{Ref: "dependabot/npm_and_yarn/left-pad-1.3.0", Repo: sameRepo}},
+		{Number: 4, Head: github.Ref{Ref: "dependabot/npm_and_yarn/chalk-5.3.0", Repo: sameRepo}},
+		// Another combined PR, which includes #2
+		{Number: 10, Body: combinedPRsHeader + "\n- closes: #2\n\n" + generatedWithLink, Head: github.Ref{Ref: "other-combined", Repo: sameRepo}},
+		// The combined PR that the run replaces, which includes #3
+		{Number: 11, Body: "Custom body\n\n<!-- gh-combine:prs=3 -->", Head: github.Ref{Ref: "combined-prs", Repo: sameRepo}},
+		// A PR from a fork that looks like a combined PR, which includes #4
+		{Number: 12, Body: "<!-- gh-combine:prs=4 -->", Head: github.Ref{Ref: "combined-prs", Repo: &github.RefRepo{FullName: "contributor/repo"}}},
+	}
+
+	for _, allow := range []bool{false, true} {
+		allowRecombine = allow
+		repoStats := &RepoStats{}
+
+		// No CI or approval requirements are set, so the GraphQL client is not used
+		matched, _, err := selectPullRequests(context.Background(), &MockRESTClient{}, nil, nil, repo, pulls, repoStats, &StatsCollector{})
+		assert.NoError(t, err)
+
+		numbers := []int{}
+		for _, pull := range matched {
+			numbers = append(numbers, pull.Number)
+		}
+
+		if allow {
+			assert.Equal(t, []int{1, 2, 3, 4}, numbers)
+			assert.Empty(t, repoStats.AlreadyCombined)
+		} else {
+			assert.Equal(t, []int{1, 3, 4}, numbers)
+			assert.Equal(t, []string{"#2 (in #10)"}, repoStats.AlreadyCombined)
+		}
+	}
+}
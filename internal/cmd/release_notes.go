@@ -0,0 +1,191 @@
+package cmd
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/github/gh-combine/internal/github"
+)
+
+// maxPRBodyLength is the maximum length of a pull request body accepted by GitHub
+const maxPRBodyLength = 65536
+
+// truncatePRBody cuts a body to at most limit bytes without splitting a UTF-8 character
+func truncatePRBody(body string, limit int) string {
+	if len(body) <= limit {
+		return body
+	}
+	Logger.Warn("Combined PR body is too long, truncating it", "length", len(body), "limit", limit)
+	return strings.ToValidUTF8(body[:max(limit, 0)], "")
+}
+
+var (
+	// Matches opening and closing details tags, e.g. "<details>", "<details open>" and "</details>"
+	detailsTagRegex = regexp.MustCompile(`(?i)<details[^>]*>|</details>`)
+	summaryRegex    = regexp.MustCompile(`(?is)<summary>(.*?)</summary>`)
+
+	// Collapsible sections of Dependabot PR bodies that are not about the dependency itself
+	ignoredReleaseNotesSections = []string{"dependabot commands and options"}
+)
+
+// releaseNotesSection is a collapsible section extracted from the body of a dependency update PR
+type releaseNotesSection struct {
+	Summary string
+	Content string
+}
+
+// dependencyNotes are the release notes of a single dependency update
+type dependencyNotes struct {
+	pull     github.Pull
+	update   DependencyUpdate
+	sections []releaseNotesSection
+	omitted  bool
+}
+
+// extractReleaseNotes returns the top level collapsible sections (release notes, changelog, commits)
+// and the compatibility score badge from the body of a Dependabot PR
+func extractReleaseNotes(body string) []releaseNotesSection {
+	var sections []releaseNotesSection
+
+	for _, line := range strings.Split(body, "\n") {
+		if strings.Contains(line, "compatibility score") && strings.HasPrefix(strings.TrimSpace(line), "[![") {
+			sections = append(sections, releaseNotesSection{Summary: "Compatibility score", Content: strings.TrimSpace(line)})
+			break
+		}
+	}
+
+	depth, start := 0, 0
+	for _, loc := range detailsTagRegex.FindAllStringIndex(body, -1) {
+		if !strings.HasPrefix(body[loc[0]:loc[1]], "</") {
+			if depth == 0 {
+				start = loc[0]
+			}
+			depth++
+			continue
+		}
+		if depth == 0 {
+			// Unbalanced closing tag
+			continue
+		}
+		depth--
+		if depth > 0 {
+			continue
+		}
+
+		content := body[start:loc[1]]
+		summary := ""
+		if match := summaryRegex.FindStringSubmatch(content); match != nil {
+			summary = strings.TrimSpace(match[1])
+		}
+		if slices.Contains(ignoredReleaseNotesSections, strings.ToLower(summary)) {
+			continue
+		}
+		sections = append(sections, releaseNotesSection{Summary: summary, Content: content})
+	}
+
+	return sections
+}
+
+// releaseNotesPriority ranks sections by how useful they are to reviewers, lower is more useful.
+// When the notes do not fit in the PR body the least useful sections are dropped first
+func releaseNotesPriority(section releaseNotesSection) int {
+	summary := strings.ToLower(section.Summary)
+	switch {
+	case strings.Contains(summary, "compatibility"):
+		return 0
+	case strings.Contains(summary, "release notes"):
+		return 1
+	case strings.Contains(summary, "changelog"):
+		return 2
+	case strings.Contains(summary, "commits"):
+		return 4
+	default:
+		return 3
+	}
+}
+
+// generateReleaseNotes renders the release notes of the combined dependency updates, nested in a collapsible
+// block per dependency. Sections are dropped, least useful and largest first, until the notes fit in budget
+func generateReleaseNotes(pulls github.Pulls, budget int) string {
+	var notes []*dependencyNotes
+	for _, pull := range pulls {
+		update, ok := ParseDependencyUpdate(pull.Title, pull.Head.Ref)
+		if !ok {
+			continue
+		}
+		sections := extractReleaseNotes(pull.Body)
+		if len(sections) == 0 {
+			continue
+		}
+		notes = append(notes, &dependencyNotes{pull: pull, update: update, sections: sections})
+	}
+
+	for len(notes) > 0 {
+		rendered := renderReleaseNotes(notes)
+		if len(rendered) <= budget {
+			return rendered
+		}
+		if !dropReleaseNotesSection(notes) {
+			break
+		}
+	}
+
+	Logger.Debug("Release notes do not fit in the combined PR body", "budget", budget)
+	return ""
+}
+
+// dropReleaseNotesSection removes the least useful section, the largest one among equally useful sections.
+// It returns false when there is nothing left to remove
+func dropReleaseNotesSection(notes []*dependencyNotes) bool {
+	var worst *dependencyNotes
+	worstIndex := -1
+	for _, n := range notes {
+		for i, section := range n.sections {
+			if worst != nil {
+				current := worst.sections[worstIndex]
+				if releaseNotesPriority(section) < releaseNotesPriority(current) {
+					continue
+				}
+				if releaseNotesPriority(section) == releaseNotesPriority(current) && len(section.Content) <= len(current.Content) {
+					continue
+				}
+			}
+			worst, worstIndex = n, i
+		}
+	}
+
+	if worst == nil {
+		return false
+	}
+
+	worst.sections = slices.Delete(worst.sections, worstIndex, worstIndex+1)
+	worst.omitted = true
+	return true
+}
+
+// renderReleaseNotes renders the release notes section of the combined PR body
+func renderReleaseNotes(notes []*dependencyNotes) string {
+	var b strings.Builder
+	b.WriteString("\n📝 Release notes:\n\n")
+	for _, n := range notes {
+		fmt.Fprintf(&b, "<details>\n<summary><code>%s</code> %s → %s (%s#%d)</summary>\n\n", n.update.Name, valueOrDash(n.update.From), n.update.To, ecosystemPrefix(n.update.Ecosystem), n.pull.Number)
+		for _, section := range n.sections {
+			b.WriteString(section.Content + "\n\n")
+		}
+		if n.omitted {
+			fmt.Fprintf(&b, "_Some notes were omitted to fit the size limit of the PR body, see #%d._\n\n", n.pull.Number)
+		}
+		b.WriteString("</details>\n")
+	}
+	return b.String()
+}
+
+// ecosystemPrefix formats the ecosystem for the release notes summary line
+func ecosystemPrefix(ecosystem string) string {
+	if ecosystem == "" {
+		return ""
+	}
+	return ecosystem + ", "
+}
@@ -0,0 +1,121 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/github/gh-combine/internal/github"
+	"github.com/stretchr/testify/assert"
+)
+
+const dependabotBody = `Bumps [lodash](https://github.com/lodash/lodash) from 4.17.20 to 4.17.21.
+<details>
+<summary>Release notes</summary>
+<p><em>Sourced from <a href="https://github.com/lodash/lodash/releases">lodash's releases</a>.</em></p>
+<blockquote>
+<h2>4.17.21</h2>
+<details>
+<summary>Security fixes</summary>
+<p>Prevent command injection in template.</p>
+</details>
+</blockquote>
+</details>
+<details>
+<summary>Commits</summary>
+<ul>
+<li><a href="https://github.com/lodash/lodash/commit/f299b52"><code>f299b52</code></a> Bump to v4.17.21</li>
+</ul>
+</details>
+<br />
+
+
+[![Dependabot compatibility score](https://dependabot-badges.githubapp.com/badges/compatibility_score?dependency-name=lodash&package-manager=npm_and_yarn&previous-version=4.17.20&new-version=4.17.21)](https://docs.github.com/en/github/managing-security-vulnerabilities/about-dependabot-security-updates#about-compatibility-scores)
+
+Dependabot will resolve any conflicts with this PR as long as you don't alter it yourself.
+
+---
+
+<details>
+<summary>Dependabot commands and options</summary>
+<br />
+
+You can trigger Dependabot actions by commenting on this PR:
+- ` + "`@dependabot rebase`" + ` will rebase this PR
+</details>`
+
+func TestExtractReleaseNotes(t *testing.T) {
+	t.Parallel()
+
+	sections := extractReleaseNotes(dependabotBody)
+
+	summaries := []string{}
+	for _, section := range sections {
+		summaries = append(summaries, section.Summary)
+	}
+	assert.Equal(t, []string{"Compatibility score", "Release notes", "Commits"}, summaries)
+
+	// Nested details blocks stay inside their parent section
+	assert.Contains(t, sections[1].Content, "<summary>Security fixes</summary>")
+	assert.True(t, strings.HasSuffix(sections[1].Content, "</blockquote>\n</details>"))
+	assert.True(t, strings.HasPrefix(sections[0].Content, "[![Dependabot compatibility score]"))
+}
+
+func TestExtractReleaseNotesWithoutSections(t *testing.T) {
+	t.Parallel()
+
+	assert.Empty(t, extractReleaseNotes("Fixes a typo in the README"))
+	assert.Empty(t, extractReleaseNotes("</details> unbalanced"))
+}
+
+func TestGenerateReleaseNotes(t *testing.T) {
+	t.Parallel()
+
+	pulls := github.Pulls{
+		{Number: 1, Title: "Bump lodash from 4.17.20 to 4.17.21", Body: dependabotBody, Head: github.Ref{Ref: "dependabot/npm_and_yarn/lodash-4.17.21"}},
+		{Number: 2, Title: "Fix typo", Body: "<details><summary>Details</summary>not a dependency</details>", Head: github.Ref{Ref: "fix-typo"}},
+	}
+
+	t.Run("fits in the budget", func(t *testing.T) {
+		t.Parallel()
+
+		got := generateReleaseNotes(pulls, maxPRBodyLength)
+		assert.Contains(t, got, "<summary><code>lodash</code> 4.17.20 → 4.17.21 (npm, #1)</summary>")
+		assert.Contains(t, got, "<summary>Release notes</summary>")
+		assert.Contains(t, got, "<summary>Commits</summary>")
+		assert.NotContains(t, got, "Dependabot commands and options")
+		assert.NotContains(t, got, "not a dependency")
+		assert.NotContains(t, got, "omitted")
+	})
+
+	t.Run("drops the commits first", func(t *testing.T) {
+		t.Parallel()
+
+		full := generateReleaseNotes(pulls, maxPRBodyLength)
+		got := generateReleaseNotes(pulls, len(full)-1)
+		assert.Contains(t, got, "<summary>Release notes</summary>")
+		assert.NotContains(t, got, "<summary>Commits</summary>")
+		assert.Contains(t, got, "_Some notes were omitted to fit the size limit of the PR body, see #1._")
+		assert.LessOrEqual(t, len(got), len(full)-1)
+	})
+
+	t.Run("does not fit at all", func(t *testing.T) {
+		t.Parallel()
+
+		assert.Empty(t, generateReleaseNotes(pulls, 10))
+	})
+}
+
+func TestGeneratePRBodyStaysUnderTheSizeLimit(t *testing.T) {
+	// Since this test reads global state, don't use t.Parallel()
+	huge := strings.Replace(dependabotBody, "<h2>4.17.21</h2>", strings.Repeat("<p>A very long release note.</p>\n", 5000), 1)
+
+	var pulls github.Pulls
+	for i := 1; i <= 5; i++ {
+		pulls = append(pulls, github.Pull{Number: i, Title: "Bump lodash from 4.17.20 to 4.17.21", Body: huge, Head: github.Ref{Ref: "dependabot/npm_and_yarn/lodash-4.17.21"}})
+	}
+
+	body := generatePRBody(pulls, nil, nil, nil, "gh combine owner/repo")
+	assert.LessOrEqual(t, len(body), maxPRBodyLength)
+	assert.Contains(t, body, "📝 Release notes:")
+	assert.Contains(t, body, "Command used:")
+}
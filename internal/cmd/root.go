@@ -4,6 +4,9 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 
@@ -21,14 +24,58 @@ var (
 
 	selectLabels []string
 	ignoreLabels []string
+	labelsMode   string
 
 	addLabels    []string
 	addAssignees []string
 
+	updateTypes []string
+	ecosystems  []string
+	ignoreDeps  []string
+
+	filterExpression string
+
+	includePRs []int
+	excludePRs []int
+
+	requireChecks    []string
+	ignoreChecks     []string
+	ciPendingPolicy  string
+	waitForCITimeout time.Duration
+	verifyCI         bool
+	allowNoChecks    bool
+
+	autoMergeMethod string
+	mergeWhenGreen  bool
+
+	commentOnSources bool
+
+	reevaluateMoved bool
+	includeForks    bool
+	allowRecombine  bool
+
+	inheritLabels        bool
+	inheritLabelsInclude []string
+	inheritLabelsExclude []string
+	createLabels         bool
+
+	reviewers        []string
+	inheritReviewers bool
+	milestone        string
+	project          string
+	draft            bool
+
+	commitMode string
+
+	titleTemplate  string
+	bodyTemplate   string
+	noReleaseNotes bool
+
 	requireCI           bool
 	mustBeApproved      bool
 	noAutoclose         bool
 	updateBranch        bool
+	updateMethod        string
 	reposFile           string
 	minimum             int
 	baseBranch          string
@@ -42,6 +89,9 @@ var (
 	dryRun              bool
 )
 
+// safeShellRegex matches values that need no quoting in a shell command
+var safeShellRegex = regexp.MustCompile(`^[A-Za-z0-9_@%+=:,./-]+$`)
+
 // StatsCollector tracks stats for the CLI run
 type StatsCollector struct {
 	ReposProcessed          int
@@ -50,6 +100,7 @@ type StatsCollector struct {
 	PRsSkippedCriteria      int
 	PerRepoStats            map[string]*RepoStats
 	CombinedPRLinks         []string
+	Journal                 *RunJournal
 	StartTime               time.Time
 	EndTime                 time.Time
 }
@@ -62,6 +113,18 @@ type RepoStats struct {
 	CombinedPRLink   string
 	NotEnoughPRs     bool
 	TotalPRs         int
+	InvalidPRs       []string
+	EjectedPRs       []string
+	CIStatus         string
+	MergeStatus      string
+	MergeError       string
+	UnsignedReason   string
+	MetadataErrors   []string
+	UpdateStatus     string
+	MovedPRs         []string
+	ForkPRs          []string
+	AlreadyCombined  []string
+	Plan             *DryRunPlan
 }
 
 // NewRootCmd creates the root command for the gh-combine CLI
@@ -98,24 +161,72 @@ func NewRootCmd() *cobra.Command {
     
       # Filter PRs by labels
       gh combine owner/repo --labels dependencies           # PRs must have this single label
-      gh combine owner/repo --labels security,dependencies  # PRs must have ALL these labels
+      gh combine owner/repo --labels security,dependencies  # PRs must have ANY of these labels
+      gh combine owner/repo --labels security,dependencies --labels-mode all  # PRs must have ALL these labels
+      gh combine owner/repo --labels 'glob:area/*'          # PRs must have a label matching this glob
+      gh combine owner/repo --labels 'regex:^team-(a|b)$'   # PRs must have a label matching this regex
 	  gh combine owner/repo --labels Dependencies --case-sensitive-labels # PRs must have this label, case-sensitive
+
+      # Filter Dependabot and Renovate PRs by the parsed dependency update
+      gh combine owner/repo --dependabot --update-types patch,minor  # Only include patch and minor version bumps
+      gh combine owner/repo --dependabot --ecosystem npm,gomod       # Only include updates from these ecosystems
+      gh combine owner/repo --dependabot --ignore-deps 'react*'      # Ignore updates to dependencies matching this glob
       
+      # Filter PRs with a boolean expression over labels, branch, author, title, age, draft and milestone
+      gh combine owner/repo --filter 'label:dependencies AND (label:security OR author:renovate[bot]) AND NOT label:wip'
+      gh combine owner/repo --filter 'branch:dependabot/* AND age:>7d AND NOT draft'
+
+      # Combine an explicit list of PRs
+      gh combine owner/repo --prs 12,15,19              # Only combine these PRs (bypasses branch, label and dependency filters)
+      gh combine owner/repo --exclude-prs 21,22         # Never combine these PRs
+
       # Exclude PRs by labels
       gh combine owner/repo --ignore-labels wip         # Ignore PRs with this label
       gh combine owner/repo --ignore-labels wip,draft   # Ignore PRs with ANY of these labels
     
       # Set requirements for PRs to be combined
       gh combine owner/repo --require-ci                # Only include PRs with passing CI
+      gh combine owner/repo --require-checks build,test # Only include PRs where these named checks pass
+      gh combine owner/repo --require-ci --ignore-checks 'flaky-*'  # Ignore these checks when evaluating CI
+      gh combine owner/repo --require-ci --allow-no-checks          # Treat PRs without any CI checks as passing
+      gh combine owner/repo --require-ci --ci-pending allow         # Treat pending CI as passing (wait, skip or allow)
+      gh combine owner/repo --require-ci --wait-for-ci 10m          # Wait up to 10 minutes for pending CI to finish
+      gh combine owner/repo --verify-ci                 # Wait for CI on the combined PR and eject the PRs that break it
       gh combine owner/repo --require-approved          # Only include approved PRs
       gh combine owner/repo --minimum 3                 # Need at least 3 matching PRs
     
       # Add metadata to combined PR
       gh combine owner/repo --add-labels security,dependencies   # Add these labels to the new PR
       gh combine owner/repo --add-assignees octocat,hubot        # Assign users to the new PR
+      gh combine owner/repo --inherit-labels                     # Add the labels of the source PRs to the new PR
+      gh combine owner/repo --inherit-labels-exclude 'wip,glob:size/*'  # Inherit the labels of the source PRs except these
+      gh combine owner/repo --inherit-labels --create-labels     # Create inherited labels that do not exist in the repository
+      gh combine owner/repo --reviewers octocat,my-org/my-team   # Request reviews from users and teams
+      gh combine owner/repo --inherit-reviewers                  # Request reviews from the reviewers of the source PRs
+      gh combine owner/repo --milestone v2.0                     # Set the milestone by title or number
+      gh combine owner/repo --project my-org/5                   # Add the new PR to a project (number or owner/number)
+      gh combine owner/repo --draft                              # Open the new PR as a draft
+      gh combine owner/repo --title-template 'chore(deps): combine {{.Count}} dependency updates'  # Use a custom title
+      gh combine owner/repo --body-template @.github/combined-pr.md  # Render the body from a Go text/template file
+      gh combine owner/repo --no-release-notes                   # Do not include the release notes of dependency updates
+
+      # Let the authors of the source PRs know what happened to their PR
+      gh combine owner/repo --comment-on-sources                 # Comment on each source PR whether it was combined or skipped
+      gh combine owner/repo --require-ci --reevaluate-moved      # Re-check PRs that got new commits during the run and combine their new head
+      gh combine owner/repo --include-forks                      # Also combine PRs from forks
+      gh combine owner/repo --allow-recombine                    # Also combine PRs that are part of another open combined PR
+
+      # Merge the combined PR
+      gh combine owner/repo --auto-merge squash                  # Enable auto-merge with this method (merge, squash or rebase)
+      gh combine owner/repo --merge-when-green                   # Wait for CI on the combined PR and merge it once it passes
     
+      # Choose how the source PRs are committed to the combined branch
+      gh combine owner/repo --commit-mode squash-each           # One commit per source PR with Co-authored-by trailers
+      gh combine owner/repo --commit-mode single                # A single commit for all source PRs
+      gh combine owner/repo --commit-mode signed                # A single commit signed by GitHub
+
       # Additional options
-	  gh combine owner/repo --dry-run                           # Simulate the actions without making any changes
+	  gh combine owner/repo --dry-run                           # Predict conflicts with trial merges and print a plan without combining anything
       gh combine owner/repo --no-autoclose                      # Do not auto-close source PRs when combined PR is merged via the closes keyword
 	  gh combine owner/repo --base-branch main                  # Use a different base branch for the combined PR
 	  gh combine owner/repo --no-color                          # Disable color output
@@ -125,8 +236,29 @@ func NewRootCmd() *cobra.Command {
 	  gh combine owner/repo --output table                      # Output stats in table format (default)
 	  gh combine owner/repo --combine-branch-name combined-prs  # Use a different name for the combined PR branch
 	  gh combine owner/repo --working-branch-suffix -working    # Use a different suffix for the working branch
-      gh combine owner/repo --update-branch                     # Update the branch of the combined PR
-	  gh combine --version                                      # Display version information`,
+      gh combine owner/repo --update-branch                     # Update the open combined PR with its base branch instead of recreating it
+      gh combine owner/repo --update-branch --update-method rebase # Rebase the open combined PR on its base branch
+	  gh combine --version                                      # Display version information
+
+      # Close the source PRs of a merged combined PR
+      gh combine finalize owner/repo#42
+
+      # Update the branch of the open combined PR
+      gh combine update owner/repo
+
+      # Save a plan for review and apply it later
+      gh combine plan owner/repo --dependabot --out plan.json
+      gh combine apply plan.json
+
+      # Close the combined PRs and delete the branches of a previous run
+      gh combine undo 20250101-120000-a1b2c3
+
+      # Delete branches left behind by interrupted runs and old combined PRs
+      gh combine cleanup owner/repo --dry-run
+
+      # List the open combined PRs of every repository of an organization
+      gh combine status --org my-org`,
+		Args: cobra.ArbitraryArgs,
 		RunE: runCombine,
 	}
 
@@ -135,20 +267,58 @@ func NewRootCmd() *cobra.Command {
 	rootCmd.Flags().StringVar(&branchSuffix, "branch-suffix", "", "Branch suffix to filter PRs")
 	rootCmd.Flags().StringVar(&branchRegex, "branch-regex", "", "Regex pattern to filter PRs by branch name")
 
-	rootCmd.Flags().StringSliceVar(&selectLabels, "labels", nil, "Only include PRs with ALL these labels (comma-separated)")
-	rootCmd.Flags().StringSliceVar(&ignoreLabels, "ignore-labels", nil, "Ignore PRs with ANY of these labels (comma-separated)")
+	rootCmd.Flags().StringSliceVar(&selectLabels, "labels", nil, "Only include PRs with ANY of these labels, or ALL with --labels-mode all (comma-separated, supports glob:patterns and regex:patterns)")
+	rootCmd.Flags().StringSliceVar(&ignoreLabels, "ignore-labels", nil, "Ignore PRs with ANY of these labels (comma-separated, supports glob:patterns and regex:patterns)")
+	rootCmd.Flags().StringVar(&labelsMode, "labels-mode", labelsModeAny, "How --labels are matched: any or all")
+
+	// Dependency update filters
+	rootCmd.Flags().StringSliceVar(&updateTypes, "update-types", nil, "Only include dependency updates of these types: major, minor, patch (comma-separated)")
+	rootCmd.Flags().StringSliceVar(&ecosystems, "ecosystem", nil, "Only include dependency updates from these ecosystems, e.g. npm, gomod (comma-separated)")
+	rootCmd.Flags().StringSliceVar(&ignoreDeps, "ignore-deps", nil, "Ignore dependency updates whose name matches ANY of these glob patterns (comma-separated)")
+
+	rootCmd.Flags().StringVar(&filterExpression, "filter", "", "Boolean expression to filter PRs, e.g. 'label:a AND (author:b OR NOT title:c)'")
+
+	// Explicit PR selection
+	rootCmd.Flags().IntSliceVar(&includePRs, "prs", nil, "Only combine these PR numbers, bypassing the other filters (comma-separated)")
+	rootCmd.Flags().IntSliceVar(&excludePRs, "exclude-prs", nil, "Never combine these PR numbers (comma-separated)")
 
 	// Labels to add to the combined PR
 	rootCmd.Flags().StringSliceVar(&addLabels, "add-labels", nil, "Comma-separated list of labels to add to the combined PR")
 
 	// Other flags
 	rootCmd.Flags().StringSliceVar(&addAssignees, "add-assignees", nil, "Comma-separated list of users to assign to the combined PR")
+	rootCmd.Flags().BoolVar(&inheritLabels, "inherit-labels", false, "Add the labels of the source PRs to the combined PR")
+	rootCmd.Flags().StringSliceVar(&inheritLabelsInclude, "inherit-labels-include", nil, "Only inherit source PR labels matching ANY of these patterns, implies --inherit-labels (comma-separated, supports glob:patterns and regex:patterns)")
+	rootCmd.Flags().StringSliceVar(&inheritLabelsExclude, "inherit-labels-exclude", nil, "Never inherit source PR labels matching ANY of these patterns, implies --inherit-labels (comma-separated, supports glob:patterns and regex:patterns)")
+	rootCmd.Flags().BoolVar(&createLabels, "create-labels", false, "Create inherited labels that do not exist in the repository instead of leaving them out")
+	rootCmd.Flags().StringSliceVar(&reviewers, "reviewers", nil, "Comma-separated list of users and org/team teams to request reviews from on the combined PR")
+	rootCmd.Flags().BoolVar(&inheritReviewers, "inherit-reviewers", false, "Request reviews on the combined PR from the reviewers requested on the source PRs")
+	rootCmd.Flags().StringVar(&milestone, "milestone", "", "Milestone title or number to set on the combined PR")
+	rootCmd.Flags().StringVar(&project, "project", "", "Project (v2) to add the combined PR to, as a number owned by the repository owner or owner/number")
+	rootCmd.Flags().BoolVar(&draft, "draft", false, "Open the combined PR as a draft")
+	rootCmd.Flags().StringVar(&titleTemplate, "title-template", "", "Go text/template for the combined PR title, inline or @file")
+	rootCmd.Flags().BoolVar(&noReleaseNotes, "no-release-notes", false, "Do not include the release notes of dependency updates in the combined PR body")
+	rootCmd.Flags().StringVar(&bodyTemplate, "body-template", "", "Go text/template for the combined PR body, inline or @file")
 	rootCmd.Flags().BoolVar(&requireCI, "require-ci", false, "Only include PRs with passing CI checks")
+	rootCmd.Flags().StringSliceVar(&requireChecks, "require-checks", nil, "Only include PRs where these named checks pass, implies --require-ci (comma-separated, supports globs)")
+	rootCmd.Flags().StringSliceVar(&ignoreChecks, "ignore-checks", nil, "Ignore these named checks when evaluating CI (comma-separated, supports globs)")
+	rootCmd.Flags().BoolVar(&allowNoChecks, "allow-no-checks", false, "Treat PRs without any CI checks as passing when CI is required")
+	rootCmd.Flags().StringVar(&ciPendingPolicy, "ci-pending", ciPendingSkip, "What to do with PRs whose CI is pending: wait, skip or allow")
+	rootCmd.Flags().BoolVar(&verifyCI, "verify-ci", false, "Wait for CI on the combined PR and bisect failures to eject the offending PRs")
+	rootCmd.Flags().BoolVar(&commentOnSources, "comment-on-sources", false, "Comment on each source PR whether it was included in the combined PR or skipped")
+	rootCmd.Flags().BoolVar(&includeForks, "include-forks", false, "Also combine PRs from forks, which are skipped by default")
+	rootCmd.Flags().BoolVar(&allowRecombine, "allow-recombine", false, "Also combine PRs that are already part of another open combined PR")
+	rootCmd.Flags().BoolVar(&reevaluateMoved, "reevaluate-moved", false, "Re-evaluate PRs that got new commits during the run and combine their new head if it still meets the requirements")
+	rootCmd.Flags().StringVar(&autoMergeMethod, "auto-merge", "", "Enable auto-merge on the combined PR with this merge method: merge, squash or rebase")
+	rootCmd.Flags().BoolVar(&mergeWhenGreen, "merge-when-green", false, "Wait for CI on the combined PR and merge it directly once it passes (fallback when auto-merge is not allowed)")
+	rootCmd.Flags().DurationVar(&waitForCITimeout, "wait-for-ci", 0, "Wait up to this long in total (e.g. 10m) for pending CI checks to finish, implies --ci-pending wait")
 	rootCmd.Flags().BoolVar(&dependabot, "dependabot", false, "Only include PRs with the dependabot branch prefix")
 	rootCmd.Flags().BoolVar(&mustBeApproved, "require-approved", false, "Only include PRs that have been approved")
 	rootCmd.Flags().BoolVar(&noAutoclose, "no-autoclose", false, "Do not auto-close source PRs when combined PR is merged")
-	rootCmd.Flags().BoolVar(&updateBranch, "update-branch", false, "Update the branch of the combined PR if possible")
+	rootCmd.Flags().BoolVar(&updateBranch, "update-branch", false, "Update the branch of the open combined PR with its base branch instead of recreating it, without combining new PRs")
+	rootCmd.Flags().StringVar(&updateMethod, "update-method", updateMethodMerge, "How --update-branch updates the combined PR: merge or rebase")
 	rootCmd.Flags().StringVar(&baseBranch, "base-branch", "main", "Base branch for the combined PR (default: main)")
+	rootCmd.Flags().StringVar(&commitMode, "commit-mode", commitModeMerge, "How source PRs are committed to the combined branch: merge, squash-each, single or signed")
 	rootCmd.Flags().StringVar(&combineBranchName, "combine-branch-name", "combined-prs", "Name of the combined PR branch")
 	rootCmd.Flags().StringVar(&workingBranchSuffix, "working-branch-suffix", "-working", "Suffix of the working branch")
 	rootCmd.Flags().StringVar(&reposFile, "file", "", "File containing repository names, one per line")
@@ -157,11 +327,20 @@ func NewRootCmd() *cobra.Command {
 	rootCmd.Flags().BoolVar(&noColor, "no-color", false, "Disable color output")
 	rootCmd.Flags().BoolVar(&noStats, "no-stats", false, "Disable stats summary display")
 	rootCmd.Flags().StringVar(&outputFormat, "output", "table", "Output format: table, plain, or json")
-	rootCmd.Flags().BoolVar(&dryRun, "dry-run", false, "Simulate the actions without making any changes")
+	rootCmd.Flags().BoolVar(&dryRun, "dry-run", false, "Predict conflicts with trial merges on a scratch branch and print a plan without making any other changes")
 
 	// Add version flag
 	rootCmd.Flags().BoolP("version", "v", false, "Display version information")
 
+	// Add subcommands
+	rootCmd.AddCommand(newFinalizeCmd())
+	rootCmd.AddCommand(newUpdateCmd())
+	rootCmd.AddCommand(newPlanCmd(rootCmd))
+	rootCmd.AddCommand(newApplyCmd())
+	rootCmd.AddCommand(newUndoCmd())
+	rootCmd.AddCommand(newCleanupCmd())
+	rootCmd.AddCommand(newStatusCmd())
+
 	return rootCmd
 }
 
@@ -187,9 +366,7 @@ func runCombine(cmd *cobra.Command, args []string) error {
 
 	Logger.Debug("starting gh-combine", "version", version.String())
 
-	if dependabot && branchPrefix == "" {
-		branchPrefix = "dependabot/"
-	}
+	normalizeInputs()
 
 	// Input validation
 	if err := ValidateInputs(args); err != nil {
@@ -209,10 +386,18 @@ func runCombine(cmd *cobra.Command, args []string) error {
 		return errors.New("no repositories specified")
 	}
 
+	if len(includePRs) > 0 && len(repos) > 1 {
+		return errPRsMultipleRepos
+	}
+
+	resetPRCIDeadline()
 	stats := &StatsCollector{
 		PerRepoStats: make(map[string]*RepoStats),
 		StartTime:    time.Now(),
 	}
+	if !dryRun {
+		stats.Journal = newRunJournal()
+	}
 
 	// Execute combination logic
 	if err := executeCombineCommand(ctx, spinner, repos, stats); err != nil {
@@ -220,14 +405,33 @@ func runCombine(cmd *cobra.Command, args []string) error {
 	}
 	stats.EndTime = time.Now()
 
+	spinner.Stop()
 	if !noStats {
-		spinner.Stop()
 		displayStatsSummary(stats, outputFormat)
 	}
+	// The JSON summary already carries the plans, anywhere else they are printed even without stats
+	if noStats || outputFormat != "json" {
+		displayDryRunPlans(stats)
+	}
 
 	return nil
 }
 
+// normalizeInputs applies the flags that imply other flags
+func normalizeInputs() {
+	if dependabot && branchPrefix == "" {
+		branchPrefix = "dependabot/"
+	}
+
+	if len(inheritLabelsInclude) > 0 || len(inheritLabelsExclude) > 0 {
+		inheritLabels = true
+	}
+
+	if waitForCITimeout > 0 && ciPendingPolicy == ciPendingSkip {
+		ciPendingPolicy = ciPendingWait
+	}
+}
+
 // executeCombineCommand performs the actual API calls and processing
 func executeCombineCommand(ctx context.Context, spinner *Spinner, repos []github.Repo, stats *StatsCollector) error {
 	// Create GitHub API client
@@ -300,30 +504,107 @@ func processRepository(ctx context.Context, client *api.RESTClient, graphQlClien
 		// Continue processing
 	}
 
+	// Wrap the *api.RESTClient to implement RESTClientInterface
+	restClientWrapper := struct {
+		RESTClientInterface
+	}{client}
+
+	// With --update-branch, an open combined PR is brought up to date instead of being recreated.
+	// This short-circuits the repository: no new PRs are combined until the combined PR is merged or closed
+	if updateBranch {
+		if combinedPR := findCombinedPR(pulls, repo); combinedPR != nil {
+			status, err := updateCombinedPR(ctx, graphQlClient, restClientWrapper, repo, *combinedPR)
+			if err != nil {
+				return err
+			}
+			repoStats.UpdateStatus = status
+			repoStats.CombinedPRLink = fmt.Sprintf("https://github.com/%s/%s/pull/%d", repo.Owner, repo.Repo, combinedPR.Number)
+			stats.CombinedPRLinks = append(stats.CombinedPRLinks, repoStats.CombinedPRLink)
+			return nil
+		}
+	}
+
+	matchedPRs, ciSkipped, err := selectPullRequests(ctx, restClientWrapper, graphQlClient, spinner, repo, pulls, repoStats, stats)
+	if err != nil {
+		return err
+	}
+
+	// Check if we have enough PRs to combine
+	if len(matchedPRs) < minimum {
+		Logger.Debug("Not enough PRs match criteria", "repo", repo, "matched", len(matchedPRs), "required", minimum)
+		repoStats.NotEnoughPRs = true
+		return nil
+	}
+
+	Logger.Debug("Matched PRs", "repo", repo, "count", len(matchedPRs))
+
+	commandString := buildCommandString([]string{repo.String()})
+
+	return combineSelectedPRs(ctx, graphQlClient, restClientWrapper, repo, matchedPRs, ciSkipped, commandString, repoStats, stats)
+}
+
+// selectPullRequests narrows the open PRs of a repository down to the ones to combine. The PRs that were
+// skipped because of their CI are returned as well with the unmet requirement, so they can be told why they were left out
+func selectPullRequests(ctx context.Context, restClient RESTClientInterface, graphQlClient *api.GraphQLClient, spinner *Spinner, repo github.Repo, pulls github.Pulls, repoStats *RepoStats, stats *StatsCollector) (github.Pulls, map[int]string, error) {
+	combinedIn := combinedInOtherPRs(repo, pulls)
+
+	// Narrow the PRs down to an explicit list if one was provided
+	explicit := len(includePRs) > 0
+	if explicit {
+		selected, invalid, err := selectExplicitPullRequests(ctx, restClient, repo, pulls, includePRs)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to select pull requests: %w", err)
+		}
+		for _, pr := range invalid {
+			Logger.Warn("Listed PR cannot be combined", "repo", repo, "pr", pr)
+		}
+		repoStats.InvalidPRs = invalid
+		pulls = selected
+	}
+
 	// Filter PRs based on criteria
 	var matchedPRs github.Pulls
+	ciSkipped := map[int]string{}
 	for _, pull := range pulls {
-		// Extract labels
-		labels := []string{}
-		for _, label := range pull.Labels {
-			labels = append(labels, label.Name)
+		if slices.Contains(excludePRs, pull.Number) {
+			Logger.Debug("PR is excluded", "repo", repo, "pr", pull.Number)
+			repoStats.SkippedCriteria++
+			stats.PRsSkippedCriteria++
+			continue
 		}
 
-		// Check if PR matches all filtering criteria
-		if !PrMatchesCriteria(pull.Head.Ref, labels) {
+		// Explicitly listed PRs bypass the branch, label, dependency and --filter criteria
+		if !explicit && !PrMatchesCriteria(pull) {
 			repoStats.SkippedCriteria++
 			stats.PRsSkippedCriteria++
 			continue
 		}
 
+		// PRs from forks are only combined when opted in, since their code comes from outside the repository
+		if !includeForks && pull.FromFork(repo) {
+			Logger.Debug("PR is from a fork", "repo", repo, "pr", pull.Number)
+			repoStats.ForkPRs = append(repoStats.ForkPRs, fmt.Sprintf("#%d", pull.Number))
+			continue
+		}
+
+		// Combining a PR twice makes the combined PRs conflict with each other once one of them merges
+		if combinedPR, ok := combinedIn[pull.Number]; ok && !allowRecombine {
+			Logger.Debug("PR is already combined", "repo", repo, "pr", pull.Number, "combinedPR", combinedPR)
+			repoStats.AlreadyCombined = append(repoStats.AlreadyCombined, fmt.Sprintf("#%d (in #%d)", pull.Number, combinedPR))
+			continue
+		}
+
 		// Check if PR meets additional requirements (CI, approval)
-		meetsRequirements, err := PrMeetsRequirements(ctx, graphQlClient, repo.Owner, repo.Repo, pull.Number)
+		unmet, err := unmetRequirement(ctx, graphQlClient, spinner, repo.Owner, repo.Repo, pull.Number)
 		if err != nil {
 			Logger.Warn("Failed to check PR requirements", "repo", repo, "pr", pull.Number, "error", err)
 			continue
 		}
 
-		if !meetsRequirements {
+		if unmet != "" {
+			if unmet != requirementNotApproved {
+				ciSkipped[pull.Number] = unmet
+			}
 			repoStats.SkippedCriteria++
 			stats.PRsSkippedCriteria++
 			continue
@@ -332,41 +613,33 @@ func processRepository(ctx context.Context, client *api.RESTClient, graphQlClien
 		matchedPRs = append(matchedPRs, pull)
 	}
 
-	// Check if we have enough PRs to combine
-	if len(matchedPRs) < minimum {
-		Logger.Debug("Not enough PRs match criteria", "repo", repo, "matched", len(matchedPRs), "required", minimum)
-		repoStats.NotEnoughPRs = true
-		return nil
-	}
-
-	Logger.Debug("Matched PRs", "repo", repo, "count", len(matchedPRs))
-
-	// Wrap the *api.RESTClient to implement RESTClientInterface
-	restClientWrapper := struct {
-		RESTClientInterface
-	}{client}
-
-	commandString := buildCommandString([]string{repo.String()})
+	return matchedPRs, ciSkipped, nil
+}
 
+// combineSelectedPRs combines the selected PRs of a repository and records the outcome in the stats
+func combineSelectedPRs(ctx context.Context, graphQlClient *api.GraphQLClient, restClient RESTClientInterface, repo github.Repo, matchedPRs github.Pulls, ciSkipped map[int]string, commandString string, repoStats *RepoStats, stats *StatsCollector) error {
 	opts := CombineOpts{
-		Noop:    dryRun,
-		Command: commandString,
-		Repo:    repo,
-		Pulls:   matchedPRs,
+		Noop:     dryRun,
+		VerifyCI: verifyCI,
+		Command:  commandString,
+		Repo:     repo,
+		Pulls:    matchedPRs,
+		Journal:  stats.Journal,
 	}
 
-	combined, mergeConflicts, combinedPRLink, err := CombinePRsWithStats(ctx, graphQlClient, restClientWrapper, opts)
+	result, err := CombinePRsWithStats(ctx, graphQlClient, restClient, opts)
 	if err != nil {
+		// A combine can fail after the combined PR was created, which still has to show up in the summary
+		if result != nil && result.PRNumber > 0 {
+			recordCombineResult(result, repoStats, stats)
+		}
 		return fmt.Errorf("failed to combine PRs: %w", err)
 	}
 
-	repoStats.CombinedCount = len(combined)
-	repoStats.SkippedMergeConf = len(mergeConflicts)
-	repoStats.CombinedPRLink = combinedPRLink
-	stats.PRsCombined += len(combined)
-	stats.PRsSkippedMergeConflict += len(mergeConflicts)
-	if combinedPRLink != "" {
-		stats.CombinedPRLinks = append(stats.CombinedPRLinks, combinedPRLink)
+	recordCombineResult(result, repoStats, stats)
+
+	if commentOnSources && !dryRun {
+		commentOnSourcePRs(ctx, restClient, repo, result, ciSkipped)
 	}
 
 	Logger.Debug("Combined PRs", "count", len(matchedPRs), "owner", repo.Owner, "repo", repo.Repo)
@@ -374,6 +647,28 @@ func processRepository(ctx context.Context, client *api.RESTClient, graphQlClien
 	return nil
 }
 
+// recordCombineResult records the outcome of combining the PRs of a repository in the stats
+func recordCombineResult(result *CombineResult, repoStats *RepoStats, stats *StatsCollector) {
+	repoStats.CombinedCount = len(result.Combined)
+	repoStats.SkippedMergeConf = len(result.MergeConflicts)
+	repoStats.CombinedPRLink = result.PRLink
+	stats.PRsCombined += len(result.Combined)
+	stats.PRsSkippedMergeConflict += len(result.MergeConflicts)
+	if result.PRLink != "" {
+		stats.CombinedPRLinks = append(stats.CombinedPRLinks, result.PRLink)
+	}
+	for _, pr := range result.Ejected {
+		repoStats.EjectedPRs = append(repoStats.EjectedPRs, fmt.Sprintf("#%d", pr.Number))
+	}
+	repoStats.CIStatus = result.CIStatus
+	repoStats.MergeStatus = result.MergeStatus
+	repoStats.MergeError = result.MergeError
+	repoStats.UnsignedReason = result.UnsignedReason
+	repoStats.MetadataErrors = result.MetadataErrors
+	repoStats.MovedPRs = result.Moved
+	repoStats.Plan = result.Plan
+}
+
 // fetchOpenPullRequests fetches all open pull requests for a repository, handling pagination
 func fetchOpenPullRequests(ctx context.Context, client *api.RESTClient, repo github.Repo) (github.Pulls, error) {
 	var allPulls github.Pulls
@@ -429,33 +724,130 @@ func displayStatsSummary(stats *StatsCollector, outputFormat string) {
 // buildCommandString reconstructs the CLI command with all set flags and arguments
 func buildCommandString(args []string) string {
 	cmd := []string{"gh combine"}
-	cmd = append(cmd, args...)
+	for _, arg := range args {
+		cmd = append(cmd, shellQuote(arg))
+	}
 
 	// Only add branch-prefix if it's not due to the dependabot flag
 	if branchPrefix != "" && (!dependabot || branchPrefix != "dependabot/") {
-		cmd = append(cmd, "--branch-prefix", branchPrefix)
+		cmd = append(cmd, "--branch-prefix", shellQuote(branchPrefix))
 	}
 	if branchSuffix != "" {
-		cmd = append(cmd, "--branch-suffix", branchSuffix)
+		cmd = append(cmd, "--branch-suffix", shellQuote(branchSuffix))
 	}
 	if branchRegex != "" {
-		cmd = append(cmd, "--branch-regex", branchRegex)
+		cmd = append(cmd, "--branch-regex", shellQuote(branchRegex))
 	}
 	if len(selectLabels) > 0 {
-		cmd = append(cmd, "--labels", strings.Join(selectLabels, ","))
+		cmd = append(cmd, "--labels", shellQuote(strings.Join(selectLabels, ",")))
 	}
 	if len(ignoreLabels) > 0 {
-		cmd = append(cmd, "--ignore-labels", strings.Join(ignoreLabels, ","))
+		cmd = append(cmd, "--ignore-labels", shellQuote(strings.Join(ignoreLabels, ",")))
+	}
+	if labelsMode != labelsModeAny && labelsMode != "" {
+		cmd = append(cmd, "--labels-mode", labelsMode)
+	}
+	if len(updateTypes) > 0 {
+		cmd = append(cmd, "--update-types", shellQuote(strings.Join(updateTypes, ",")))
+	}
+	if len(ecosystems) > 0 {
+		cmd = append(cmd, "--ecosystem", shellQuote(strings.Join(ecosystems, ",")))
+	}
+	if len(ignoreDeps) > 0 {
+		cmd = append(cmd, "--ignore-deps", shellQuote(strings.Join(ignoreDeps, ",")))
+	}
+	if filterExpression != "" {
+		cmd = append(cmd, "--filter", shellQuote(filterExpression))
+	}
+	if len(includePRs) > 0 {
+		cmd = append(cmd, "--prs", joinInts(includePRs))
+	}
+	if len(excludePRs) > 0 {
+		cmd = append(cmd, "--exclude-prs", joinInts(excludePRs))
 	}
 	if len(addLabels) > 0 {
-		cmd = append(cmd, "--add-labels", strings.Join(addLabels, ","))
+		cmd = append(cmd, "--add-labels", shellQuote(strings.Join(addLabels, ",")))
 	}
 	if len(addAssignees) > 0 {
-		cmd = append(cmd, "--add-assignees", strings.Join(addAssignees, ","))
+		cmd = append(cmd, "--add-assignees", shellQuote(strings.Join(addAssignees, ",")))
+	}
+	// Only add inherit-labels if it's not implied by the include or exclude patterns
+	if inheritLabels && len(inheritLabelsInclude) == 0 && len(inheritLabelsExclude) == 0 {
+		cmd = append(cmd, "--inherit-labels")
+	}
+	if len(inheritLabelsInclude) > 0 {
+		cmd = append(cmd, "--inherit-labels-include", shellQuote(strings.Join(inheritLabelsInclude, ",")))
+	}
+	if len(inheritLabelsExclude) > 0 {
+		cmd = append(cmd, "--inherit-labels-exclude", shellQuote(strings.Join(inheritLabelsExclude, ",")))
+	}
+	if createLabels {
+		cmd = append(cmd, "--create-labels")
+	}
+	if len(reviewers) > 0 {
+		cmd = append(cmd, "--reviewers", shellQuote(strings.Join(reviewers, ",")))
+	}
+	if inheritReviewers {
+		cmd = append(cmd, "--inherit-reviewers")
+	}
+	if milestone != "" {
+		cmd = append(cmd, "--milestone", shellQuote(milestone))
+	}
+	if project != "" {
+		cmd = append(cmd, "--project", shellQuote(project))
+	}
+	if draft {
+		cmd = append(cmd, "--draft")
 	}
 	if requireCI {
 		cmd = append(cmd, "--require-ci")
 	}
+	if len(requireChecks) > 0 {
+		cmd = append(cmd, "--require-checks", shellQuote(strings.Join(requireChecks, ",")))
+	}
+	if len(ignoreChecks) > 0 {
+		cmd = append(cmd, "--ignore-checks", shellQuote(strings.Join(ignoreChecks, ",")))
+	}
+	if allowNoChecks {
+		cmd = append(cmd, "--allow-no-checks")
+	}
+	// Only add ci-pending if it's not due to the wait-for-ci flag
+	if ciPendingPolicy != ciPendingSkip && ciPendingPolicy != "" && (waitForCITimeout == 0 || ciPendingPolicy != ciPendingWait) {
+		cmd = append(cmd, "--ci-pending", ciPendingPolicy)
+	}
+	if waitForCITimeout > 0 {
+		cmd = append(cmd, "--wait-for-ci", waitForCITimeout.String())
+	}
+	if verifyCI {
+		cmd = append(cmd, "--verify-ci")
+	}
+	if autoMergeMethod != "" {
+		cmd = append(cmd, "--auto-merge", autoMergeMethod)
+	}
+	if mergeWhenGreen {
+		cmd = append(cmd, "--merge-when-green")
+	}
+	if commentOnSources {
+		cmd = append(cmd, "--comment-on-sources")
+	}
+	if reevaluateMoved {
+		cmd = append(cmd, "--reevaluate-moved")
+	}
+	if includeForks {
+		cmd = append(cmd, "--include-forks")
+	}
+	if allowRecombine {
+		cmd = append(cmd, "--allow-recombine")
+	}
+	if titleTemplate != "" {
+		cmd = append(cmd, "--title-template", shellQuote(titleTemplate))
+	}
+	if bodyTemplate != "" {
+		cmd = append(cmd, "--body-template", shellQuote(bodyTemplate))
+	}
+	if noReleaseNotes {
+		cmd = append(cmd, "--no-release-notes")
+	}
 	if dependabot {
 		cmd = append(cmd, "--dependabot")
 	}
@@ -468,17 +860,23 @@ func buildCommandString(args []string) string {
 	if updateBranch {
 		cmd = append(cmd, "--update-branch")
 	}
+	if updateMethod != updateMethodMerge && updateMethod != "" {
+		cmd = append(cmd, "--update-method", updateMethod)
+	}
 	if baseBranch != "main" && baseBranch != "" {
-		cmd = append(cmd, "--base-branch", baseBranch)
+		cmd = append(cmd, "--base-branch", shellQuote(baseBranch))
+	}
+	if commitMode != commitModeMerge && commitMode != "" {
+		cmd = append(cmd, "--commit-mode", commitMode)
 	}
 	if combineBranchName != "combined-prs" && combineBranchName != "" {
-		cmd = append(cmd, "--combine-branch-name", combineBranchName)
+		cmd = append(cmd, "--combine-branch-name", shellQuote(combineBranchName))
 	}
 	if workingBranchSuffix != "-working" && workingBranchSuffix != "" {
-		cmd = append(cmd, "--working-branch-suffix", workingBranchSuffix)
+		cmd = append(cmd, "--working-branch-suffix", shellQuote(workingBranchSuffix))
 	}
 	if reposFile != "" {
-		cmd = append(cmd, "--file", reposFile)
+		cmd = append(cmd, "--file", shellQuote(reposFile))
 	}
 	if minimum != 2 {
 		cmd = append(cmd, "--minimum", fmt.Sprintf("%d", minimum))
@@ -501,3 +899,21 @@ func buildCommandString(args []string) string {
 
 	return strings.Join(cmd, " ")
 }
+
+// shellQuote quotes a value so the command can be pasted into a POSIX shell. Values made of safe characters are
+// left as they are, others are single-quoted since nothing is expanded within single quotes
+func shellQuote(value string) string {
+	if value != "" && safeShellRegex.MatchString(value) {
+		return value
+	}
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
+
+// joinInts joins integers into a comma-separated string
+func joinInts(values []int) string {
+	parts := make([]string, len(values))
+	for i, v := range values {
+		parts[i] = strconv.Itoa(v)
+	}
+	return strings.Join(parts, ",")
+}
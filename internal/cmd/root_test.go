@@ -0,0 +1,116 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/github/gh-combine/internal/github"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShellQuote(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{value: "dependencies", want: "dependencies"},
+		{value: "v1.2", want: "v1.2"},
+		{value: "", want: "''"},
+		{value: "label:a AND NOT label:b", want: "'label:a AND NOT label:b'"},
+		{value: "Bump $DEP `now`!", want: "'Bump $DEP `now`!'"},
+		{value: "it's", want: `'it'\''s'`},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.want, shellQuote(test.value), test.value)
+	}
+}
+
+func TestBuildCommandStringQuotesValues(t *testing.T) {
+	// Since this test changes global state, don't use t.Parallel()
+	origIgnoreDeps, origSelectLabels, origBranchRegex := ignoreDeps, selectLabels, branchRegex
+	origRequireChecks, origIgnoreChecks := requireChecks, ignoreChecks
+	origInclude, origExclude := inheritLabelsInclude, inheritLabelsExclude
+	defer func() {
+		ignoreDeps, selectLabels, branchRegex = origIgnoreDeps, origSelectLabels, origBranchRegex
+		requireChecks, ignoreChecks = origRequireChecks, origIgnoreChecks
+		inheritLabelsInclude, inheritLabelsExclude = origInclude, origExclude
+	}()
+
+	ignoreDeps = []string{"react*"}
+	selectLabels = []string{"glob:deps/*"}
+	branchRegex = "^(deps|chore)/"
+	requireChecks = []string{"build (*)"}
+	ignoreChecks = []string{"lint"}
+	inheritLabelsInclude = []string{"size/?"}
+	inheritLabelsExclude = []string{"wip"}
+
+	got := buildCommandString([]string{"owner/repo"})
+
+	assert.Contains(t, got, "gh combine owner/repo ")
+	assert.Contains(t, got, "--ignore-deps 'react*'")
+	assert.Contains(t, got, "--labels 'glob:deps/*'")
+	assert.Contains(t, got, "--branch-regex '^(deps|chore)/'")
+	assert.Contains(t, got, "--require-checks 'build (*)'")
+	assert.Contains(t, got, "--ignore-checks lint")
+	assert.Contains(t, got, "--inherit-labels-include 'size/?'")
+	assert.Contains(t, got, "--inherit-labels-exclude wip")
+}
+
+func TestCombineSelectedPRsRecordsStatsOnError(t *testing.T) {
+	// Since this test changes global state, don't use t.Parallel()
+	origVerifyCI, origDryRun, origCommentOnSources := verifyCI, dryRun, commentOnSources
+	origAssignees := addAssignees
+	defer func() {
+		verifyCI, dryRun, commentOnSources = origVerifyCI, origDryRun, origCommentOnSources
+		addAssignees = origAssignees
+	}()
+	verifyCI = true
+	dryRun = false
+	commentOnSources = false
+	addAssignees = []string{"octocat"}
+
+	// The run is cancelled right after the combined PR is created, so verifying its CI fails
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	client := &MockRESTClient{
+		GetFunc: func(endpoint string, response interface{}) error {
+			if endpoint == "repos/owner/repo" {
+				return json.Unmarshal([]byte(`{"default_branch": "main"}`), response)
+			}
+			return json.Unmarshal([]byte(`{"object": {"sha": "base"}}`), response)
+		},
+		PostFunc: func(endpoint string, body interface{}, response interface{}) error {
+			switch {
+			case strings.HasSuffix(endpoint, "/merges"):
+				return json.Unmarshal([]byte(`{"sha": "merge", "commit": {"tree": {"sha": "tree"}}}`), response)
+			case strings.HasSuffix(endpoint, "/pulls"):
+				cancel()
+				return json.Unmarshal([]byte(`{"number": 7}`), response)
+			case strings.HasSuffix(endpoint, "/assignees"):
+				return errors.New("HTTP 422")
+			}
+			return nil
+		},
+	}
+
+	repo := github.Repo{Owner: "owner", Repo: "repo"}
+	pulls := github.Pulls{{Number: 1, Head: github.Ref{Ref: "feature-1", Repo: &github.RefRepo{FullName: "owner/repo"}}}}
+	repoStats := &RepoStats{RepoName: repo.String()}
+	stats := &StatsCollector{}
+
+	err := combineSelectedPRs(ctx, nil, client, repo, pulls, nil, "gh combine owner/repo", repoStats, stats)
+
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, "https://github.com/owner/repo/pull/7", repoStats.CombinedPRLink)
+	assert.Equal(t, 1, repoStats.CombinedCount)
+	assert.Equal(t, 1, stats.PRsCombined)
+	assert.Equal(t, []string{"https://github.com/owner/repo/pull/7"}, stats.CombinedPRLinks)
+	assert.Equal(t, []string{"assignees: HTTP 422"}, repoStats.MetadataErrors)
+}
@@ -0,0 +1,168 @@
+package cmd
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/cli/go-gh/v2/pkg/config"
+
+	"github.com/github/gh-combine/internal/github"
+)
+
+var (
+	errInvalidRunID = errors.New("invalid run ID")
+
+	// runIDRegex matches the IDs generated by newRunJournal
+	runIDRegex = regexp.MustCompile(`^\d{8}-\d{6}-[0-9a-f]{6}$`)
+
+	// sampleRunID has the length of the IDs generated by newRunJournal
+	sampleRunID = "20060102-150405-000000"
+)
+
+// RunJournal records the changes a run made to every repository, so the run can be undone later
+type RunJournal struct {
+	ID        string    `json:"id"`
+	CreatedAt time.Time `json:"createdAt"`
+	Repos     []RepoRun `json:"repos"`
+}
+
+// RepoRun is what a run changed in a repository
+type RepoRun struct {
+	Repo string `json:"repo"`
+	// CreatedBranches are the branches created by the run that still exist
+	CreatedBranches []string `json:"createdBranches,omitempty"`
+	// DeletedBranches are branches left over from a previous run that were replaced
+	DeletedBranches []string `json:"deletedBranches,omitempty"`
+	PRNumber        int      `json:"prNumber,omitempty"`
+}
+
+// newRunJournal creates a journal with a new run ID made of the time and a random suffix
+func newRunJournal() *RunJournal {
+	suffix := make([]byte, 3)
+	_, _ = rand.Read(suffix)
+	now := time.Now().UTC()
+	return &RunJournal{
+		ID:        now.Format("20060102-150405") + "-" + hex.EncodeToString(suffix),
+		CreatedAt: now,
+	}
+}
+
+// runID returns the ID of the run, or an empty string if the journal is nil
+func (j *RunJournal) runID() string {
+	if j == nil {
+		return ""
+	}
+	return j.ID
+}
+
+// record adds the changes made to a repository to the journal and saves it, so a run that is interrupted
+// can still be undone. Repositories where nothing was left to undo are not recorded
+func (j *RunJournal) record(run RepoRun) {
+	if j == nil || (len(run.CreatedBranches) == 0 && run.PRNumber == 0) {
+		return
+	}
+	j.Repos = append(j.Repos, run)
+	if err := j.save(); err != nil {
+		Logger.Warn("Failed to save run journal", "run", j.ID, "error", err)
+	}
+}
+
+// save writes the journal to the state directory of gh
+func (j *RunJournal) save() error {
+	path, err := runJournalPath(j.ID)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create run directory: %w", err)
+	}
+
+	data, err := json.MarshalIndent(j, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode run journal: %w", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("failed to write run journal: %w", err)
+	}
+	return nil
+}
+
+// loadRunJournal reads the journal of a previous run
+func loadRunJournal(id string) (*RunJournal, error) {
+	path, err := runJournalPath(id)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("no run with ID %s was recorded", id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read run journal: %w", err)
+	}
+
+	var journal RunJournal
+	if err := json.Unmarshal(data, &journal); err != nil {
+		return nil, fmt.Errorf("failed to parse run journal: %w", err)
+	}
+	return &journal, nil
+}
+
+// runJournalPath returns where the journal of a run is stored
+func runJournalPath(id string) (string, error) {
+	if !runIDRegex.MatchString(id) {
+		return "", fmt.Errorf("%w: %q", errInvalidRunID, id)
+	}
+	return filepath.Join(config.StateDir(), "gh-combine", "runs", id+".json"), nil
+}
+
+// runMarker returns a hidden marker identifying the run that created a combined PR
+func runMarker(id string) string {
+	return fmt.Sprintf("<!-- gh-combine:run=%s -->", id)
+}
+
+// rollbackCombine undoes a combine that failed. Branches created before the combined PR exists are deleted,
+// while a combined PR and its branch are kept once created. The returned error describes the state that remains
+func rollbackCombine(ctx context.Context, restClient RESTClientInterface, repo github.Repo, run *RepoRun, err error) error {
+	var remaining []string
+	if run.PRNumber > 0 {
+		remaining = append(remaining, fmt.Sprintf("combined PR #%d and branch %s remain", run.PRNumber, strings.Join(run.CreatedBranches, ", ")))
+	} else {
+		var kept, deleted []string
+		for _, branch := range run.CreatedBranches {
+			if delErr := deleteBranch(ctx, restClient, repo, branch); delErr != nil {
+				Logger.Warn("Failed to roll back branch", "repo", repo, "branch", branch, "error", delErr)
+				kept = append(kept, branch)
+				continue
+			}
+			Logger.Debug("Rolled back branch", "repo", repo, "branch", branch)
+			deleted = append(deleted, branch)
+		}
+		run.CreatedBranches = kept
+
+		if len(deleted) > 0 {
+			remaining = append(remaining, "rolled back branch "+strings.Join(deleted, ", "))
+		}
+		if len(kept) > 0 {
+			remaining = append(remaining, "branch "+strings.Join(kept, ", ")+" remains")
+		}
+	}
+	if len(run.DeletedBranches) > 0 {
+		remaining = append(remaining, "branch "+strings.Join(run.DeletedBranches, ", ")+" from a previous run was deleted")
+	}
+
+	if len(remaining) == 0 {
+		return err
+	}
+	return fmt.Errorf("%w (%s)", err, strings.Join(remaining, "; "))
+}
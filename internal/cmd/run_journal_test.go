@@ -0,0 +1,149 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/github/gh-combine/internal/github"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunJournalRoundTrip(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+
+	journal := newRunJournal()
+	assert.Regexp(t, runIDRegex, journal.ID)
+
+	// Repositories where nothing was left behind are not recorded
+	journal.record(RepoRun{Repo: "owner/unchanged"})
+	journal.record(RepoRun{Repo: "owner/repo", CreatedBranches: []string{"combined-prs"}, DeletedBranches: []string{"combined-prs"}, PRNumber: 42})
+
+	loaded, err := loadRunJournal(journal.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, journal.ID, loaded.ID)
+	assert.Equal(t, []RepoRun{{Repo: "owner/repo", CreatedBranches: []string{"combined-prs"}, DeletedBranches: []string{"combined-prs"}, PRNumber: 42}}, loaded.Repos)
+
+	_, err = loadRunJournal("20250101-120000-abcdef")
+	assert.ErrorContains(t, err, "no run with ID 20250101-120000-abcdef")
+}
+
+func TestLoadRunJournalRejectsInvalidIDs(t *testing.T) {
+	t.Parallel()
+
+	for _, id := range []string{"", "../../etc/passwd", "20250101-120000"} {
+		_, err := loadRunJournal(id)
+		assert.ErrorIs(t, err, errInvalidRunID, id)
+	}
+}
+
+func TestRunMarkerInBody(t *testing.T) {
+	// Since this test reads global state, don't use t.Parallel()
+	data := newPRTemplateData(github.Repo{Owner: "owner", Repo: "repo"}, github.Pulls{{Number: 1}}, nil, nil, nil, "gh combine owner/repo")
+
+	_, body, err := renderPRTitleAndBody(data)
+	assert.NoError(t, err)
+	assert.NotContains(t, body, "gh-combine:run=")
+
+	data.RunID = "20250101-120000-abcdef"
+	_, body, err = renderPRTitleAndBody(data)
+	assert.NoError(t, err)
+	assert.True(t, strings.HasSuffix(body, "\n<!-- gh-combine:run=20250101-120000-abcdef -->"))
+}
+
+func TestCombinePRsWithStatsRollsBack(t *testing.T) {
+	// Since this test changes global state, don't use t.Parallel()
+	origBranch, origSuffix := combineBranchName, workingBranchSuffix
+	defer func() { combineBranchName, workingBranchSuffix = origBranch, origSuffix }()
+	combineBranchName = "combined-prs"
+	workingBranchSuffix = "-working"
+
+	tests := []struct {
+		name        string
+		failPR      bool
+		failDelete  bool
+		wantDeleted []string
+		wantError   string
+		wantRun     RepoRun
+	}{
+		{
+			name:        "PR creation fails",
+			failPR:      true,
+			wantDeleted: []string{"combined-prs-working", "combined-prs", "combined-prs-working", "combined-prs"},
+			wantError:   "failed to create combined PR: failed to create pull request: HTTP 422 (rolled back branch combined-prs; branch combined-prs from a previous run was deleted)",
+			wantRun:     RepoRun{Repo: "owner/repo", DeletedBranches: []string{"combined-prs"}},
+		},
+		{
+			name:        "PR creation and rollback fail",
+			failPR:      true,
+			failDelete:  true,
+			wantDeleted: []string{"combined-prs-working", "combined-prs", "combined-prs-working", "combined-prs"},
+			wantError:   "failed to create combined PR: failed to create pull request: HTTP 422 (branch combined-prs remains; branch combined-prs from a previous run was deleted)",
+			wantRun:     RepoRun{Repo: "owner/repo", CreatedBranches: []string{"combined-prs"}, DeletedBranches: []string{"combined-prs"}},
+		},
+		{
+			name:        "PR is created",
+			wantDeleted: []string{"combined-prs-working", "combined-prs", "combined-prs-working"},
+			wantRun:     RepoRun{Repo: "owner/repo", CreatedBranches: []string{"combined-prs"}, DeletedBranches: []string{"combined-prs"}, PRNumber: 7},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var deleted []string
+			client := &MockRESTClient{
+				GetFunc: func(endpoint string, response interface{}) error {
+					if endpoint == "repos/owner/repo" {
+						return json.Unmarshal([]byte(`{"default_branch": "main"}`), response)
+					}
+					return json.Unmarshal([]byte(`{"object": {"sha": "base"}}`), response)
+				},
+				PostFunc: func(endpoint string, body interface{}, response interface{}) error {
+					switch {
+					case strings.HasSuffix(endpoint, "/merges"):
+						return json.Unmarshal([]byte(`{"sha": "merge", "commit": {"tree": {"sha": "tree"}}}`), response)
+					case strings.HasSuffix(endpoint, "/pulls"):
+						if test.failPR {
+							return errors.New("HTTP 422")
+						}
+						return json.Unmarshal([]byte(`{"number": 7}`), response)
+					}
+					return nil
+				},
+				DeleteFunc: func(endpoint string, response interface{}) error {
+					branch := strings.TrimPrefix(endpoint, "repos/owner/repo/git/refs/heads/")
+					deleted = append(deleted, branch)
+					// The first deletions remove the branches left over from a previous run
+					if test.failDelete && len(deleted) > 2 {
+						return errors.New("HTTP 500")
+					}
+					return nil
+				},
+			}
+
+			journal := &RunJournal{ID: "20250101-120000-abcdef"}
+			t.Setenv("XDG_STATE_HOME", t.TempDir())
+			opts := CombineOpts{
+				Repo:    github.Repo{Owner: "owner", Repo: "repo"},
+				Pulls:   github.Pulls{{Number: 1, Head: github.Ref{Ref: "feature-1", Repo: &github.RefRepo{FullName: "owner/repo"}}}},
+				Journal: journal,
+			}
+
+			_, err := CombinePRsWithStats(context.Background(), nil, client, opts)
+			if test.wantError != "" {
+				assert.EqualError(t, err, test.wantError)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, test.wantDeleted, deleted)
+
+			if len(test.wantRun.CreatedBranches) == 0 && test.wantRun.PRNumber == 0 {
+				assert.Empty(t, journal.Repos)
+			} else {
+				assert.Equal(t, []RepoRun{test.wantRun}, journal.Repos)
+			}
+		})
+	}
+}
@@ -0,0 +1,234 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/cli/go-gh/v2/pkg/api"
+	graphql "github.com/cli/shurcooL-graphql"
+
+	"github.com/github/gh-combine/internal/github"
+)
+
+// maxCompareFiles is the number of files the compare API returns at most
+const maxCompareFiles = 300
+
+var errTooManyFiles = errors.New("too many changed files for a signed commit")
+
+// Base64String is the GraphQL scalar for base64 encoded file contents
+type Base64String string
+
+// CreateCommitOnBranchInput is the GraphQL input type of the createCommitOnBranch mutation
+type CreateCommitOnBranchInput struct {
+	Branch          CommittableBranch `json:"branch"`
+	ExpectedHeadOid GitObjectID       `json:"expectedHeadOid"`
+	FileChanges     FileChanges       `json:"fileChanges"`
+	Message         CommitMessage     `json:"message"`
+}
+
+// CommittableBranch is the GraphQL type identifying the branch to commit to
+type CommittableBranch struct {
+	RepositoryNameWithOwner graphql.String `json:"repositoryNameWithOwner"`
+	BranchName              graphql.String `json:"branchName"`
+}
+
+// FileChanges is the GraphQL type for the files added and deleted by a commit
+type FileChanges struct {
+	Additions []FileAddition `json:"additions"`
+	Deletions []FileDeletion `json:"deletions"`
+}
+
+// FileAddition is the GraphQL type for a file added or modified by a commit
+type FileAddition struct {
+	Path     graphql.String `json:"path"`
+	Contents Base64String   `json:"contents"`
+}
+
+// FileDeletion is the GraphQL type for a file deleted by a commit
+type FileDeletion struct {
+	Path graphql.String `json:"path"`
+}
+
+// CommitMessage is the GraphQL type for the message of a commit
+type CommitMessage struct {
+	Headline graphql.String `json:"headline"`
+	Body     graphql.String `json:"body"`
+}
+
+// compareFile is a changed file as returned by the compare API
+type compareFile struct {
+	Filename         string `json:"filename"`
+	Status           string `json:"status"`
+	SHA              string `json:"sha"`
+	PreviousFilename string `json:"previous_filename"`
+}
+
+// createSignedCommit replaces a branch with a single commit on top of baseSHA holding the
+// changes of the working branch. The commit is created with createCommitOnBranch, so GitHub signs it
+func createSignedCommit(ctx context.Context, graphQlClient *api.GraphQLClient, restClient RESTClientInterface, repo github.Repo, branch, baseSHA, workingBranch, message string) error {
+	if graphQlClient == nil {
+		return errors.New("no GraphQL client available")
+	}
+
+	files, err := compareCommits(ctx, restClient, repo, baseSHA, workingBranch)
+	if err != nil {
+		return err
+	}
+
+	changes, err := fileChanges(ctx, restClient, repo, files)
+	if err != nil {
+		return err
+	}
+
+	// The branch may still point to a previous build, createCommitOnBranch has to start from the base
+	if err := setBranchSHA(ctx, restClient, repo, branch, baseSHA); err != nil {
+		return fmt.Errorf("failed to reset %s: %w", branch, err)
+	}
+
+	headline, body, _ := strings.Cut(message, "\n")
+
+	var mutation struct {
+		CreateCommitOnBranch struct {
+			Commit struct {
+				Oid string
+			}
+		} `graphql:"createCommitOnBranch(input: $input)"`
+	}
+
+	variables := map[string]interface{}{
+		"input": CreateCommitOnBranchInput{
+			Branch: CommittableBranch{
+				RepositoryNameWithOwner: graphql.String(repo.Owner + "/" + repo.Repo),
+				BranchName:              graphql.String(branch),
+			},
+			ExpectedHeadOid: GitObjectID(baseSHA),
+			FileChanges:     changes,
+			Message: CommitMessage{
+				Headline: graphql.String(headline),
+				Body:     graphql.String(strings.TrimSpace(body)),
+			},
+		},
+	}
+
+	if err := graphQlClient.MutateWithContext(ctx, "CreateCommitOnBranch", &mutation, variables); err != nil {
+		return fmt.Errorf("failed to create signed commit: %w", err)
+	}
+
+	Logger.Debug("Created signed commit", "repo", repo, "sha", mutation.CreateCommitOnBranch.Commit.Oid)
+	return nil
+}
+
+// checkUnsignedFallback returns an error when a branch cannot fall back to unsigned merge commits after its signed
+// commit failed, because a ruleset requires signed commits on the branch or on the default branch it is merged into.
+// When the rules cannot be read, signed commits are assumed to be required
+func checkUnsignedFallback(ctx context.Context, client RESTClientInterface, repo github.Repo, branch string, signErr error) error {
+	base, err := getDefaultBranch(ctx, client, repo)
+	if err != nil {
+		return fmt.Errorf("%w (could not check whether signed commits are required: %w)", signErr, err)
+	}
+
+	required, err := signedCommitsRequired(ctx, client, repo, branch, base)
+	if err != nil {
+		return fmt.Errorf("%w (could not check whether signed commits are required: %w)", signErr, err)
+	}
+	if required {
+		return fmt.Errorf("%w (a ruleset requires signed commits)", signErr)
+	}
+	return nil
+}
+
+// signedCommitsRequired reports whether a ruleset requires signed commits on any of the branches
+func signedCommitsRequired(ctx context.Context, client RESTClientInterface, repo github.Repo, branches ...string) (bool, error) {
+	for _, branch := range branches {
+		select {
+		case <-ctx.Done():
+			return false, ctx.Err()
+		default:
+			// Continue processing
+		}
+
+		var rules []struct {
+			Type string `json:"type"`
+		}
+		endpoint := fmt.Sprintf("repos/%s/%s/rules/branches/%s?per_page=100", repo.Owner, repo.Repo, branch)
+		if err := client.Get(endpoint, &rules); err != nil {
+			return false, fmt.Errorf("failed to get the rules of %s: %w", branch, err)
+		}
+		for _, rule := range rules {
+			if rule.Type == "required_signatures" {
+				Logger.Debug("Ruleset requires signed commits", "repo", repo, "branch", branch)
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+// compareCommits returns the files changed between base and head
+func compareCommits(ctx context.Context, client RESTClientInterface, repo github.Repo, base, head string) ([]compareFile, error) {
+	endpoint := fmt.Sprintf("repos/%s/%s/compare/%s...%s", repo.Owner, repo.Repo, base, head)
+
+	var comparison struct {
+		Files []compareFile `json:"files"`
+	}
+	if err := client.Get(endpoint, &comparison); err != nil {
+		return nil, fmt.Errorf("failed to compare %s...%s: %w", base, head, err)
+	}
+
+	// The compare API silently truncates the file list
+	if len(comparison.Files) >= maxCompareFiles {
+		return nil, fmt.Errorf("%w: %d or more files", errTooManyFiles, maxCompareFiles)
+	}
+	return comparison.Files, nil
+}
+
+// fileChanges converts the changed files of a comparison into the additions and deletions of a commit
+func fileChanges(ctx context.Context, client RESTClientInterface, repo github.Repo, files []compareFile) (FileChanges, error) {
+	changes := FileChanges{Additions: []FileAddition{}, Deletions: []FileDeletion{}}
+
+	for _, file := range files {
+		select {
+		case <-ctx.Done():
+			return changes, ctx.Err()
+		default:
+			// Continue processing
+		}
+
+		if file.Status == "removed" {
+			changes.Deletions = append(changes.Deletions, FileDeletion{Path: graphql.String(file.Filename)})
+			continue
+		}
+		if file.Status == "renamed" && file.PreviousFilename != "" {
+			changes.Deletions = append(changes.Deletions, FileDeletion{Path: graphql.String(file.PreviousFilename)})
+		}
+
+		contents, err := getBlobContents(ctx, client, repo, file.SHA)
+		if err != nil {
+			return changes, fmt.Errorf("failed to get contents of %s: %w", file.Filename, err)
+		}
+		changes.Additions = append(changes.Additions, FileAddition{Path: graphql.String(file.Filename), Contents: Base64String(contents)})
+	}
+
+	return changes, nil
+}
+
+// getBlobContents returns the base64 encoded contents of a blob
+func getBlobContents(ctx context.Context, client RESTClientInterface, repo github.Repo, sha string) (string, error) {
+	endpoint := fmt.Sprintf("repos/%s/%s/git/blobs/%s", repo.Owner, repo.Repo, sha)
+
+	var blob struct {
+		Content  string `json:"content"`
+		Encoding string `json:"encoding"`
+	}
+	if err := client.Get(endpoint, &blob); err != nil {
+		return "", err
+	}
+	if blob.Encoding != "base64" {
+		return "", fmt.Errorf("unexpected blob encoding %q", blob.Encoding)
+	}
+
+	// The API wraps the base64 content in lines, GraphQL expects it in one piece
+	return strings.ReplaceAll(blob.Content, "\n", ""), nil
+}
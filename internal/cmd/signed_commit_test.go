@@ -0,0 +1,82 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	graphql "github.com/cli/shurcooL-graphql"
+
+	"github.com/github/gh-combine/internal/github"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFileChanges(t *testing.T) {
+	t.Parallel()
+
+	var blobs []string
+	client := &MockRESTClient{
+		GetFunc: func(endpoint string, response interface{}) error {
+			blobs = append(blobs, endpoint)
+			return json.Unmarshal([]byte(`{"content": "aGVs\nbG8=\n", "encoding": "base64"}`), response)
+		},
+	}
+
+	files := []compareFile{
+		{Filename: "package.json", Status: "modified", SHA: "aaa"},
+		{Filename: "go.sum", Status: "added", SHA: "bbb"},
+		{Filename: "old.txt", Status: "removed", SHA: "ccc"},
+		{Filename: "new-name.txt", Status: "renamed", SHA: "ddd", PreviousFilename: "old-name.txt"},
+	}
+
+	changes, err := fileChanges(context.Background(), client, github.Repo{Owner: "owner", Repo: "repo"}, files)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []FileAddition{
+		{Path: "package.json", Contents: "aGVsbG8="},
+		{Path: "go.sum", Contents: "aGVsbG8="},
+		{Path: "new-name.txt", Contents: "aGVsbG8="},
+	}, changes.Additions)
+	assert.Equal(t, []FileDeletion{{Path: graphql.String("old.txt")}, {Path: graphql.String("old-name.txt")}}, changes.Deletions)
+	assert.Equal(t, []string{
+		"repos/owner/repo/git/blobs/aaa",
+		"repos/owner/repo/git/blobs/bbb",
+		"repos/owner/repo/git/blobs/ddd",
+	}, blobs)
+}
+
+func TestCompareCommits(t *testing.T) {
+	t.Parallel()
+
+	repo := github.Repo{Owner: "owner", Repo: "repo"}
+
+	t.Run("returns the changed files", func(t *testing.T) {
+		t.Parallel()
+
+		client := &MockRESTClient{
+			GetFunc: func(endpoint string, response interface{}) error {
+				assert.Equal(t, "repos/owner/repo/compare/base...combined-prs-working", endpoint)
+				return json.Unmarshal([]byte(`{"files": [{"filename": "go.mod", "status": "modified", "sha": "abc"}]}`), response)
+			},
+		}
+
+		files, err := compareCommits(context.Background(), client, repo, "base", "combined-prs-working")
+		assert.NoError(t, err)
+		assert.Equal(t, []compareFile{{Filename: "go.mod", Status: "modified", SHA: "abc"}}, files)
+	})
+
+	t.Run("refuses truncated comparisons", func(t *testing.T) {
+		t.Parallel()
+
+		client := &MockRESTClient{
+			GetFunc: func(endpoint string, response interface{}) error {
+				files := make([]compareFile, maxCompareFiles)
+				data, _ := json.Marshal(map[string]interface{}{"files": files})
+				return json.Unmarshal(data, response)
+			},
+		}
+
+		_, err := compareCommits(context.Background(), client, repo, "base", "head")
+		assert.ErrorIs(t, err, errTooManyFiles)
+	})
+}
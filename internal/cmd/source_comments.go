@@ -0,0 +1,207 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/github/gh-combine/internal/github"
+)
+
+// sourceCommentMarker is a hidden marker that identifies the comment gh-combine leaves on source PRs,
+// so that later runs update it instead of posting another comment
+const sourceCommentMarker = "<!-- gh-combine:source-comment -->"
+
+// issueComment is a comment on an issue or pull request
+type issueComment struct {
+	ID   int64  `json:"id"`
+	Body string `json:"body"`
+}
+
+// commentOnSourcePRs lets the source PRs know whether they were included in the combined PR or why they were skipped.
+// ciSkipped holds the unmet CI requirement of the PRs that were skipped because of their CI.
+// Failures are logged and do not fail the run, since the combined PR has already been created
+func commentOnSourcePRs(ctx context.Context, client RESTClientInterface, repo github.Repo, result *CombineResult, ciSkipped map[int]string) {
+	comments := map[int]string{}
+	for _, pr := range result.Combined {
+		comments[pr.Number] = fmt.Sprintf("This pull request was included in the combined pull request #%d.", result.PRNumber)
+	}
+	files := map[int][]string{}
+	for _, pr := range result.MergeConflicts {
+		comments[pr.Number] = "This pull request was skipped: " + mergeConflictReason(ctx, client, repo, pr, result.Combined, files) + "."
+	}
+	for _, pr := range result.Ejected {
+		comments[pr.Number] = fmt.Sprintf("This pull request was skipped: CI failing on the combined branch when it was included in #%d.", result.PRNumber)
+	}
+	for number, unmet := range ciSkipped {
+		comments[number] = "This pull request was skipped: " + ciSkipReason(unmet) + "."
+	}
+
+	for number, text := range comments {
+		// Check for cancellation
+		select {
+		case <-ctx.Done():
+			return
+		default:
+			// Continue processing
+		}
+
+		if err := upsertSourceComment(ctx, client, repo, number, sourceCommentBody(text)); err != nil {
+			Logger.Warn("Failed to comment on source PR", "repo", repo, "pr", number, "error", err)
+		}
+	}
+}
+
+// ciSkipReason describes an unmet CI requirement to the author of a source PR
+func ciSkipReason(unmet string) string {
+	switch unmet {
+	case requirementCIPending:
+		return "CI was still pending, it will be considered again in the next run once CI finishes"
+	case requirementNoChecks:
+		return "no CI checks have reported on it"
+	default:
+		return "CI failing"
+	}
+}
+
+// mergeConflictReason describes what a PR conflicted with. A PR that GitHub reports as not mergeable conflicts with
+// its base branch, otherwise it conflicted with the combined PRs that change the same files. The changed files of
+// the PRs are cached in files, since several PRs can conflict with the same combined PR
+func mergeConflictReason(ctx context.Context, client RESTClientInterface, repo github.Repo, pr github.Pull, combined github.Pulls, files map[int][]string) string {
+	var details struct {
+		Mergeable *bool `json:"mergeable"`
+	}
+	if err := client.Get(fmt.Sprintf("repos/%s/%s/pulls/%d", repo.Owner, repo.Repo, pr.Number), &details); err != nil {
+		Logger.Debug("Failed to get mergeability of PR", "repo", repo, "pr", pr.Number, "error", err)
+	} else if details.Mergeable != nil && !*details.Mergeable {
+		return fmt.Sprintf("merge conflict with the base branch %s", pr.Base.Ref)
+	}
+
+	prFiles, err := cachedPullRequestFiles(ctx, client, repo, pr.Number, files)
+	if err != nil {
+		Logger.Debug("Failed to list files of PR", "repo", repo, "pr", pr.Number, "error", err)
+		return "merge conflict with the other combined pull requests"
+	}
+
+	var conflicting []string
+	for _, other := range combined {
+		otherFiles, err := cachedPullRequestFiles(ctx, client, repo, other.Number, files)
+		if err != nil {
+			Logger.Debug("Failed to list files of PR", "repo", repo, "pr", other.Number, "error", err)
+			continue
+		}
+		if slices.ContainsFunc(prFiles, func(file string) bool { return slices.Contains(otherFiles, file) }) {
+			conflicting = append(conflicting, fmt.Sprintf("#%d", other.Number))
+		}
+	}
+
+	if len(conflicting) == 0 {
+		return "merge conflict with the other combined pull requests"
+	}
+	return "merge conflict with the changes of " + strings.Join(conflicting, ", ")
+}
+
+// cachedPullRequestFiles returns the files changed by a PR, fetching them only once
+func cachedPullRequestFiles(ctx context.Context, client RESTClientInterface, repo github.Repo, number int, cache map[int][]string) ([]string, error) {
+	if files, ok := cache[number]; ok {
+		return files, nil
+	}
+	files, err := listPullRequestFiles(ctx, client, repo, number)
+	if err != nil {
+		return nil, err
+	}
+	cache[number] = files
+	return files, nil
+}
+
+// listPullRequestFiles fetches the names of the files changed by a PR, handling pagination
+func listPullRequestFiles(ctx context.Context, client RESTClientInterface, repo github.Repo, number int) ([]string, error) {
+	var names []string
+	for page := 1; ; page++ {
+		// Check for cancellation
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		default:
+			// Continue processing
+		}
+
+		var files []struct {
+			Filename string `json:"filename"`
+		}
+		endpoint := fmt.Sprintf("repos/%s/%s/pulls/%d/files?per_page=100&page=%d", repo.Owner, repo.Repo, number, page)
+		if err := client.Get(endpoint, &files); err != nil {
+			return nil, fmt.Errorf("failed to fetch files of #%d from page %d: %w", number, page, err)
+		}
+		for _, file := range files {
+			names = append(names, file.Filename)
+		}
+
+		// If fewer than 100 files are returned, we've reached the last page
+		if len(files) < 100 {
+			return names, nil
+		}
+	}
+}
+
+// sourceCommentBody builds the body of the comment left on a source PR
+func sourceCommentBody(text string) string {
+	return sourceCommentMarker + "\n" + text + "\n\n" + generatedWithLink
+}
+
+// upsertSourceComment updates the existing gh-combine comment on a PR, or posts a new one if there is none
+func upsertSourceComment(ctx context.Context, client RESTClientInterface, repo github.Repo, number int, body string) error {
+	existing, err := findSourceComment(ctx, client, repo, number)
+	if err != nil {
+		return err
+	}
+
+	if existing == nil {
+		return createIssueComment(ctx, client, repo, number, body)
+	}
+	if existing.Body == body {
+		Logger.Debug("Source PR comment is up to date", "repo", repo, "pr", number)
+		return nil
+	}
+
+	payload, err := encodePayload(map[string]string{"body": body})
+	if err != nil {
+		return fmt.Errorf("failed to encode payload: %w", err)
+	}
+	return client.Patch(fmt.Sprintf("repos/%s/%s/issues/comments/%d", repo.Owner, repo.Repo, existing.ID), payload, nil)
+}
+
+// findSourceComment returns the comment on a PR that carries the gh-combine marker, handling pagination
+func findSourceComment(ctx context.Context, client RESTClientInterface, repo github.Repo, number int) (*issueComment, error) {
+	page := 1
+
+	for {
+		// Check for cancellation
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		default:
+			// Continue processing
+		}
+
+		var comments []issueComment
+		endpoint := fmt.Sprintf("repos/%s/%s/issues/%d/comments?page=%d&per_page=100", repo.Owner, repo.Repo, number, page)
+		if err := client.Get(endpoint, &comments); err != nil {
+			return nil, fmt.Errorf("failed to fetch comments from page %d: %w", page, err)
+		}
+
+		for _, comment := range comments {
+			if strings.Contains(comment.Body, sourceCommentMarker) {
+				return &comment, nil
+			}
+		}
+
+		// If fewer than 100 comments are returned, we've reached the last page
+		if len(comments) < 100 {
+			return nil, nil
+		}
+
+		page++
+	}
+}
@@ -0,0 +1,137 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/github/gh-combine/internal/github"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUpsertSourceComment(t *testing.T) {
+	t.Parallel()
+
+	repo := github.Repo{Owner: "owner", Repo: "repo"}
+	body := sourceCommentBody("This pull request was included in the combined pull request #42.")
+
+	tests := []struct {
+		name      string
+		comments  string
+		wantPost  bool
+		wantPatch string
+	}{
+		{
+			name:     "no existing comment",
+			comments: `[{"id": 1, "body": "LGTM"}]`,
+			wantPost: true,
+		},
+		{
+			name:      "outdated comment",
+			comments:  `[{"id": 1, "body": "LGTM"}, {"id": 2, "body": "` + sourceCommentMarker + `\nThis pull request was skipped: CI failing."}]`,
+			wantPatch: "repos/owner/repo/issues/comments/2",
+		},
+		{
+			name:     "up to date comment",
+			comments: `[{"id": 3, "body": ` + jsonString(t, body) + `}]`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			posted := false
+			patched := ""
+			client := &MockRESTClient{
+				GetFunc: func(endpoint string, response interface{}) error {
+					assert.Equal(t, "repos/owner/repo/issues/7/comments?page=1&per_page=100", endpoint)
+					return json.Unmarshal([]byte(test.comments), response)
+				},
+				PostFunc: func(endpoint string, body interface{}, response interface{}) error {
+					assert.Equal(t, "repos/owner/repo/issues/7/comments", endpoint)
+					posted = true
+					return nil
+				},
+				PatchFunc: func(endpoint string, body io.Reader, response interface{}) error {
+					patched = endpoint
+					return nil
+				},
+			}
+
+			err := upsertSourceComment(context.Background(), client, repo, 7, body)
+
+			assert.NoError(t, err)
+			assert.Equal(t, test.wantPost, posted)
+			assert.Equal(t, test.wantPatch, patched)
+		})
+	}
+}
+
+func TestCommentOnSourcePRs(t *testing.T) {
+	t.Parallel()
+
+	comments := map[string]string{}
+	client := &MockRESTClient{
+		GetFunc: func(endpoint string, response interface{}) error {
+			switch {
+			case strings.HasSuffix(endpoint, "/pulls/2"):
+				return json.Unmarshal([]byte(`{"mergeable": true}`), response)
+			case strings.HasSuffix(endpoint, "/pulls/7"):
+				return json.Unmarshal([]byte(`{"mergeable": false}`), response)
+			case strings.Contains(endpoint, "/pulls/1/files"), strings.Contains(endpoint, "/pulls/2/files"):
+				return json.Unmarshal([]byte(`[{"filename": "go.mod"}, {"filename": "go.sum"}]`), response)
+			case strings.Contains(endpoint, "/pulls/8/files"):
+				return json.Unmarshal([]byte(`[{"filename": "package.json"}]`), response)
+			}
+			return json.Unmarshal([]byte(`[]`), response)
+		},
+		PostFunc: func(endpoint string, body interface{}, response interface{}) error {
+			var payload map[string]string
+			if err := json.NewDecoder(body.(io.Reader)).Decode(&payload); err != nil {
+				return err
+			}
+			comments[endpoint] = payload["body"]
+			return nil
+		},
+	}
+
+	result := &CombineResult{
+		Combined:       github.Pulls{{Number: 1}},
+		MergeConflicts: github.Pulls{{Number: 2}, {Number: 7, Base: github.Ref{Ref: "main"}}, {Number: 8}},
+		Ejected:        github.Pulls{{Number: 3}},
+		PRNumber:       42,
+	}
+
+	ciSkipped := map[int]string{4: requirementCIFailing, 5: requirementCIPending, 6: requirementNoChecks}
+	commentOnSourcePRs(context.Background(), client, github.Repo{Owner: "owner", Repo: "repo"}, result, ciSkipped)
+
+	assert.Len(t, comments, 8)
+	assert.Contains(t, comments["repos/owner/repo/issues/1/comments"], "included in the combined pull request #42")
+	assert.Contains(t, comments["repos/owner/repo/issues/2/comments"], "skipped: merge conflict with the changes of #1.")
+	assert.Contains(t, comments["repos/owner/repo/issues/7/comments"], "skipped: merge conflict with the base branch main.")
+	assert.Contains(t, comments["repos/owner/repo/issues/8/comments"], "skipped: merge conflict with the other combined pull requests.")
+	assert.Contains(t, comments["repos/owner/repo/issues/3/comments"], "skipped: CI failing on the combined branch")
+	assert.Contains(t, comments["repos/owner/repo/issues/4/comments"], "skipped: CI failing.")
+	assert.Contains(t, comments["repos/owner/repo/issues/5/comments"], "skipped: CI was still pending")
+	assert.Contains(t, comments["repos/owner/repo/issues/6/comments"], "skipped: no CI checks have reported on it.")
+	for _, comment := range comments {
+		assert.True(t, strings.HasSuffix(comment, generatedWithLink))
+	}
+	for _, comment := range comments {
+		assert.True(t, strings.HasPrefix(comment, sourceCommentMarker))
+	}
+}
+
+// jsonString encodes a string as a JSON string literal
+func jsonString(t *testing.T, s string) string {
+	t.Helper()
+
+	data, err := json.Marshal(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
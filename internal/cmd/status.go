@@ -0,0 +1,337 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/cli/go-gh/v2/pkg/api"
+	"github.com/spf13/cobra"
+
+	"github.com/github/gh-combine/internal/github"
+)
+
+var statusOrg string
+
+// CombinedPRStatus describes an open combined PR for the status command
+type CombinedPRStatus struct {
+	Repo          string    `json:"repo"`
+	Number        int       `json:"number"`
+	Title         string    `json:"title"`
+	URL           string    `json:"url"`
+	CreatedAt     time.Time `json:"createdAt"`
+	CI            string    `json:"ci"`
+	Review        string    `json:"review"`
+	Mergeable     string    `json:"mergeable"`
+	SourcePRs     int       `json:"sourcePRs"`
+	OpenSourcePRs int       `json:"openSourcePRs"`
+}
+
+// newStatusCmd creates the status subcommand, which lists the open combined PRs of many repositories
+func newStatusCmd() *cobra.Command {
+	statusCmd := &cobra.Command{
+		Use:   "status owner/repo",
+		Short: "List the open combined PRs of repositories",
+		Long: `List every open PR created by gh-combine with its age, CI state, review state, mergeability
+    and how many of its source PRs are still open.
+    Examples:
+      gh combine status owner/repo                # List the open combined PRs of a repository
+      gh combine status --file repos.txt          # List the open combined PRs of multiple repositories
+      gh combine status --org my-org --output json # List the open combined PRs of every repository of an organization`,
+		Args: cobra.ArbitraryArgs,
+		RunE: runStatus,
+	}
+
+	statusCmd.Flags().StringVar(&reposFile, "file", "", "File containing repository names, one per line")
+	statusCmd.Flags().StringVar(&statusOrg, "org", "", "List the combined PRs of every repository of an organization")
+	statusCmd.Flags().StringVar(&outputFormat, "output", "table", "Output format: table, plain, or json")
+	statusCmd.Flags().BoolVar(&noColor, "no-color", false, "Disable color output")
+
+	return statusCmd
+}
+
+// runStatus is the main execution function for the status command
+func runStatus(cmd *cobra.Command, args []string) error {
+	ctx, cancel := SetupSignalContext()
+	defer cancel()
+
+	repos, err := ParseRepositories(args, reposFile)
+	if err != nil {
+		return fmt.Errorf("failed to parse repositories: %w", err)
+	}
+
+	restClient, err := api.DefaultRESTClient()
+	if err != nil {
+		return fmt.Errorf("failed to create REST client: %w", err)
+	}
+
+	graphQlClient, err := api.DefaultGraphQLClient()
+	if err != nil {
+		return fmt.Errorf("failed to create GraphQLClient client: %w", err)
+	}
+
+	restClientWrapper := struct {
+		RESTClientInterface
+	}{restClient}
+
+	if statusOrg != "" {
+		orgRepos, err := listOrgRepositories(ctx, restClientWrapper, statusOrg)
+		if err != nil {
+			return err
+		}
+		repos = append(repos, orgRepos...)
+	}
+	if len(repos) == 0 {
+		return errors.New("no repositories specified")
+	}
+
+	spinner := NewSpinner("")
+	defer spinner.Stop()
+
+	var statuses []CombinedPRStatus
+	for _, repo := range repos {
+		// Check for cancellation
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+			// Continue processing
+		}
+
+		spinner.UpdateMessage("Checking " + repo.String())
+		repoStatuses, err := combinedPRStatuses(ctx, graphQlClient, restClientWrapper, repo)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			Logger.Warn("Failed to get combined PRs", "repo", repo, "error", err)
+			continue
+		}
+		statuses = append(statuses, repoStatuses...)
+	}
+
+	spinner.Stop()
+	displayCombinedPRStatuses(statuses, outputFormat, time.Now())
+	return nil
+}
+
+// listOrgRepositories fetches the repositories of an organization that are not archived, handling pagination
+func listOrgRepositories(ctx context.Context, client RESTClientInterface, org string) ([]github.Repo, error) {
+	var repos []github.Repo
+	for page := 1; ; page++ {
+		// Check for cancellation
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		default:
+			// Continue processing
+		}
+
+		var orgRepos []struct {
+			FullName string `json:"full_name"`
+			Archived bool   `json:"archived"`
+		}
+		endpoint := fmt.Sprintf("orgs/%s/repos?per_page=100&page=%d", org, page)
+		if err := client.Get(endpoint, &orgRepos); err != nil {
+			return nil, fmt.Errorf("failed to fetch repositories of %s from page %d: %w", org, page, err)
+		}
+
+		for _, orgRepo := range orgRepos {
+			if orgRepo.Archived {
+				continue
+			}
+			repo, err := github.ParseRepo(orgRepo.FullName)
+			if err != nil {
+				return nil, err
+			}
+			repos = append(repos, repo)
+		}
+
+		// If fewer than 100 repositories are returned, we've reached the last page
+		if len(orgRepos) < 100 {
+			return repos, nil
+		}
+	}
+}
+
+// combinedPRStatuses returns the status of every open PR of a repository that was created by gh-combine
+func combinedPRStatuses(ctx context.Context, graphQlClient *api.GraphQLClient, restClient RESTClientInterface, repo github.Repo) ([]CombinedPRStatus, error) {
+	pulls, err := listPullRequests(ctx, restClient, repo, "open")
+	if err != nil {
+		return nil, err
+	}
+
+	var statuses []CombinedPRStatus
+	for _, pull := range pulls {
+		if !isCombinedPRBody(pull.Body) {
+			continue
+		}
+
+		response, err := GetPRStatusInfo(ctx, graphQlClient, repo.Owner, repo.Repo, pull.Number)
+		if err != nil {
+			return nil, err
+		}
+
+		// The mergeable state is only returned for a single PR
+		var details struct {
+			MergeableState string `json:"mergeable_state"`
+		}
+		if err := restClient.Get(fmt.Sprintf("repos/%s/%s/pulls/%d", repo.Owner, repo.Repo, pull.Number), &details); err != nil {
+			return nil, fmt.Errorf("failed to get #%d: %w", pull.Number, err)
+		}
+
+		statuses = append(statuses, newCombinedPRStatus(repo, pull, pulls, response, details.MergeableState))
+	}
+	return statuses, nil
+}
+
+// newCombinedPRStatus builds the status of a combined PR, counting its source PRs among the open PRs
+func newCombinedPRStatus(repo github.Repo, pull github.Pull, openPulls github.Pulls, response *prStatusResponse, mergeableState string) CombinedPRStatus {
+	open := make(map[int]bool)
+	for _, openPull := range openPulls {
+		open[openPull.Number] = true
+	}
+
+	sources := parseCombinedPRNumbers(pull.Body)
+	openSources := 0
+	for _, number := range sources {
+		if open[number] {
+			openSources++
+		}
+	}
+
+	return CombinedPRStatus{
+		Repo:          repo.String(),
+		Number:        pull.Number,
+		Title:         pull.Title,
+		URL:           fmt.Sprintf("https://github.com/%s/%s/pull/%d", repo.Owner, repo.Repo, pull.Number),
+		CreatedAt:     pull.CreatedAt,
+		CI:            ciState(response, nil, nil),
+		Review:        reviewState(response.Data.Repository.PullRequest.ReviewDecision),
+		Mergeable:     mergeableStatus(mergeableState),
+		SourcePRs:     len(sources),
+		OpenSourcePRs: openSources,
+	}
+}
+
+// reviewState describes the review decision of a PR
+func reviewState(decision string) string {
+	if decision == "" {
+		return "none"
+	}
+	return strings.ReplaceAll(strings.ToLower(decision), "_", " ")
+}
+
+// mergeableStatus describes the mergeable state of a PR, which GitHub computes in the background
+func mergeableStatus(state string) string {
+	switch state {
+	case "":
+		return "unknown"
+	case "dirty":
+		return "conflicting"
+	default:
+		return state
+	}
+}
+
+// displayCombinedPRStatuses prints the combined PR statuses in the requested output format
+func displayCombinedPRStatuses(statuses []CombinedPRStatus, outputFormat string, now time.Time) {
+	switch outputFormat {
+	case "table":
+		displayTableStatuses(statuses, now)
+	case "json":
+		if statuses == nil {
+			statuses = []CombinedPRStatus{}
+		}
+		jsonData, _ := json.MarshalIndent(statuses, "", "  ")
+		fmt.Println(string(jsonData))
+	case "plain":
+		fallthrough
+	default:
+		displayPlainStatuses(statuses, now)
+	}
+}
+
+// statusTableRow returns the cells of a combined PR in the status table
+func statusTableRow(status CombinedPRStatus, now time.Time) []string {
+	return []string{
+		status.Repo,
+		fmt.Sprintf("#%d", status.Number),
+		formatAge(now.Sub(status.CreatedAt)),
+		status.CI,
+		status.Review,
+		status.Mergeable,
+		fmt.Sprintf("%d/%d", status.OpenSourcePRs, status.SourcePRs),
+	}
+}
+
+// displayTableStatuses prints the combined PR statuses as a table
+func displayTableStatuses(statuses []CombinedPRStatus, now time.Time) {
+	if len(statuses) == 0 {
+		fmt.Println("No open combined PRs found.")
+		return
+	}
+
+	header := []string{"Repository", "PR", "Age", "CI", "Review", "Mergeable", "Sources Open"}
+	rows := make([][]string, len(statuses))
+	colWidths := make([]int, len(header))
+	for i, cell := range header {
+		colWidths[i] = len(cell)
+	}
+	for i, status := range statuses {
+		rows[i] = statusTableRow(status, now)
+		for j, cell := range rows[i] {
+			colWidths[j] = max(colWidths[j], len(cell))
+		}
+	}
+	colWidths[0] = min(colWidths[0], maxRepoNameLength)
+
+	top, sep, bot := generateTableBorders(colWidths)
+	fmt.Println(top)
+	fmt.Println(formatStatusRow(header, colWidths, ""))
+	fmt.Println(sep)
+	for i, row := range rows {
+		ciColor := ""
+		switch statuses[i].CI {
+		case ciStatePassing:
+			ciColor = colorGreen
+		case ciStateFailing:
+			ciColor = colorYellow
+		}
+		fmt.Println(formatStatusRow(row, colWidths, ciColor))
+	}
+	fmt.Println(bot)
+}
+
+// formatStatusRow formats a row of the status table, coloring the CI cell
+func formatStatusRow(cells []string, colWidths []int, ciColor string) string {
+	row := tableVertLine
+	for i, cell := range cells {
+		if len(cell) > colWidths[i] {
+			cell = cell[:colWidths[i]]
+		}
+		padding := strings.Repeat(" ", colWidths[i]-len(cell))
+		if i == 3 && ciColor != "" {
+			cell = colorize(cell, ciColor)
+		}
+		row += " " + cell + padding + " " + tableVertLine
+	}
+	return row
+}
+
+// displayPlainStatuses prints the combined PR statuses as plain text
+func displayPlainStatuses(statuses []CombinedPRStatus, now time.Time) {
+	fmt.Printf("Open Combined PRs: %d\n", len(statuses))
+	for _, status := range statuses {
+		fmt.Printf("\n%s#%d %s\n", status.Repo, status.Number, status.Title)
+		fmt.Printf("  URL: %s\n", status.URL)
+		fmt.Printf("  Age: %s\n", formatAge(now.Sub(status.CreatedAt)))
+		fmt.Printf("  CI: %s\n", status.CI)
+		fmt.Printf("  Review: %s\n", status.Review)
+		fmt.Printf("  Mergeable: %s\n", status.Mergeable)
+		fmt.Printf("  Source PRs Open: %d/%d\n", status.OpenSourcePRs, status.SourcePRs)
+	}
+}
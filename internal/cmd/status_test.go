@@ -0,0 +1,90 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/github/gh-combine/internal/github"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewCombinedPRStatus(t *testing.T) {
+	t.Parallel()
+
+	repo := github.Repo{Owner: "owner", Repo: "repo"}
+	created := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	pull := github.Pull{
+		Number:    10,
+		Title:     "Combined PRs",
+		Body:      combinedPRsHeader + "\n- closes: #1\n- closes: #2\n- closes: #3\n\n" + generatedWithLink,
+		CreatedAt: created,
+	}
+	openPulls := github.Pulls{{Number: 1}, {Number: 3}, pull}
+
+	var response prStatusResponse
+	assert.NoError(t, json.Unmarshal([]byte(`{"data": {"repository": {"pullRequest": {
+		"reviewDecision": "CHANGES_REQUESTED",
+		"commits": {"nodes": [{"commit": {"statusCheckRollup": {"state": "FAILURE"}}}]}
+	}}}}`), &response))
+
+	status := newCombinedPRStatus(repo, pull, openPulls, &response, "dirty")
+
+	assert.Equal(t, CombinedPRStatus{
+		Repo:          "owner/repo",
+		Number:        10,
+		Title:         "Combined PRs",
+		URL:           "https://github.com/owner/repo/pull/10",
+		CreatedAt:     created,
+		CI:            ciStateFailing,
+		Review:        "changes requested",
+		Mergeable:     "conflicting",
+		SourcePRs:     3,
+		OpenSourcePRs: 2,
+	}, status)
+	assert.Equal(t, []string{"owner/repo", "#10", "9d", "failing", "changes requested", "conflicting", "2/3"}, statusTableRow(status, created.Add(9*24*time.Hour)))
+}
+
+func TestReviewAndMergeableStates(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "approved", reviewState("APPROVED"))
+	assert.Equal(t, "review required", reviewState("REVIEW_REQUIRED"))
+	assert.Equal(t, "none", reviewState(""))
+
+	assert.Equal(t, "clean", mergeableStatus("clean"))
+	assert.Equal(t, "conflicting", mergeableStatus("dirty"))
+	assert.Equal(t, "unknown", mergeableStatus(""))
+}
+
+func TestListOrgRepositories(t *testing.T) {
+	t.Parallel()
+
+	client := &MockRESTClient{
+		GetFunc: func(endpoint string, response interface{}) error {
+			if endpoint != "orgs/my-org/repos?per_page=100&page=1" {
+				return fmt.Errorf("unexpected endpoint %s", endpoint)
+			}
+			return json.Unmarshal([]byte(`[
+				{"full_name": "my-org/active"},
+				{"full_name": "my-org/archived", "archived": true}
+			]`), response)
+		},
+	}
+
+	repos, err := listOrgRepositories(context.Background(), client, "my-org")
+	assert.NoError(t, err)
+	assert.Equal(t, []github.Repo{{Owner: "my-org", Repo: "active"}}, repos)
+}
+
+func TestFormatStatusRow(t *testing.T) {
+	// Since this test changes global state, don't use t.Parallel()
+	origNoColor := noColor
+	defer func() { noColor = origNoColor }()
+	noColor = true
+
+	row := formatStatusRow([]string{"owner/repo", "#1", "2d", "passing"}, []int{12, 3, 3, 7}, colorGreen)
+	assert.Equal(t, "│ owner/repo   │ #1  │ 2d  │ passing │", row)
+}
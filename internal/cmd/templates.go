@@ -0,0 +1,232 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/github/gh-combine/internal/github"
+)
+
+// defaultPRTitle is the title of the combined PR when no --title-template is given
+const defaultPRTitle = "Combined PRs"
+
+var (
+	errInvalidTemplate = errors.New("invalid template")
+
+	// combinedPRsMarkerRegex matches the hidden marker listing the combined PRs in custom PR bodies
+	combinedPRsMarkerRegex = regexp.MustCompile(`<!-- gh-combine:prs=([\d,]*) -->`)
+
+	// templateFuncs are the extra functions available in --title-template and --body-template
+	templateFuncs = template.FuncMap{
+		"join":  strings.Join,
+		"lower": strings.ToLower,
+		"upper": strings.ToUpper,
+	}
+)
+
+// PRTemplateData is the data available to --title-template and --body-template
+type PRTemplateData struct {
+	Repo          string
+	Owner         string
+	Name          string
+	Date          time.Time
+	Command       string
+	Pulls         []PRTemplatePull
+	Conflicts     []PRTemplatePull
+	Ejected       []PRTemplatePull
+	Count         int
+	ConflictCount int
+	EjectedCount  int
+	// DefaultBody is the body gh-combine generates without a template
+	DefaultBody string
+	// RunID identifies the run that created the PR for the undo command, empty when the run is not recorded
+	RunID string
+}
+
+// PRTemplatePull describes a pull request in PRTemplateData
+type PRTemplatePull struct {
+	Number int
+	Title  string
+	Author string
+	Branch string
+	Labels []string
+	URL    string
+	// Dependency is the parsed dependency update for Dependabot and Renovate PRs, nil otherwise
+	Dependency *DependencyUpdate
+	// Outdated is set when the PR got new commits during the run and was combined at the commit that was
+	// evaluated. The combined PR does not close it
+	Outdated bool
+}
+
+// newPRTemplateData builds the template data for a combined PR
+func newPRTemplateData(repo github.Repo, combined, conflicts, ejected, outdated github.Pulls, command string) PRTemplateData {
+	data := PRTemplateData{
+		Repo:          repo.String(),
+		Owner:         repo.Owner,
+		Name:          repo.Repo,
+		Date:          time.Now(),
+		Command:       command,
+		Pulls:         templatePulls(repo, combined),
+		Conflicts:     templatePulls(repo, conflicts),
+		Ejected:       templatePulls(repo, ejected),
+		Count:         len(combined),
+		ConflictCount: len(conflicts),
+		EjectedCount:  len(ejected),
+		DefaultBody:   generatePRBody(combined, conflicts, ejected, outdated, command),
+	}
+	for i := range data.Pulls {
+		data.Pulls[i].Outdated = isOutdated(data.Pulls[i].Number, outdated)
+	}
+	return data
+}
+
+// templatePulls converts pull requests to their template representation
+func templatePulls(repo github.Repo, pulls github.Pulls) []PRTemplatePull {
+	result := make([]PRTemplatePull, 0, len(pulls))
+	for _, pull := range pulls {
+		templatePull := PRTemplatePull{
+			Number: pull.Number,
+			Title:  pull.Title,
+			Author: pull.User.Login,
+			Branch: pull.Head.Ref,
+			Labels: pull.Labels.Names(),
+			URL:    fmt.Sprintf("https://github.com/%s/%s/pull/%d", repo.Owner, repo.Repo, pull.Number),
+		}
+		if update, ok := ParseDependencyUpdate(pull.Title, pull.Head.Ref); ok {
+			templatePull.Dependency = &update
+		}
+		result = append(result, templatePull)
+	}
+	return result
+}
+
+// renderPRTitleAndBody renders the title and body of the combined PR from the templates, falling back to the defaults
+func renderPRTitleAndBody(data PRTemplateData) (string, string, error) {
+	title := defaultPRTitle
+	if titleTemplate != "" {
+		rendered, err := executeTemplate("title", titleTemplate, data)
+		if err != nil {
+			return "", "", err
+		}
+		// Titles are a single line
+		title = strings.Join(strings.Fields(rendered), " ")
+		if title == "" {
+			return "", "", fmt.Errorf("%w: --title-template rendered an empty title", errInvalidTemplate)
+		}
+	}
+
+	body, suffix := data.DefaultBody, ""
+	if bodyTemplate != "" {
+		rendered, err := executeTemplate("body", bodyTemplate, data)
+		if err != nil {
+			return "", "", err
+		}
+		body = rendered
+		// The source PRs are closed when the combined PR merges, like with the default body
+		if closing := closingKeywords(rendered, data.Pulls); !noAutoclose && closing != "" {
+			suffix += "\n\n" + closing
+		}
+		// Keep track of the combined PRs for commands that parse the body, like finalize
+		suffix += "\n\n" + combinedPRsMarker(data.Pulls)
+	}
+	if data.RunID != "" {
+		suffix += "\n" + runMarker(data.RunID)
+	}
+
+	// The keywords and markers must not be cut off, so only the body before them is truncated
+	return title, truncatePRBody(body, maxPRBodyLength-len(suffix)) + suffix, nil
+}
+
+// prBodySuffixLength is the most room renderPRTitleAndBody needs after a body for the closing keywords, the
+// combined PRs marker and the run marker
+func prBodySuffixLength(pulls github.Pulls) int {
+	templated := make([]PRTemplatePull, len(pulls))
+	for i, pull := range pulls {
+		templated[i] = PRTemplatePull{Number: pull.Number}
+	}
+	return len("\n\n"+closingKeywords("", templated)) + len("\n\n"+combinedPRsMarker(templated)) + len("\n"+runMarker(sampleRunID))
+}
+
+// closingKeywords returns the closes keywords for the combined PRs that a rendered body does not close already.
+// Outdated PRs are not closed
+func closingKeywords(rendered string, pulls []PRTemplatePull) string {
+	var keywords []string
+	for _, pull := range pulls {
+		if !pull.Outdated && !closingKeywordRegex(pull.Number).MatchString(rendered) {
+			keywords = append(keywords, fmt.Sprintf("closes: #%d", pull.Number))
+		}
+	}
+	return strings.Join(keywords, ", ")
+}
+
+// closingKeywordRegex matches a GitHub closing keyword referencing a PR number, e.g. "closes #12" or "Fixes: #12"
+func closingKeywordRegex(number int) *regexp.Regexp {
+	return regexp.MustCompile(fmt.Sprintf(`(?i)\b(?:close[sd]?|fix(?:e[sd])?|resolve[sd]?):?\s+#%d\b`, number))
+}
+
+// combinedPRsMarker returns a hidden marker listing the combined PRs. Outdated PRs are left out, so finalize
+// does not close them
+func combinedPRsMarker(pulls []PRTemplatePull) string {
+	numbers := []string{}
+	for _, pull := range pulls {
+		if !pull.Outdated {
+			numbers = append(numbers, strconv.Itoa(pull.Number))
+		}
+	}
+	return fmt.Sprintf("<!-- gh-combine:prs=%s -->", strings.Join(numbers, ","))
+}
+
+// loadTemplate parses a template given inline, or read from a file when the value starts with @
+func loadTemplate(name, value string) (*template.Template, error) {
+	text := value
+	if path, ok := strings.CutPrefix(value, "@"); ok {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("%w: failed to read %s template: %w", errInvalidTemplate, name, err)
+		}
+		text = string(data)
+	}
+
+	tmpl, err := template.New(name).Funcs(templateFuncs).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", errInvalidTemplate, err)
+	}
+	return tmpl, nil
+}
+
+// executeTemplate loads and renders a template
+func executeTemplate(name, value string, data PRTemplateData) (string, error) {
+	tmpl, err := loadTemplate(name, value)
+	if err != nil {
+		return "", err
+	}
+
+	var out strings.Builder
+	if err := tmpl.Execute(&out, data); err != nil {
+		return "", fmt.Errorf("%w: %w", errInvalidTemplate, err)
+	}
+	return out.String(), nil
+}
+
+// ValidateTemplates checks that the templates can be loaded and rendered, so mistakes
+// are reported before any branch is created
+func ValidateTemplates(titleTmpl, bodyTmpl string) error {
+	sample := newPRTemplateData(github.Repo{Owner: "owner", Repo: "repo"}, github.Pulls{{Number: 1}}, nil, nil, nil, "gh combine owner/repo")
+	if titleTmpl != "" {
+		if _, err := executeTemplate("title", titleTmpl, sample); err != nil {
+			return fmt.Errorf("--title-template: %w", err)
+		}
+	}
+	if bodyTmpl != "" {
+		if _, err := executeTemplate("body", bodyTmpl, sample); err != nil {
+			return fmt.Errorf("--body-template: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,208 @@
+package cmd
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/github/gh-combine/internal/github"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRenderPRTitleAndBody(t *testing.T) {
+	// Since this test changes global state, don't use t.Parallel()
+	origTitleTemplate := titleTemplate
+	origBodyTemplate := bodyTemplate
+	origNoAutoclose := noAutoclose
+	defer func() {
+		titleTemplate = origTitleTemplate
+		bodyTemplate = origBodyTemplate
+		noAutoclose = origNoAutoclose
+	}()
+
+	repo := github.Repo{Owner: "owner", Repo: "repo"}
+	combined := github.Pulls{
+		{Number: 1, Title: "Bump lodash from 4.17.20 to 4.17.21", User: github.User{Login: "dependabot[bot]"}, Head: github.Ref{Ref: "dependabot/npm_and_yarn/lodash-4.17.21"}, Labels: github.Labels{{Name: "dependencies"}}},
+		{Number: 2, Title: "Fix typo", User: github.User{Login: "octocat"}, Head: github.Ref{Ref: "fix-typo"}},
+	}
+	conflicts := github.Pulls{{Number: 3, Title: "Bump react from 17.0.0 to 18.0.0"}}
+	data := newPRTemplateData(repo, combined, conflicts, nil, nil, "gh combine owner/repo")
+
+	tests := []struct {
+		name          string
+		titleTemplate string
+		bodyTemplate  string
+		noAutoclose   bool
+		wantTitle     string
+		wantBody      string
+	}{
+		{
+			name:      "defaults",
+			wantTitle: defaultPRTitle,
+			wantBody:  data.DefaultBody,
+		},
+		{
+			name:          "conventional commit title",
+			titleTemplate: "chore(deps): combine {{.Count}} dependency updates",
+			wantTitle:     "chore(deps): combine 2 dependency updates",
+			wantBody:      data.DefaultBody,
+		},
+		{
+			name:          "title is a single line",
+			titleTemplate: "Combined {{range .Pulls}}\n#{{.Number}}{{end}}\n",
+			wantTitle:     "Combined #1 #2",
+			wantBody:      data.DefaultBody,
+		},
+		{
+			name:         "custom body",
+			bodyTemplate: "{{.Repo}}:{{range .Pulls}}\n- #{{.Number}} {{.Title}} by @{{.Author}} [{{join .Labels \", \"}}]{{if .Dependency}} ({{.Dependency.Name}}){{end}}{{end}}\nConflicts: {{.ConflictCount}}",
+			wantTitle:    defaultPRTitle,
+			wantBody:     "owner/repo:\n- #1 Bump lodash from 4.17.20 to 4.17.21 by @dependabot[bot] [dependencies] (lodash)\n- #2 Fix typo by @octocat []\nConflicts: 1\n\ncloses: #1, closes: #2\n\n<!-- gh-combine:prs=1,2 -->",
+		},
+		{
+			name:         "custom body that closes some PRs itself",
+			bodyTemplate: "Fixes #1\nSee #2",
+			wantTitle:    defaultPRTitle,
+			wantBody:     "Fixes #1\nSee #2\n\ncloses: #2\n\n<!-- gh-combine:prs=1,2 -->",
+		},
+		{
+			name:         "custom body that closes every PR itself",
+			bodyTemplate: "{{range .Pulls}}- closes: #{{.Number}}\n{{end}}",
+			wantTitle:    defaultPRTitle,
+			wantBody:     "- closes: #1\n- closes: #2\n\n\n<!-- gh-combine:prs=1,2 -->",
+		},
+		{
+			name:         "custom body with --no-autoclose",
+			bodyTemplate: "Combined {{.Count}} PRs",
+			noAutoclose:  true,
+			wantTitle:    defaultPRTitle,
+			wantBody:     "Combined 2 PRs\n\n<!-- gh-combine:prs=1,2 -->",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			titleTemplate = test.titleTemplate
+			bodyTemplate = test.bodyTemplate
+			noAutoclose = test.noAutoclose
+
+			title, body, err := renderPRTitleAndBody(data)
+
+			assert.NoError(t, err)
+			assert.Equal(t, test.wantTitle, title)
+			assert.Equal(t, test.wantBody, body)
+		})
+	}
+}
+
+func TestRenderPRBodyAtTheSizeLimit(t *testing.T) {
+	// Since this test changes global state, don't use t.Parallel()
+	origBodyTemplate, origNoAutoclose, origNoReleaseNotes := bodyTemplate, noAutoclose, noReleaseNotes
+	defer func() {
+		bodyTemplate, noAutoclose, noReleaseNotes = origBodyTemplate, origNoAutoclose, origNoReleaseNotes
+	}()
+	noAutoclose = false
+	noReleaseNotes = false
+
+	huge := strings.Replace(dependabotBody, "<h2>4.17.21</h2>", strings.Repeat("<p>A very long release note ✨</p>\n", 5000), 1)
+	var pulls github.Pulls
+	for i := 1; i <= 5; i++ {
+		pulls = append(pulls, github.Pull{Number: i, Title: "Bump lodash from 4.17.20 to 4.17.21", Body: huge, Head: github.Ref{Ref: "dependabot/npm_and_yarn/lodash-4.17.21"}})
+	}
+	data := newPRTemplateData(github.Repo{Owner: "owner", Repo: "repo"}, pulls, nil, nil, nil, "gh combine owner/repo")
+	data.RunID = "20250101-120000-abcdef"
+
+	tests := []struct {
+		name         string
+		bodyTemplate string
+	}{
+		{name: "default body with release notes"},
+		{name: "custom body including the default body", bodyTemplate: "{{.DefaultBody}}"},
+		{name: "custom body over the limit", bodyTemplate: "{{range .Pulls}}{{$.DefaultBody}}{{end}}"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			bodyTemplate = test.bodyTemplate
+
+			_, body, err := renderPRTitleAndBody(data)
+
+			assert.NoError(t, err)
+			assert.LessOrEqual(t, len(body), maxPRBodyLength)
+			assert.True(t, utf8.ValidString(body))
+			assert.True(t, strings.HasSuffix(body, runMarker(data.RunID)), "the run marker must not be cut off")
+			if test.bodyTemplate == "" {
+				assert.Contains(t, body, "Command used:")
+			} else {
+				assert.Contains(t, body, "closes: #5")
+				assert.Contains(t, body, "<!-- gh-combine:prs=1,2,3,4,5 -->")
+			}
+		})
+	}
+}
+
+func TestRenderPRBodyWithOutdatedPRs(t *testing.T) {
+	// Since this test changes global state, don't use t.Parallel()
+	origBodyTemplate, origNoAutoclose := bodyTemplate, noAutoclose
+	defer func() { bodyTemplate, noAutoclose = origBodyTemplate, origNoAutoclose }()
+	bodyTemplate = "{{range .Pulls}}- #{{.Number}}{{if .Outdated}} (not closed){{end}}\n{{end}}"
+	noAutoclose = false
+
+	pulls := github.Pulls{{Number: 1}, {Number: 2, Head: github.Ref{SHA: "bbbbbbbbbb"}}}
+	data := newPRTemplateData(github.Repo{Owner: "owner", Repo: "repo"}, pulls, nil, nil, github.Pulls{pulls[1]}, "gh combine owner/repo")
+
+	_, body, err := renderPRTitleAndBody(data)
+	assert.NoError(t, err)
+	assert.Equal(t, "- #1\n- #2 (not closed)\n\n\ncloses: #1\n\n<!-- gh-combine:prs=1 -->", body)
+}
+
+func TestLoadTemplateFromFile(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "body.md")
+	if err := os.WriteFile(path, []byte("Combined {{.Count}} PRs in {{.Name}}"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tmpl, err := loadTemplate("body", "@"+path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var out strings.Builder
+	if err := tmpl.Execute(&out, PRTemplateData{Count: 3, Name: "repo"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	assert.Equal(t, "Combined 3 PRs in repo", out.String())
+}
+
+func TestValidateTemplates(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name          string
+		titleTemplate string
+		bodyTemplate  string
+		wantErr       bool
+	}{
+		{name: "no templates"},
+		{name: "valid templates", titleTemplate: "{{.Count}} updates", bodyTemplate: "{{range .Pulls}}#{{.Number}}{{end}}"},
+		{name: "syntax error", titleTemplate: "{{.Count", wantErr: true},
+		{name: "unknown field", bodyTemplate: "{{.Nope}}", wantErr: true},
+		{name: "missing file", bodyTemplate: "@/does/not/exist.md", wantErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := ValidateTemplates(test.titleTemplate, test.bodyTemplate)
+			if test.wantErr != errors.Is(err, errInvalidTemplate) {
+				t.Errorf("ValidateTemplates(%q, %q) = %v; wantErr %v", test.titleTemplate, test.bodyTemplate, err, test.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,179 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/cli/go-gh/v2/pkg/api"
+	"github.com/spf13/cobra"
+
+	"github.com/github/gh-combine/internal/github"
+)
+
+// UndoResult tracks what was undone in a repository
+type UndoResult struct {
+	Repo   string
+	Undone []string
+	Failed []string
+}
+
+// newUndoCmd creates the undo subcommand, which closes the combined PRs and deletes the branches of a previous run
+func newUndoCmd() *cobra.Command {
+	undoCmd := &cobra.Command{
+		Use:   "undo run-id",
+		Short: "Close the combined PRs and delete the branches of a previous run",
+		Long: `Close the combined PRs and delete the branches created by a previous run.
+    The run ID is printed at the end of every run that changed a repository.
+    Combined PRs that were already merged are left untouched.
+    Examples:
+      gh combine undo 20250101-120000-a1b2c3  # Undo the run with ID 20250101-120000-a1b2c3`,
+		Args: cobra.ExactArgs(1),
+		RunE: runUndo,
+	}
+
+	return undoCmd
+}
+
+// runUndo is the main execution function for the undo command
+func runUndo(cmd *cobra.Command, args []string) error {
+	ctx, cancel := SetupSignalContext()
+	defer cancel()
+
+	journal, err := loadRunJournal(args[0])
+	if err != nil {
+		return err
+	}
+
+	restClient, err := api.DefaultRESTClient()
+	if err != nil {
+		return fmt.Errorf("failed to create REST client: %w", err)
+	}
+
+	restClientWrapper := struct {
+		RESTClientInterface
+	}{restClient}
+
+	results, err := undoRun(ctx, restClientWrapper, journal)
+	if err != nil {
+		return err
+	}
+
+	displayUndoResults(journal.ID, results)
+
+	for _, result := range results {
+		if len(result.Failed) > 0 {
+			return fmt.Errorf("failed to undo run %s completely", journal.ID)
+		}
+	}
+
+	// The run is fully undone, so it cannot be undone again
+	path, err := runJournalPath(journal.ID)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil {
+		Logger.Warn("Failed to remove run journal", "run", journal.ID, "error", err)
+	}
+	return nil
+}
+
+// undoRun undoes the changes of a run in every repository it changed
+func undoRun(ctx context.Context, client RESTClientInterface, journal *RunJournal) ([]UndoResult, error) {
+	var results []UndoResult
+	for _, run := range journal.Repos {
+		// Check for cancellation
+		select {
+		case <-ctx.Done():
+			return results, ctx.Err()
+		default:
+			// Continue processing
+		}
+
+		results = append(results, undoRepoRun(ctx, client, journal.ID, run))
+	}
+	return results, nil
+}
+
+// undoRepoRun closes the combined PR created by a run in a repository and deletes its branches. A merged PR,
+// or a PR that was not created by the run, is left untouched along with its branch
+func undoRepoRun(ctx context.Context, client RESTClientInterface, runID string, run RepoRun) UndoResult {
+	result := UndoResult{Repo: run.Repo}
+
+	repo, err := github.ParseRepo(run.Repo)
+	if err != nil {
+		result.Failed = append(result.Failed, err.Error())
+		return result
+	}
+
+	// The head of the combined PR, which tells whether its branch was recreated by a later run
+	headSHA := ""
+	if run.PRNumber > 0 {
+		var pull github.Pull
+		if err := client.Get(fmt.Sprintf("repos/%s/%s/pulls/%d", repo.Owner, repo.Repo, run.PRNumber), &pull); err != nil {
+			result.Failed = append(result.Failed, fmt.Sprintf("could not get #%d: %v", run.PRNumber, err))
+			return result
+		}
+
+		switch {
+		case pull.Merged:
+			result.Undone = append(result.Undone, fmt.Sprintf("#%d was merged, leaving it and its branch", run.PRNumber))
+			return result
+		case !strings.Contains(pull.Body, runMarker(runID)):
+			result.Failed = append(result.Failed, fmt.Sprintf("#%d was not created by run %s", run.PRNumber, runID))
+			return result
+		case pull.State == "closed":
+			result.Undone = append(result.Undone, fmt.Sprintf("#%d was already closed", run.PRNumber))
+		default:
+			if err := closePullRequest(ctx, client, repo, run.PRNumber); err != nil {
+				result.Failed = append(result.Failed, fmt.Sprintf("could not close #%d: %v", run.PRNumber, err))
+				return result
+			}
+			result.Undone = append(result.Undone, fmt.Sprintf("Closed #%d", run.PRNumber))
+		}
+		headSHA = pull.Head.SHA
+	}
+
+	for _, branch := range run.CreatedBranches {
+		sha, err := getBranchSHA(ctx, client, repo, branch)
+		if err != nil {
+			result.Undone = append(result.Undone, fmt.Sprintf("Branch %s was already deleted", branch))
+			continue
+		}
+		if headSHA != "" && sha != headSHA {
+			result.Failed = append(result.Failed, fmt.Sprintf("branch %s moved since the run, leaving it", branch))
+			continue
+		}
+		if err := deleteBranch(ctx, client, repo, branch); err != nil {
+			result.Failed = append(result.Failed, fmt.Sprintf("could not delete branch %s: %v", branch, err))
+			continue
+		}
+		result.Undone = append(result.Undone, "Deleted branch "+branch)
+	}
+
+	return result
+}
+
+// closePullRequest closes a pull request without merging it
+func closePullRequest(ctx context.Context, client RESTClientInterface, repo github.Repo, number int) error {
+	payload, err := encodePayload(map[string]string{"state": "closed"})
+	if err != nil {
+		return fmt.Errorf("failed to encode payload: %w", err)
+	}
+	return client.Patch(fmt.Sprintf("repos/%s/%s/pulls/%d", repo.Owner, repo.Repo, number), payload, nil)
+}
+
+// displayUndoResults prints what was undone in every repository
+func displayUndoResults(runID string, results []UndoResult) {
+	fmt.Printf("Undid run %s:\n", colorize(runID, colorBlue))
+	for _, result := range results {
+		fmt.Printf("  %s\n", result.Repo)
+		for _, undone := range result.Undone {
+			fmt.Printf("  - %s\n", undone)
+		}
+		for _, failed := range result.Failed {
+			fmt.Printf("  - %s\n", colorize("Failed: "+failed, colorYellow))
+		}
+	}
+}
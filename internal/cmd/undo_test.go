@@ -0,0 +1,113 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUndoRepoRun(t *testing.T) {
+	t.Parallel()
+
+	const runID = "20250101-120000-abcdef"
+	body := `"body": "Combined PRs\n<!-- gh-combine:run=` + runID + ` -->"`
+
+	tests := []struct {
+		name        string
+		run         RepoRun
+		pull        string
+		branchSHA   string
+		wantUndone  []string
+		wantFailed  []string
+		wantClosed  bool
+		wantDeleted bool
+	}{
+		{
+			name:        "open PR is closed and its branch deleted",
+			run:         RepoRun{Repo: "owner/repo", CreatedBranches: []string{"combined-prs"}, PRNumber: 7},
+			pull:        `{"number": 7, "state": "open", ` + body + `, "head": {"sha": "head"}}`,
+			branchSHA:   "head",
+			wantUndone:  []string{"Closed #7", "Deleted branch combined-prs"},
+			wantClosed:  true,
+			wantDeleted: true,
+		},
+		{
+			name:       "merged PR is left untouched",
+			run:        RepoRun{Repo: "owner/repo", CreatedBranches: []string{"combined-prs"}, PRNumber: 7},
+			pull:       `{"number": 7, "state": "closed", "merged": true, ` + body + `, "head": {"sha": "head"}}`,
+			branchSHA:  "head",
+			wantUndone: []string{"#7 was merged, leaving it and its branch"},
+		},
+		{
+			name:       "PR of another run is left untouched",
+			run:        RepoRun{Repo: "owner/repo", CreatedBranches: []string{"combined-prs"}, PRNumber: 7},
+			pull:       `{"number": 7, "state": "open", "body": "Combined PRs", "head": {"sha": "head"}}`,
+			branchSHA:  "head",
+			wantFailed: []string{"#7 was not created by run " + runID},
+		},
+		{
+			name:       "branch recreated by a later run is kept",
+			run:        RepoRun{Repo: "owner/repo", CreatedBranches: []string{"combined-prs"}, PRNumber: 7},
+			pull:       `{"number": 7, "state": "closed", ` + body + `, "head": {"sha": "head"}}`,
+			branchSHA:  "other",
+			wantUndone: []string{"#7 was already closed"},
+			wantFailed: []string{"branch combined-prs moved since the run, leaving it"},
+		},
+		{
+			name:       "branch already deleted",
+			run:        RepoRun{Repo: "owner/repo", CreatedBranches: []string{"combined-prs"}},
+			wantUndone: []string{"Branch combined-prs was already deleted"},
+		},
+		{
+			name:        "branch without PR is deleted",
+			run:         RepoRun{Repo: "owner/repo", CreatedBranches: []string{"combined-prs"}},
+			branchSHA:   "head",
+			wantUndone:  []string{"Deleted branch combined-prs"},
+			wantDeleted: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			var closed, deleted bool
+			client := &MockRESTClient{
+				GetFunc: func(endpoint string, response interface{}) error {
+					switch endpoint {
+					case "repos/owner/repo/pulls/7":
+						return json.Unmarshal([]byte(test.pull), response)
+					case "repos/owner/repo/git/ref/heads/combined-prs":
+						if test.branchSHA == "" {
+							return errors.New("HTTP 404: Not Found")
+						}
+						return json.Unmarshal([]byte(`{"object": {"sha": "`+test.branchSHA+`"}}`), response)
+					}
+					return errors.New("unexpected endpoint " + endpoint)
+				},
+				PatchFunc: func(endpoint string, body io.Reader, response interface{}) error {
+					data, _ := io.ReadAll(body)
+					assert.Equal(t, "repos/owner/repo/pulls/7", endpoint)
+					assert.JSONEq(t, `{"state": "closed"}`, string(data))
+					closed = true
+					return nil
+				},
+				DeleteFunc: func(endpoint string, response interface{}) error {
+					assert.Equal(t, "repos/owner/repo/git/refs/heads/combined-prs", endpoint)
+					deleted = true
+					return nil
+				},
+			}
+
+			result := undoRepoRun(context.Background(), client, runID, test.run)
+			assert.Equal(t, test.wantUndone, result.Undone)
+			assert.Equal(t, test.wantFailed, result.Failed)
+			assert.Equal(t, test.wantClosed, closed)
+			assert.Equal(t, test.wantDeleted, deleted)
+		})
+	}
+}
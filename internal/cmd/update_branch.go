@@ -0,0 +1,255 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/cli/go-gh/v2/pkg/api"
+	graphql "github.com/cli/shurcooL-graphql"
+	"github.com/spf13/cobra"
+
+	"github.com/github/gh-combine/internal/github"
+)
+
+// Update methods for --update-method
+const (
+	updateMethodMerge  = "merge"
+	updateMethodRebase = "rebase"
+)
+
+// Update statuses of the combined PR
+const (
+	updateStatusUpToDate    = "already up to date"
+	updateStatusUpdated     = "updated"
+	updateStatusConflict    = "conflicts with the base branch"
+	updateStatusWouldUpdate = "behind the base branch"
+	updateStatusNoPR        = "no open combined PR"
+)
+
+var errInvalidUpdateMethod = errors.New("invalid --update-method value")
+
+// PullRequestBranchUpdateMethod is the GraphQL enum for update methods
+type PullRequestBranchUpdateMethod string
+
+// UpdatePullRequestBranchInput is the GraphQL input type of the updatePullRequestBranch mutation
+type UpdatePullRequestBranchInput struct {
+	PullRequestID   graphql.ID                    `json:"pullRequestId"`
+	ExpectedHeadOid GitObjectID                   `json:"expectedHeadOid"`
+	UpdateMethod    PullRequestBranchUpdateMethod `json:"updateMethod"`
+}
+
+// UpdateResult is the outcome of updating the combined PR of a repository
+type UpdateResult struct {
+	Repo   github.Repo
+	PRLink string
+	Status string
+	Error  error
+}
+
+// newUpdateCmd creates the update subcommand, which brings open combined PRs up to date with their base branch
+func newUpdateCmd() *cobra.Command {
+	updateCmd := &cobra.Command{
+		Use:   "update owner/repo",
+		Short: "Update the branch of open combined PRs with their base branch",
+		Long: `Update the branch of the open combined PR in each repository with the latest changes of its base branch.
+    Examples:
+      gh combine update owner/repo                         # Merge the base branch into the combined PR
+      gh combine update owner/repo --update-method rebase  # Rebase the combined PR on the base branch
+      gh combine update --file repos.txt                   # Update the combined PRs of multiple repositories`,
+		Args: cobra.ArbitraryArgs,
+		RunE: runUpdate,
+	}
+
+	updateCmd.Flags().StringVar(&reposFile, "file", "", "File containing repository names, one per line")
+	updateCmd.Flags().StringVar(&combineBranchName, "combine-branch-name", "combined-prs", "Name of the combined PR branch")
+	updateCmd.Flags().StringVar(&updateMethod, "update-method", updateMethodMerge, "How the branch is updated: merge or rebase")
+	updateCmd.Flags().BoolVar(&dryRun, "dry-run", false, "Only report whether the combined PRs are behind their base branch")
+
+	return updateCmd
+}
+
+// runUpdate is the main execution function for the update command
+func runUpdate(cmd *cobra.Command, args []string) error {
+	ctx, cancel := SetupSignalContext()
+	defer cancel()
+
+	if err := ValidateUpdateMethod(updateMethod); err != nil {
+		return err
+	}
+
+	repos, err := ParseRepositories(args, reposFile)
+	if err != nil {
+		return fmt.Errorf("failed to parse repositories: %w", err)
+	}
+	if len(repos) == 0 {
+		return errors.New("no repositories specified")
+	}
+
+	restClient, err := api.DefaultRESTClient()
+	if err != nil {
+		return fmt.Errorf("failed to create REST client: %w", err)
+	}
+
+	graphQlClient, err := api.DefaultGraphQLClient()
+	if err != nil {
+		return fmt.Errorf("failed to create GraphQLClient client: %w", err)
+	}
+
+	restClientWrapper := struct {
+		RESTClientInterface
+	}{restClient}
+
+	var results []UpdateResult
+	for _, repo := range repos {
+		// Check for cancellation
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+			// Continue processing
+		}
+
+		result := UpdateResult{Repo: repo}
+		pull, err := findOpenCombinedPR(ctx, restClientWrapper, repo)
+		if err != nil {
+			result.Error = err
+		} else if pull == nil {
+			result.Status = updateStatusNoPR
+		} else {
+			result.PRLink = fmt.Sprintf("https://github.com/%s/%s/pull/%d", repo.Owner, repo.Repo, pull.Number)
+			result.Status, result.Error = updateCombinedPR(ctx, graphQlClient, restClientWrapper, repo, *pull)
+		}
+		results = append(results, result)
+	}
+
+	displayUpdateResults(results)
+	return nil
+}
+
+// findOpenCombinedPR returns the open PR of the combined branch, or nil if there is none
+func findOpenCombinedPR(ctx context.Context, client RESTClientInterface, repo github.Repo) (*github.Pull, error) {
+	var pulls github.Pulls
+	endpoint := fmt.Sprintf("%s&head=%s:%s", repo.PullsEndpoint(), repo.Owner, combineBranchName)
+	if err := client.Get(endpoint, &pulls); err != nil {
+		return nil, fmt.Errorf("failed to find combined PR: %w", err)
+	}
+	if len(pulls) == 0 {
+		return nil, nil
+	}
+	return &pulls[0], nil
+}
+
+// findCombinedPR returns the combined PR among the open PRs of a repository, or nil if there is none
+func findCombinedPR(pulls github.Pulls, repo github.Repo) *github.Pull {
+	for i, pull := range pulls {
+		if pull.Head.Ref != combineBranchName {
+			continue
+		}
+		// A fork can have a branch with the same name
+		if pull.Head.Repo != nil && !strings.EqualFold(pull.Head.Repo.FullName, repo.String()) {
+			continue
+		}
+		return &pulls[i]
+	}
+	return nil
+}
+
+// updateCombinedPR brings the branch of the combined PR up to date with its base branch using --update-method.
+// A conflict with the base branch is reported as a status rather than an error
+func updateCombinedPR(ctx context.Context, graphQlClient *api.GraphQLClient, restClient RESTClientInterface, repo github.Repo, pull github.Pull) (string, error) {
+	behind, err := commitsBehind(ctx, restClient, repo, pull.Base.Ref, pull.Head.SHA)
+	if err != nil {
+		return "", err
+	}
+	if behind == 0 {
+		return updateStatusUpToDate, nil
+	}
+	if dryRun {
+		Logger.Debug("Dry-run mode enabled, not updating combined PR", "repo", repo, "pr", pull.Number, "behind", behind)
+		return fmt.Sprintf("%s by %d commits", updateStatusWouldUpdate, behind), nil
+	}
+
+	nodeID, err := getPullRequestNodeID(ctx, restClient, repo, pull.Number)
+	if err != nil {
+		return "", err
+	}
+
+	var mutation struct {
+		UpdatePullRequestBranch struct {
+			ClientMutationID string
+		} `graphql:"updatePullRequestBranch(input: $input)"`
+	}
+
+	variables := map[string]interface{}{
+		"input": UpdatePullRequestBranchInput{
+			PullRequestID:   graphql.ID(nodeID),
+			ExpectedHeadOid: GitObjectID(pull.Head.SHA),
+			UpdateMethod:    PullRequestBranchUpdateMethod(strings.ToUpper(updateMethod)),
+		},
+	}
+
+	if err := graphQlClient.MutateWithContext(ctx, "UpdatePullRequestBranch", &mutation, variables); err != nil {
+		if isUpdateConflictError(err) {
+			Logger.Debug("Combined PR conflicts with its base branch", "repo", repo, "pr", pull.Number, "error", err)
+			return updateStatusConflict, nil
+		}
+		return "", fmt.Errorf("failed to update combined PR: %w", err)
+	}
+
+	Logger.Debug("Updated combined PR", "repo", repo, "pr", pull.Number, "method", updateMethod)
+	return updateStatusUpdated, nil
+}
+
+// graphQLErrorUnprocessable is the GraphQL error type updatePullRequestBranch reports when the branch cannot be
+// updated. Besides conflicts it also covers a stale expectedHeadOid and refusals by branch protection
+const graphQLErrorUnprocessable = "UNPROCESSABLE"
+
+// isUpdateConflictError reports whether updating a PR branch failed because it conflicts with its base branch
+func isUpdateConflictError(err error) bool {
+	var gqlErr *api.GraphQLError
+	if !errors.As(err, &gqlErr) {
+		return false
+	}
+	return slices.ContainsFunc(gqlErr.Errors, func(item api.GraphQLErrorItem) bool {
+		return item.Type == graphQLErrorUnprocessable && strings.Contains(strings.ToLower(item.Message), "conflict")
+	})
+}
+
+// commitsBehind returns how many commits of the base branch are missing from head
+func commitsBehind(ctx context.Context, client RESTClientInterface, repo github.Repo, base, head string) (int, error) {
+	var comparison struct {
+		BehindBy int `json:"behind_by"`
+	}
+	endpoint := fmt.Sprintf("repos/%s/%s/compare/%s...%s", repo.Owner, repo.Repo, base, head)
+	if err := client.Get(endpoint, &comparison); err != nil {
+		return 0, fmt.Errorf("failed to compare %s...%s: %w", base, head, err)
+	}
+	return comparison.BehindBy, nil
+}
+
+// displayUpdateResults prints the outcome of the update command
+func displayUpdateResults(results []UpdateResult) {
+	for _, result := range results {
+		switch {
+		case result.Error != nil:
+			fmt.Printf("- %s %s\n", result.Repo, colorize("failed: "+result.Error.Error(), colorYellow))
+		case result.Status == updateStatusConflict:
+			fmt.Printf("- %s %s %s\n", result.Repo, colorize(result.PRLink, colorBlue), colorize(result.Status, colorYellow))
+		case result.PRLink != "":
+			fmt.Printf("- %s %s %s\n", result.Repo, colorize(result.PRLink, colorBlue), result.Status)
+		default:
+			fmt.Printf("- %s %s\n", result.Repo, result.Status)
+		}
+	}
+}
+
+// ValidateUpdateMethod checks that --update-method is one of the supported update methods
+func ValidateUpdateMethod(method string) error {
+	if !slices.Contains([]string{updateMethodMerge, updateMethodRebase}, method) {
+		return fmt.Errorf("%w: %q (must be %s or %s)", errInvalidUpdateMethod, method, updateMethodMerge, updateMethodRebase)
+	}
+	return nil
+}
@@ -0,0 +1,124 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/cli/go-gh/v2/pkg/api"
+	"github.com/github/gh-combine/internal/github"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFindCombinedPR(t *testing.T) {
+	// Since this test changes global state, don't use t.Parallel()
+	origCombineBranchName := combineBranchName
+	defer func() { combineBranchName = origCombineBranchName }()
+	combineBranchName = "combined-prs"
+
+	repo := github.Repo{Owner: "owner", Repo: "repo"}
+
+	pulls := github.Pulls{
+		{Number: 1, Head: github.Ref{Ref: "dependabot/npm_and_yarn/lodash-4.17.21"}},
+		{Number: 2, Head: github.Ref{Ref: "combined-prs", Repo: &github.RefRepo{FullName: "someone/repo"}}},
+		{Number: 3, Head: github.Ref{Ref: "combined-prs", Repo: &github.RefRepo{FullName: "Owner/Repo"}}},
+	}
+
+	got := findCombinedPR(pulls, repo)
+	if assert.NotNil(t, got) {
+		assert.Equal(t, 3, got.Number)
+	}
+	assert.Nil(t, findCombinedPR(pulls[:2], repo))
+}
+
+func TestUpdateCombinedPR(t *testing.T) {
+	// Since this test changes global state, don't use t.Parallel()
+	origDryRun := dryRun
+	defer func() { dryRun = origDryRun }()
+
+	repo := github.Repo{Owner: "owner", Repo: "repo"}
+	pull := github.Pull{Number: 42, Base: github.Ref{Ref: "main"}, Head: github.Ref{Ref: "combined-prs", SHA: "abc123"}}
+
+	tests := []struct {
+		name       string
+		dryRun     bool
+		comparison string
+		want       string
+	}{
+		{
+			name:       "up to date",
+			comparison: `{"behind_by": 0, "ahead_by": 3}`,
+			want:       updateStatusUpToDate,
+		},
+		{
+			name:       "behind in dry-run",
+			dryRun:     true,
+			comparison: `{"behind_by": 2, "ahead_by": 3}`,
+			want:       "behind the base branch by 2 commits",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dryRun = test.dryRun
+
+			client := &MockRESTClient{
+				GetFunc: func(endpoint string, response interface{}) error {
+					assert.Equal(t, "repos/owner/repo/compare/main...abc123", endpoint)
+					return json.Unmarshal([]byte(test.comparison), response)
+				},
+			}
+
+			// The GraphQL client is not used when there is nothing to update
+			status, err := updateCombinedPR(context.Background(), nil, client, repo, pull)
+			assert.NoError(t, err)
+			assert.Equal(t, test.want, status)
+		})
+	}
+}
+
+func TestFindOpenCombinedPR(t *testing.T) {
+	// Since this test changes global state, don't use t.Parallel()
+	origCombineBranchName := combineBranchName
+	defer func() { combineBranchName = origCombineBranchName }()
+	combineBranchName = "combined-prs"
+
+	repo := github.Repo{Owner: "owner", Repo: "repo"}
+
+	client := &MockRESTClient{
+		GetFunc: func(endpoint string, response interface{}) error {
+			assert.Equal(t, "repos/owner/repo/pulls?state=open&head=owner:combined-prs", endpoint)
+			return json.Unmarshal([]byte(`[{"number": 42}]`), response)
+		},
+	}
+
+	pull, err := findOpenCombinedPR(context.Background(), client, repo)
+	assert.NoError(t, err)
+	if assert.NotNil(t, pull) {
+		assert.Equal(t, 42, pull.Number)
+	}
+}
+
+func TestValidateUpdateMethod(t *testing.T) {
+	t.Parallel()
+
+	assert.NoError(t, ValidateUpdateMethod(updateMethodMerge))
+	assert.NoError(t, ValidateUpdateMethod(updateMethodRebase))
+	assert.ErrorIs(t, ValidateUpdateMethod("squash"), errInvalidUpdateMethod)
+}
+
+func TestIsUpdateConflictError(t *testing.T) {
+	t.Parallel()
+
+	conflict := &api.GraphQLError{Errors: []api.GraphQLErrorItem{{Type: graphQLErrorUnprocessable, Message: "Merge conflict between base and head"}}}
+
+	assert.True(t, isUpdateConflictError(conflict))
+	assert.True(t, isUpdateConflictError(fmt.Errorf("mutation failed: %w", conflict)))
+	assert.False(t, isUpdateConflictError(&api.GraphQLError{Errors: []api.GraphQLErrorItem{{Type: "FORBIDDEN", Message: "conflict with a ruleset"}}}))
+	assert.False(t, isUpdateConflictError(errors.New("merge conflict")), "only GraphQL errors should be checked")
+	assert.False(t, isUpdateConflictError(&api.GraphQLError{Errors: []api.GraphQLErrorItem{{Type: graphQLErrorUnprocessable, Message: "Expected head oid abc123 does not match the head of the branch"}}}),
+		"an UNPROCESSABLE error that is not a conflict should fail the update")
+	assert.False(t, isUpdateConflictError(&api.GraphQLError{Errors: []api.GraphQLErrorItem{{Type: graphQLErrorUnprocessable, Message: "Protected branch update failed"}}}))
+}
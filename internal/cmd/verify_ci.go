@@ -0,0 +1,334 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/cli/go-gh/v2/pkg/api"
+	graphql "github.com/cli/shurcooL-graphql"
+
+	"github.com/github/gh-combine/internal/github"
+)
+
+// GitObjectID is the GraphQL scalar type for commit SHAs
+type GitObjectID string
+
+// bisectBranchInfix separates the combined branch name from the unique suffix of the scratch branches used for bisecting
+const bisectBranchInfix = "-bisect-"
+
+// The draft PR opened for the bisect branch
+const (
+	bisectPRTitle = "gh-combine: bisecting CI failures"
+	bisectPRBody  = "Temporary draft pull request opened by gh-combine `--verify-ci` so that CI runs on a subset of the combined pull requests. It is closed and its branch deleted once bisecting is done."
+)
+
+// Outcomes of waiting for CI on a branch
+const (
+	ciOutcomePassing      = "passing"
+	ciOutcomeFailing      = "failing"
+	ciOutcomeInconclusive = "inconclusive"
+)
+
+// CI statuses reported when CI fails on the combined PR but bisecting cannot tell which PRs to eject
+const (
+	ciStatusBisectInconclusive = "failing (CI did not settle while bisecting, nothing ejected)"
+	ciStatusNoSingleCulprit    = "failing (only fails when PRs are combined, nothing ejected)"
+)
+
+var (
+	// How long a freshly pushed commit without any checks is considered pending rather than passing,
+	// since CI needs some time to register its checks after a push
+	ciRegistrationGracePeriod = 2 * time.Minute
+
+	// How many CI wait timeouts verifying a combined PR may take in total, including the bisection and the final check
+	ciVerifyTimeoutFactor = 4
+
+	// errCIInconclusive is returned by bisectCIFailures when CI did not settle on one of the trial branches
+	errCIInconclusive = errors.New("CI is inconclusive")
+
+	// errNoSingleCulprit is returned by bisectCIFailures when PRs only fail CI together
+	errNoSingleCulprit = errors.New("CI only fails when PRs are combined")
+)
+
+// verifyCombinedPR waits for CI on the combined PR. When it fails, the PR set is bisected on a scratch branch with
+// halves of the PRs to find the offending PRs, which are then dropped from the combined branch and listed in the
+// updated PR body. Nothing is ejected when CI does not settle in time or when no single PR can be isolated, and the
+// rebuilt combined branch is verified again. The final CI outcome is reported in result.CIStatus
+func verifyCombinedPR(ctx context.Context, graphQlClient *api.GraphQLClient, restClient RESTClientInterface, opts CombineOpts, baseBranch, baseSHA string, result *CombineResult) error {
+	deadline := time.Now().Add(time.Duration(ciVerifyTimeoutFactor) * ciWaitTimeout())
+
+	outcome, err := branchCIOutcome(ctx, graphQlClient, restClient, opts.Repo, combineBranchName, deadline)
+	if err != nil {
+		return err
+	}
+	result.CIStatus = outcome
+	if outcome != ciOutcomeFailing {
+		Logger.Debug("CI did not fail on combined PR", "repo", opts.Repo, "pr", result.PRNumber, "outcome", outcome)
+		return nil
+	}
+
+	Logger.Debug("CI failed on combined PR, bisecting", "repo", opts.Repo, "pr", result.PRNumber, "count", len(result.Combined))
+
+	culprits, err := bisectOnScratchBranch(ctx, graphQlClient, restClient, opts.Repo, baseBranch, baseSHA, result.Combined, deadline)
+	if errors.Is(err, errCIInconclusive) {
+		Logger.Warn("CI did not settle while bisecting, not ejecting any PRs", "repo", opts.Repo, "pr", result.PRNumber)
+		result.CIStatus = ciStatusBisectInconclusive
+		return nil
+	}
+	if errors.Is(err, errNoSingleCulprit) {
+		Logger.Warn("CI only fails when PRs are combined, not ejecting any PRs", "repo", opts.Repo, "pr", result.PRNumber)
+		result.CIStatus = ciStatusNoSingleCulprit
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var kept github.Pulls
+	for _, pr := range result.Combined {
+		if !slices.ContainsFunc(culprits, func(c github.Pull) bool { return c.Number == pr.Number }) {
+			kept = append(kept, pr)
+		}
+	}
+
+	Logger.Debug("Ejecting PRs that fail CI", "repo", opts.Repo, "ejected", len(culprits), "kept", len(kept))
+
+	// Only the culprits are known at this point, so the real combined branch is rebuilt without them
+	combined, conflicts, unsignedReason, err := buildCombinedBranch(ctx, graphQlClient, restClient, opts.Repo, baseSHA, kept)
+	if err != nil {
+		return err
+	}
+
+	result.Combined = combined
+	result.MergeConflicts = append(result.MergeConflicts, conflicts...)
+	result.UnsignedReason = unsignedReason
+	result.Ejected = append(result.Ejected, culprits...)
+
+	data := newPRTemplateData(opts.Repo, result.Combined, result.MergeConflicts, result.Ejected, result.Outdated, opts.Command)
+	data.RunID = opts.Journal.runID()
+	title, body, err := renderPRTitleAndBody(data)
+	if err != nil {
+		return fmt.Errorf("failed to render combined PR: %w", err)
+	}
+	if err := updatePullRequest(ctx, restClient, opts.Repo, result.PRNumber, title, body); err != nil {
+		return fmt.Errorf("failed to update combined PR: %w", err)
+	}
+
+	// The remaining PRs may still fail together, so the rebuilt branch is verified once more
+	result.CIStatus, err = branchCIOutcome(ctx, graphQlClient, restClient, opts.Repo, combineBranchName, deadline)
+	if err != nil {
+		return err
+	}
+	Logger.Debug("CI on rebuilt combined PR", "repo", opts.Repo, "pr", result.PRNumber, "outcome", result.CIStatus)
+
+	return nil
+}
+
+// bisectOnScratchBranch bisects the CI failures of the PRs on a uniquely named scratch branch, so the combined
+// branch keeps pointing at the full set of PRs until the culprits are known. A draft PR is opened for the scratch
+// branch, so workflows that only run on pull_request events run on it too. The draft PR is always closed and the
+// scratch branch deleted
+func bisectOnScratchBranch(ctx context.Context, graphQlClient *api.GraphQLClient, restClient RESTClientInterface, repo github.Repo, baseBranch, baseSHA string, pulls github.Pulls, deadline time.Time) (github.Pulls, error) {
+	scratchBranch := combineBranchName + bisectBranchInfix + strconv.FormatInt(time.Now().UnixNano(), 36)
+
+	if err := createBranch(ctx, restClient, repo, scratchBranch, baseSHA); err != nil {
+		return nil, fmt.Errorf("failed to create bisect branch: %w", err)
+	}
+
+	prNumber := 0
+	defer func() {
+		if prNumber > 0 {
+			if err := closePullRequest(ctx, restClient, repo, prNumber); err != nil {
+				Logger.Warn("Failed to close bisect PR", "repo", repo, "pr", prNumber, "error", err)
+			}
+		}
+		if err := deleteBranch(ctx, restClient, repo, scratchBranch); err != nil {
+			Logger.Warn("Failed to delete bisect branch", "repo", repo, "branch", scratchBranch, "error", err)
+		}
+	}()
+
+	return bisectCIFailures(ctx, pulls, func(subset github.Pulls) (string, error) {
+		if _, _, _, err := buildBranch(ctx, graphQlClient, restClient, repo, scratchBranch, baseSHA, subset); err != nil {
+			return "", err
+		}
+
+		// GitHub refuses a PR without changes, so the draft PR is opened once the scratch branch has commits
+		if prNumber == 0 {
+			number, err := createPullRequestWithNumber(ctx, restClient, repo, bisectPRTitle, scratchBranch, baseBranch, bisectPRBody, true)
+			if err != nil {
+				return "", fmt.Errorf("failed to open bisect PR: %w", err)
+			}
+			prNumber = number
+		}
+
+		return branchCIOutcome(ctx, graphQlClient, restClient, repo, scratchBranch, deadline)
+	})
+}
+
+// bisectCIFailures finds the PRs that make CI fail. outcome reports the CI outcome of a branch built from
+// the given PRs. The PRs are known to fail together, so they are split in halves and each failing half is
+// bisected further. If neither half fails on its own the failure comes from an interaction, and bisecting
+// stops with errNoSingleCulprit since no single culprit can be isolated. If CI is inconclusive for any half,
+// bisecting stops with errCIInconclusive, since a half that did not settle cannot be told apart from a passing one
+func bisectCIFailures(ctx context.Context, pulls github.Pulls, outcome func(github.Pulls) (string, error)) (github.Pulls, error) {
+	if len(pulls) <= 1 {
+		return pulls, nil
+	}
+
+	// Check for cancellation
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	default:
+		// Continue processing
+	}
+
+	mid := len(pulls) / 2
+	var culprits github.Pulls
+
+	for _, half := range []github.Pulls{pulls[:mid], pulls[mid:]} {
+		halfOutcome, err := outcome(half)
+		if err != nil {
+			return nil, err
+		}
+		switch halfOutcome {
+		case ciOutcomeInconclusive:
+			return nil, errCIInconclusive
+		case ciOutcomePassing:
+			continue
+		}
+		halfCulprits, err := bisectCIFailures(ctx, half, outcome)
+		if err != nil {
+			return nil, err
+		}
+		culprits = append(culprits, halfCulprits...)
+	}
+
+	if len(culprits) == 0 {
+		Logger.Debug("CI only fails when PRs are combined, unable to isolate a single PR", "count", len(pulls))
+		return nil, errNoSingleCulprit
+	}
+
+	return culprits, nil
+}
+
+// branchCIOutcome waits for CI on the current head of a branch until the deadline and reports whether it passed
+// or failed. CI that is still pending at the deadline, or that has no checks, is inconclusive
+func branchCIOutcome(ctx context.Context, graphQlClient *api.GraphQLClient, restClient RESTClientInterface, repo github.Repo, branch string, deadline time.Time) (string, error) {
+	sha, err := getBranchSHA(ctx, restClient, repo, branch)
+	if err != nil {
+		return "", err
+	}
+
+	state, err := waitForCommitCI(ctx, graphQlClient, repo, sha, min(ciWaitTimeout(), max(time.Until(deadline), 0)))
+	if err != nil {
+		return "", err
+	}
+
+	switch {
+	case ciPassing(state):
+		return ciOutcomePassing, nil
+	case state == ciStateFailing:
+		return ciOutcomeFailing, nil
+	default:
+		Logger.Warn("CI did not settle on branch", "repo", repo, "branch", branch, "sha", sha, "state", state)
+		return ciOutcomeInconclusive, nil
+	}
+}
+
+// waitForCommitCI polls the checks of a commit with backoff until they settle or the timeout is reached
+func waitForCommitCI(ctx context.Context, graphQlClient *api.GraphQLClient, repo github.Repo, sha string, timeout time.Duration) (string, error) {
+	start := time.Now()
+	deadline := start.Add(timeout)
+	interval := ciInitialPollInterval
+
+	for {
+		select {
+		case <-ctx.Done():
+			return "", ctx.Err()
+		case <-time.After(min(interval, max(time.Until(deadline), 0))):
+		}
+
+		response, err := GetCommitStatusInfo(ctx, graphQlClient, repo.Owner, repo.Repo, sha)
+		if err != nil {
+			return "", err
+		}
+
+		state := ciState(response, requireChecks, ignoreChecks)
+		if hasNoChecks(response) && time.Since(start) < ciRegistrationGracePeriod {
+			state = ciStatePending
+		}
+
+		Logger.Debug("Combined branch CI state", "repo", repo, "sha", sha, "state", state)
+		if state != ciStatePending || !time.Now().Before(deadline) {
+			return state, nil
+		}
+
+		interval = nextPollInterval(interval)
+	}
+}
+
+// hasNoChecks reports whether no checks have been registered on the commit yet
+func hasNoChecks(response *prStatusResponse) bool {
+	commits := response.Data.Repository.PullRequest.Commits.Nodes
+	return len(commits) == 0 || commits[0].Commit.StatusCheckRollup == nil
+}
+
+// GetCommitStatusInfo fetches the CI status and check contexts of a single commit using GitHub's GraphQL API.
+// The check contexts are paginated like in GetPRStatusInfo
+func GetCommitStatusInfo(ctx context.Context, graphQlClient *api.GraphQLClient, owner, repo, sha string) (*prStatusResponse, error) {
+	var query struct {
+		Repository struct {
+			Object *struct {
+				Commit struct {
+					StatusCheckRollup *struct {
+						State    string
+						Contexts checkContextConnection `graphql:"contexts(first: 100, after: $contextsCursor)"`
+					}
+				} `graphql:"... on Commit"`
+			} `graphql:"object(oid: $sha)"`
+		} `graphql:"repository(owner: $owner, name: $repo)"`
+	}
+
+	variables := map[string]interface{}{
+		"owner":          graphql.String(owner),
+		"repo":           graphql.String(repo),
+		"sha":            GitObjectID(sha),
+		"contextsCursor": (*graphql.String)(nil),
+	}
+
+	response := &prStatusResponse{}
+	for {
+		err := graphQlClient.QueryWithContext(ctx, "CommitStatus", &query, variables)
+		if err != nil {
+			return nil, fmt.Errorf("GraphQL query failed: %w", err)
+		}
+
+		if query.Repository.Object == nil {
+			return response, nil
+		}
+
+		rollup := query.Repository.Object.Commit.StatusCheckRollup
+		if rollup == nil {
+			response.Data.Repository.PullRequest.Commits.Nodes = make([]struct {
+				Commit struct {
+					StatusCheckRollup *struct {
+						State    string         `json:"state"`
+						Contexts []checkContext `json:"contexts"`
+					} `json:"statusCheckRollup"`
+				} `json:"commit"`
+			}, 1)
+			return response, nil
+		}
+
+		setStatusCheckRollup(response, rollup.State, rollup.Contexts.checkContexts())
+		if !rollup.Contexts.PageInfo.HasNextPage {
+			return response, nil
+		}
+		variables["contextsCursor"] = graphql.NewString(graphql.String(rollup.Contexts.PageInfo.EndCursor))
+	}
+}
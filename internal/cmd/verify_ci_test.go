@@ -0,0 +1,248 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/github/gh-combine/internal/github"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBisectCIFailures(t *testing.T) {
+	t.Parallel()
+
+	pulls := github.Pulls{{Number: 1}, {Number: 2}, {Number: 3}, {Number: 4}, {Number: 5}, {Number: 6}, {Number: 7}}
+
+	tests := []struct {
+		name string
+		// bad are the PRs that break CI on their own
+		bad []int
+		// together breaks CI only when all of these PRs are combined
+		together []int
+		want     []int
+		wantErr  error
+	}{
+		{
+			name: "single culprit",
+			bad:  []int{3},
+			want: []int{3},
+		},
+		{
+			name: "two culprits in different halves",
+			bad:  []int{2, 6},
+			want: []int{2, 6},
+		},
+		{
+			name: "two culprits in the same half",
+			bad:  []int{5, 7},
+			want: []int{5, 7},
+		},
+		{
+			name:     "failure only from an interaction across halves",
+			together: []int{2, 5},
+			wantErr:  errNoSingleCulprit,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			trials := 0
+			outcome := func(subset github.Pulls) (string, error) {
+				trials++
+				numbers := []int{}
+				for _, pr := range subset {
+					numbers = append(numbers, pr.Number)
+				}
+				for _, bad := range test.bad {
+					if slices.Contains(numbers, bad) {
+						return ciOutcomeFailing, nil
+					}
+				}
+				if len(test.together) > 0 {
+					for _, n := range test.together {
+						if !slices.Contains(numbers, n) {
+							return ciOutcomePassing, nil
+						}
+					}
+					return ciOutcomeFailing, nil
+				}
+				return ciOutcomePassing, nil
+			}
+
+			culprits, err := bisectCIFailures(context.Background(), pulls, outcome)
+			if test.wantErr != nil {
+				assert.ErrorIs(t, err, test.wantErr)
+				assert.Empty(t, culprits, "nothing should be ejected without a single culprit")
+				return
+			}
+			assert.NoError(t, err)
+
+			got := []int{}
+			for _, pr := range culprits {
+				got = append(got, pr.Number)
+			}
+			assert.Equal(t, test.want, got)
+			assert.Less(t, trials, 2*len(pulls), "bisection should need fewer trials than testing every PR twice")
+		})
+	}
+}
+
+func TestBisectCIFailuresError(t *testing.T) {
+	t.Parallel()
+
+	_, err := bisectCIFailures(context.Background(), github.Pulls{{Number: 1}, {Number: 2}}, func(github.Pulls) (string, error) {
+		return "", errors.New("boom")
+	})
+	assert.Error(t, err)
+}
+
+func TestBisectCIFailuresInconclusive(t *testing.T) {
+	t.Parallel()
+
+	pulls := github.Pulls{{Number: 1}, {Number: 2}, {Number: 3}, {Number: 4}}
+
+	tests := []struct {
+		name    string
+		outcome func(github.Pulls) string
+	}{
+		{
+			name:    "both halves time out",
+			outcome: func(github.Pulls) string { return ciOutcomeInconclusive },
+		},
+		{
+			name: "one half passes and the other times out",
+			outcome: func(subset github.Pulls) string {
+				if subset[0].Number == 1 {
+					return ciOutcomePassing
+				}
+				return ciOutcomeInconclusive
+			},
+		},
+		{
+			name: "a nested half times out",
+			outcome: func(subset github.Pulls) string {
+				if len(subset) == 1 {
+					return ciOutcomeInconclusive
+				}
+				return ciOutcomeFailing
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			culprits, err := bisectCIFailures(context.Background(), pulls, func(subset github.Pulls) (string, error) {
+				return test.outcome(subset), nil
+			})
+			assert.ErrorIs(t, err, errCIInconclusive)
+			assert.Empty(t, culprits, "nothing should be ejected when CI is inconclusive")
+		})
+	}
+}
+
+func TestBisectCIFailuresCancelled(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := bisectCIFailures(ctx, github.Pulls{{Number: 1}, {Number: 2}}, func(github.Pulls) (string, error) {
+		return ciOutcomeFailing, nil
+	})
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestBisectOnScratchBranch(t *testing.T) {
+	// Since this test reads global state, don't use t.Parallel()
+	// refs collects the branches that are created, read or deleted while bisecting
+	var refs []string
+	client := &MockRESTClient{
+		PostFunc: func(endpoint string, body interface{}, response interface{}) error {
+			data, _ := io.ReadAll(body.(io.Reader))
+			if strings.HasSuffix(endpoint, "/git/refs") {
+				refs = append(refs, string(data))
+			}
+			return nil
+		},
+		DeleteFunc: func(endpoint string, response interface{}) error {
+			refs = append(refs, endpoint)
+			return nil
+		},
+		GetFunc: func(endpoint string, response interface{}) error {
+			refs = append(refs, endpoint)
+			return errors.New("HTTP 404: Not Found")
+		},
+	}
+
+	pulls := github.Pulls{{Number: 1, Head: github.Ref{Ref: "feature-1"}}, {Number: 2, Head: github.Ref{Ref: "feature-2"}}}
+	_, err := bisectOnScratchBranch(context.Background(), nil, client, github.Repo{Owner: "owner", Repo: "repo"}, "main", "base", pulls, time.Now())
+	assert.Error(t, err)
+
+	assert.NotEmpty(t, refs)
+	for _, ref := range refs {
+		assert.Contains(t, ref, combineBranchName+bisectBranchInfix, "bisecting must not touch the combined branch")
+	}
+	assert.True(t, strings.HasPrefix(refs[len(refs)-1], "repos/owner/repo/git/refs/heads/"+combineBranchName+bisectBranchInfix), "the bisect branch should be deleted")
+}
+
+func TestBisectOnScratchBranchOpensDraftPR(t *testing.T) {
+	// Since this test changes global state, don't use t.Parallel()
+	origSuffix := workingBranchSuffix
+	defer func() { workingBranchSuffix = origSuffix }()
+	workingBranchSuffix = "-working"
+
+	var draftPR map[string]interface{}
+	var closed, deleted []string
+	client := &MockRESTClient{
+		PostFunc: func(endpoint string, body interface{}, response interface{}) error {
+			if endpoint == "repos/owner/repo/pulls" {
+				if err := json.NewDecoder(body.(io.Reader)).Decode(&draftPR); err != nil {
+					return err
+				}
+				return json.Unmarshal([]byte(`{"number": 99}`), response)
+			}
+			return nil
+		},
+		PatchFunc: func(endpoint string, body io.Reader, response interface{}) error {
+			if strings.HasPrefix(endpoint, "repos/owner/repo/pulls/") {
+				closed = append(closed, endpoint)
+			}
+			return nil
+		},
+		DeleteFunc: func(endpoint string, response interface{}) error {
+			deleted = append(deleted, strings.TrimPrefix(endpoint, "repos/owner/repo/git/refs/heads/"))
+			return nil
+		},
+		GetFunc: func(endpoint string, response interface{}) error {
+			// The working branch is built, but CI cannot be read on the scratch branch
+			if strings.HasSuffix(endpoint, workingBranchSuffix) {
+				return json.Unmarshal([]byte(`{"object": {"sha": "abc"}}`), response)
+			}
+			return errors.New("HTTP 404: Not Found")
+		},
+	}
+
+	pulls := github.Pulls{{Number: 1, Head: github.Ref{Ref: "feature-1"}}, {Number: 2, Head: github.Ref{Ref: "feature-2"}}}
+	_, err := bisectOnScratchBranch(context.Background(), nil, client, github.Repo{Owner: "owner", Repo: "repo"}, "main", "base", pulls, time.Now())
+	assert.Error(t, err)
+
+	if assert.NotNil(t, draftPR, "a draft PR should be opened so that pull_request workflows run") {
+		assert.Equal(t, true, draftPR["draft"])
+		assert.Equal(t, "main", draftPR["base"])
+		assert.Contains(t, draftPR["head"], combineBranchName+bisectBranchInfix)
+	}
+	assert.Equal(t, []string{"repos/owner/repo/pulls/99"}, closed, "the draft PR should be closed")
+	if assert.NotEmpty(t, deleted) {
+		assert.Contains(t, deleted[len(deleted)-1], combineBranchName+bisectBranchInfix, "the bisect branch should be deleted")
+	}
+}
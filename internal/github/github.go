@@ -1,22 +1,74 @@
 package github
 
+import (
+	"strings"
+	"time"
+)
+
 type Ref struct {
-	Ref string `json:"ref"`
-	SHA string `json:"sha"`
+	Ref  string   `json:"ref"`
+	SHA  string   `json:"sha"`
+	Repo *RefRepo `json:"repo"`
+}
+
+// RefRepo is the repository a head or base ref lives in, which is nil when a fork was deleted
+type RefRepo struct {
+	FullName string `json:"full_name"`
 }
 
 type Label struct {
-	Name string `json:"name"`
+	Name  string `json:"name"`
+	Color string `json:"color"`
 }
 
 type Labels []Label
 
-type Pull struct {
+type User struct {
+	ID    int64  `json:"id"`
+	Login string `json:"login"`
+}
+
+type Milestone struct {
 	Number int    `json:"number"`
 	Title  string `json:"title"`
-	Head   Ref    `json:"head"`
-	Base   Ref    `json:"base"`
-	Labels Labels `json:"labels"`
+}
+
+type Team struct {
+	Slug string `json:"slug"`
+}
+
+type Pull struct {
+	Number    int        `json:"number"`
+	Title     string     `json:"title"`
+	Body      string     `json:"body"`
+	State     string     `json:"state"`
+	Merged    bool       `json:"merged"`
+	MergedAt  *time.Time `json:"merged_at"`
+	Draft     bool       `json:"draft"`
+	User      User       `json:"user"`
+	Milestone *Milestone `json:"milestone"`
+	CreatedAt time.Time  `json:"created_at"`
+	Head      Ref        `json:"head"`
+	Base      Ref        `json:"base"`
+	Labels    Labels     `json:"labels"`
+
+	RequestedReviewers []User `json:"requested_reviewers"`
+	RequestedTeams     []Team `json:"requested_teams"`
 }
 
 type Pulls []Pull
+
+// FromFork reports whether the head branch of the pull request lives in another repository than repo.
+// The head repository of a pull request from a deleted fork is gone, so it counts as a fork too
+func (p Pull) FromFork(repo Repo) bool {
+	return p.Head.Repo == nil || !strings.EqualFold(p.Head.Repo.FullName, repo.String())
+}
+
+// Names returns the names of the labels
+func (l Labels) Names() []string {
+	names := make([]string, len(l))
+	for i, label := range l {
+		names[i] = label.Name
+	}
+	return names
+}
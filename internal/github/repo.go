@@ -3,6 +3,7 @@ package github
 import (
 	"errors"
 	"fmt"
+	"strconv"
 	"strings"
 )
 
@@ -11,7 +12,10 @@ type Repo struct {
 	Repo  string `json:"repo"`
 }
 
-var ErrInvalidRepository = errors.New("invalid repository")
+var (
+	ErrInvalidRepository  = errors.New("invalid repository")
+	ErrInvalidPullRequest = errors.New("invalid pull request")
+)
 
 func ParseRepo(s string) (Repo, error) {
 	parts := strings.Split(s, "/")
@@ -32,3 +36,23 @@ func (r Repo) String() string {
 func (r Repo) PullsEndpoint() string {
 	return fmt.Sprintf("repos/%s/%s/pulls?state=open", r.Owner, r.Repo)
 }
+
+// ParsePullRequest parses a pull request reference in the owner/repo#number format
+func ParsePullRequest(s string) (Repo, int, error) {
+	repoPart, numberPart, found := strings.Cut(s, "#")
+	if !found {
+		return Repo{}, 0, fmt.Errorf("%w: %s (expected owner/repo#number)", ErrInvalidPullRequest, s)
+	}
+
+	repo, err := ParseRepo(repoPart)
+	if err != nil {
+		return Repo{}, 0, fmt.Errorf("%w: %s (expected owner/repo#number)", ErrInvalidPullRequest, s)
+	}
+
+	number, err := strconv.Atoi(numberPart)
+	if err != nil || number <= 0 {
+		return Repo{}, 0, fmt.Errorf("%w: %s (expected owner/repo#number)", ErrInvalidPullRequest, s)
+	}
+
+	return repo, number, nil
+}
@@ -54,3 +54,87 @@ func TestParseRepo(t *testing.T) {
 		})
 	}
 }
+
+func TestParsePullRequest(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		ref        string
+		err        error
+		wantRepo   Repo
+		wantNumber int
+	}{
+		{
+			ref: "owner/repo",
+			err: ErrInvalidPullRequest,
+		},
+		{
+			ref: "owner#12",
+			err: ErrInvalidPullRequest,
+		},
+		{
+			ref: "owner/repo#",
+			err: ErrInvalidPullRequest,
+		},
+		{
+			ref: "owner/repo#abc",
+			err: ErrInvalidPullRequest,
+		},
+		{
+			ref: "owner/repo#0",
+			err: ErrInvalidPullRequest,
+		},
+		{
+			ref:        "owner/repo#12",
+			wantRepo:   Repo{Owner: "owner", Repo: "repo"},
+			wantNumber: 12,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.ref, func(t *testing.T) {
+			t.Parallel()
+
+			repo, number, err := ParsePullRequest(test.ref)
+			if !errors.Is(err, test.err) {
+				t.Errorf("want %q, got %q", test.err, err)
+			}
+
+			if repo != test.wantRepo {
+				t.Errorf("want repo %s, got %s", test.wantRepo, repo)
+			}
+
+			if number != test.wantNumber {
+				t.Errorf("want number %d, got %d", test.wantNumber, number)
+			}
+		})
+	}
+}
+
+func TestPullFromFork(t *testing.T) {
+	t.Parallel()
+
+	repo := Repo{Owner: "owner", Repo: "repo"}
+
+	tests := []struct {
+		name string
+		head *RefRepo
+		want bool
+	}{
+		{name: "same repository", head: &RefRepo{FullName: "owner/repo"}, want: false},
+		{name: "same repository with different case", head: &RefRepo{FullName: "Owner/Repo"}, want: false},
+		{name: "fork", head: &RefRepo{FullName: "contributor/repo"}, want: true},
+		{name: "deleted fork", head: nil, want: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			pull := Pull{Head: Ref{Ref: "feature", Repo: test.head}}
+			if got := pull.FromFork(repo); got != test.want {
+				t.Errorf("want %t, got %t", test.want, got)
+			}
+		})
+	}
+}